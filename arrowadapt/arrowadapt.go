@@ -0,0 +1,239 @@
+// Package arrowadapt converts TOON tables to Apache Arrow records and back,
+// so analytics tools can load TOON exports without going through CSV:
+//
+//	rec, err := arrowadapt.ReadTable(data, "hikes", memory.DefaultAllocator)
+//	...
+//	defer rec.Release()
+//
+// Column types are inferred from the cells as Document.ColumnStats infers
+// them: int columns become int64, float columns float64, bool columns
+// boolean and null columns null, while string and mixed columns hold the
+// text of each cell. Nulls and blank cells are null in every column. A
+// unit in a column name, as in dist(km), is kept in the field metadata
+// under "unit" and written back by WriteTable.
+//
+// The package is a module of its own, so that the toon package does not
+// depend on Arrow.
+package arrowadapt
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	toon "github.com/l00pss/gotoon"
+)
+
+// unitKey is the field metadata key holding the unit of a column.
+const unitKey = "unit"
+
+// unitPattern splits a column name such as dist(km) into name and unit.
+var unitPattern = regexp.MustCompile(`^(.*)\(([^()]*)\)$`)
+
+// ReadTable reads the table at path, such as hikes or trip.hikes, into a
+// record allocated with mem. The caller releases the record.
+func ReadTable(data []byte, path string, mem memory.Allocator) (arrow.Record, error) {
+	return ReadTableWithOptions(data, path, mem, toon.DefaultUnmarshalOptions())
+}
+
+// ReadTableWithOptions is ReadTable decoding the document with opts.
+func ReadTableWithOptions(data []byte, path string, mem memory.Allocator, opts toon.UnmarshalOptions) (arrow.Record, error) {
+	if strings.ContainsAny(path, "[]") {
+		return nil, fmt.Errorf("arrowadapt: path %s must name a table by its keys", path)
+	}
+	doc, err := toon.ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := doc.ColumnStats(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]arrow.Field, len(stats))
+	structFields := make([]reflect.StructField, len(stats))
+	for i, column := range stats {
+		dt, t := columnType(column.Type)
+		fields[i] = arrow.Field{Name: column.Name, Type: dt, Nullable: true}
+		tag := column.Name
+		if m := unitPattern.FindStringSubmatch(column.Name); m != nil {
+			fields[i].Name, tag = m[1], m[1]+",unit="+m[2]
+			fields[i].Metadata = arrow.NewMetadata([]string{unitKey}, []string{m[2]})
+		}
+		structFields[i] = reflect.StructField{
+			Name: "F" + strconv.Itoa(i),
+			Type: t,
+			Tag:  reflect.StructTag(fmt.Sprintf("toon:%q", tag)),
+		}
+	}
+
+	// Decode the document into nested structs leading to the rows, so
+	// that cells are read as Unmarshal reads them
+	keys := strings.Split(path, ".")
+	t := reflect.SliceOf(reflect.StructOf(structFields))
+	for i := len(keys) - 1; i >= 0; i-- {
+		t = wrap(t, keys[i])
+	}
+	v := reflect.New(t)
+	if err := toon.UnmarshalWithOptions(data, v.Interface(), opts); err != nil {
+		return nil, err
+	}
+	rows := v.Elem()
+	for range keys {
+		rows = rows.Field(0)
+	}
+
+	b := array.NewRecordBuilder(mem, arrow.NewSchema(fields, nil))
+	defer b.Release()
+	for r := 0; r < rows.Len(); r++ {
+		row := rows.Index(r)
+		for i := range fields {
+			appendCell(b.Field(i), row.Field(i))
+		}
+	}
+	return b.NewRecord(), nil
+}
+
+// columnType returns the Arrow type of a column of the ColumnStats type
+// typ and the Go type its cells are decoded into, a pointer so that nulls
+// stay nil.
+func columnType(typ string) (arrow.DataType, reflect.Type) {
+	switch typ {
+	case "int":
+		return arrow.PrimitiveTypes.Int64, reflect.TypeOf((*int64)(nil))
+	case "float":
+		return arrow.PrimitiveTypes.Float64, reflect.TypeOf((*float64)(nil))
+	case "bool":
+		return arrow.FixedWidthTypes.Boolean, reflect.TypeOf((*bool)(nil))
+	case "null":
+		return arrow.Null, reflect.TypeOf((*string)(nil))
+	}
+	return arrow.BinaryTypes.String, reflect.TypeOf((*string)(nil))
+}
+
+// appendCell appends the decoded cell v to b.
+func appendCell(b array.Builder, v reflect.Value) {
+	if v.IsNil() {
+		b.AppendNull()
+		return
+	}
+	v = v.Elem()
+	switch b := b.(type) {
+	case *array.Int64Builder:
+		b.Append(v.Int())
+	case *array.Float64Builder:
+		b.Append(v.Float())
+	case *array.BooleanBuilder:
+		b.Append(v.Bool())
+	case *array.StringBuilder:
+		b.Append(v.String())
+	default:
+		b.AppendNull()
+	}
+}
+
+// WriteTable writes rec as a table under key with default options,
+// optionally overridden. Integer, float and boolean columns are written as
+// numbers and booleans, other columns as the text Arrow gives their
+// values, and nulls as null.
+func WriteTable(rec arrow.Record, key string, options ...toon.MarshalOption) ([]byte, error) {
+	schema := rec.Schema()
+	structFields := make([]reflect.StructField, len(schema.Fields()))
+	for i, field := range schema.Fields() {
+		tag := field.Name
+		if j := field.Metadata.FindKey(unitKey); j >= 0 {
+			tag += ",unit=" + field.Metadata.Values()[j]
+		}
+		structFields[i] = reflect.StructField{
+			Name: "F" + strconv.Itoa(i),
+			Type: reflect.PointerTo(cellType(field.Type)),
+			Tag:  reflect.StructTag(fmt.Sprintf("toon:%q", tag)),
+		}
+	}
+
+	n := int(rec.NumRows())
+	rows := reflect.MakeSlice(reflect.SliceOf(reflect.StructOf(structFields)), n, n)
+	for i := range structFields {
+		// Null columns carry no validity bitmap, so IsNull reports false
+		column := rec.Column(i)
+		if column.DataType().ID() == arrow.NULL {
+			continue
+		}
+		for r := 0; r < n; r++ {
+			if column.IsNull(r) {
+				continue
+			}
+			cell := rows.Index(r).Field(i)
+			cell.Set(reflect.New(cell.Type().Elem()))
+			setCell(cell.Elem(), column, r)
+		}
+	}
+
+	v := reflect.New(wrap(rows.Type(), key)).Elem()
+	v.Field(0).Set(rows)
+	return toon.Marshal(v.Interface(), options...)
+}
+
+// cellType returns the Go type cells of dt are written from.
+func cellType(dt arrow.DataType) reflect.Type {
+	switch dt.ID() {
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64:
+		return reflect.TypeOf(int64(0))
+	case arrow.UINT8, arrow.UINT16, arrow.UINT32, arrow.UINT64:
+		return reflect.TypeOf(uint64(0))
+	case arrow.FLOAT32:
+		return reflect.TypeOf(float32(0))
+	case arrow.FLOAT64:
+		return reflect.TypeOf(float64(0))
+	case arrow.BOOL:
+		return reflect.TypeOf(false)
+	}
+	return reflect.TypeOf("")
+}
+
+// setCell stores the value at row r of column in v, whose type cellType
+// chose for the column.
+func setCell(v reflect.Value, column arrow.Array, r int) {
+	switch c := column.(type) {
+	case *array.Int8:
+		v.SetInt(int64(c.Value(r)))
+	case *array.Int16:
+		v.SetInt(int64(c.Value(r)))
+	case *array.Int32:
+		v.SetInt(int64(c.Value(r)))
+	case *array.Int64:
+		v.SetInt(c.Value(r))
+	case *array.Uint8:
+		v.SetUint(uint64(c.Value(r)))
+	case *array.Uint16:
+		v.SetUint(uint64(c.Value(r)))
+	case *array.Uint32:
+		v.SetUint(uint64(c.Value(r)))
+	case *array.Uint64:
+		v.SetUint(c.Value(r))
+	case *array.Float32:
+		v.SetFloat(float64(c.Value(r)))
+	case *array.Float64:
+		v.SetFloat(c.Value(r))
+	case *array.Boolean:
+		v.SetBool(c.Value(r))
+	case *array.String:
+		v.SetString(c.Value(r))
+	default:
+		v.SetString(column.ValueStr(r))
+	}
+}
+
+// wrap returns a struct type holding a single field of type t under key.
+func wrap(t reflect.Type, key string) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{{
+		Name: "F",
+		Type: t,
+		Tag:  reflect.StructTag(fmt.Sprintf("toon:%q", key)),
+	}})
+}
@@ -0,0 +1,136 @@
+package arrowadapt_test
+
+import (
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/l00pss/gotoon/arrowadapt"
+)
+
+const hikes = `trip:
+  owner: ana
+  hikes[3]{id,name,dist(km),sunny,note}:
+    1,Blue Lake,7.5,true,null
+    2,"Ridge, north",,false,null
+    3,7,9,,null
+`
+
+func TestReadTable(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	rec, err := arrowadapt.ReadTable([]byte(hikes), "trip.hikes", mem)
+	if err != nil {
+		t.Fatalf("ReadTable failed: %v", err)
+	}
+	defer rec.Release()
+
+	wantTypes := []arrow.DataType{
+		arrow.PrimitiveTypes.Int64,
+		arrow.BinaryTypes.String,
+		arrow.PrimitiveTypes.Float64,
+		arrow.FixedWidthTypes.Boolean,
+		arrow.Null,
+	}
+	schema := rec.Schema()
+	for i, want := range wantTypes {
+		if got := schema.Field(i).Type; !arrow.TypeEqual(got, want) {
+			t.Errorf("column %d has type %v, want %v", i, got, want)
+		}
+	}
+	dist := schema.Field(2)
+	if dist.Name != "dist" {
+		t.Errorf("column 2 is named %q, want dist", dist.Name)
+	}
+	if unit, ok := dist.Metadata.GetValue("unit"); !ok || unit != "km" {
+		t.Errorf("dist has unit %q, want km", unit)
+	}
+
+	if rec.NumRows() != 3 {
+		t.Fatalf("NumRows = %d, want 3", rec.NumRows())
+	}
+	names := rec.Column(1).(*array.String)
+	if names.Value(1) != "Ridge, north" || names.Value(2) != "7" {
+		t.Errorf("names = %v", names)
+	}
+	km := rec.Column(2).(*array.Float64)
+	if km.Value(0) != 7.5 || !km.IsNull(1) || km.Value(2) != 9 {
+		t.Errorf("dist = %v", km)
+	}
+	if sunny := rec.Column(3).(*array.Boolean); !sunny.Value(0) || sunny.Value(1) || !sunny.IsNull(2) {
+		t.Errorf("sunny = %v", sunny)
+	}
+	if rec.Column(4).NullN() != 3 {
+		t.Errorf("note = %v, want only nulls", rec.Column(4))
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	rec, err := arrowadapt.ReadTable([]byte(hikes), "trip.hikes", memory.DefaultAllocator)
+	if err != nil {
+		t.Fatalf("ReadTable failed: %v", err)
+	}
+	defer rec.Release()
+
+	data, err := arrowadapt.WriteTable(rec, "hikes")
+	if err != nil {
+		t.Fatalf("WriteTable failed: %v", err)
+	}
+	want := `hikes[3]{id,name,dist(km),sunny,note}:
+  1,Blue Lake,7.5,true,null
+  2,"Ridge, north",null,false,null
+  3,"7",9,null,null
+`
+	if string(data) != want {
+		t.Fatalf("WriteTable = %q, want %q", data, want)
+	}
+
+	again, err := arrowadapt.ReadTable(data, "hikes", memory.DefaultAllocator)
+	if err != nil {
+		t.Fatalf("ReadTable failed: %v", err)
+	}
+	defer again.Release()
+	if !array.RecordEqual(again, rec) {
+		t.Errorf("round trip changed the record: %v", again)
+	}
+}
+
+func TestWriteTableTypes(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "n", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "u", Type: arrow.PrimitiveTypes.Uint8},
+		{Name: "f", Type: arrow.PrimitiveTypes.Float32},
+		{Name: "d", Type: arrow.FixedWidthTypes.Date32},
+	}, nil)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	b.Field(0).(*array.Int32Builder).AppendValues([]int32{-4, 5}, nil)
+	b.Field(1).(*array.Uint8Builder).AppendValues([]uint8{255, 0}, []bool{true, false})
+	b.Field(2).(*array.Float32Builder).AppendValues([]float32{0.1, 2}, nil)
+	b.Field(3).(*array.Date32Builder).AppendValues([]arrow.Date32{19783, 19784}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	data, err := arrowadapt.WriteTable(rec, "rows")
+	if err != nil {
+		t.Fatalf("WriteTable failed: %v", err)
+	}
+	want := `rows[2]{n,u,f,d}:
+  -4,255,0.1,2024-03-01
+  5,null,2,2024-03-02
+`
+	if string(data) != want {
+		t.Fatalf("WriteTable = %q, want %q", data, want)
+	}
+}
+
+func TestReadTableErrors(t *testing.T) {
+	if _, err := arrowadapt.ReadTable([]byte(hikes), "trip.owner", memory.DefaultAllocator); err == nil {
+		t.Error("ReadTable accepted a value that is not a table")
+	}
+	if _, err := arrowadapt.ReadTable([]byte(hikes), "trip.hikes[0]", memory.DefaultAllocator); err == nil {
+		t.Error("ReadTable accepted a path into a table")
+	}
+}
@@ -0,0 +1,20 @@
+module github.com/l00pss/gotoon/arrowadapt
+
+go 1.25
+
+require (
+	github.com/apache/arrow-go/v18 v18.2.0
+	github.com/l00pss/gotoon v0.0.0-20261016020315-a13ad6b5c93e
+)
+
+require (
+	github.com/goccy/go-json v0.10.5 // indirect
+	github.com/google/flatbuffers v25.2.10+incompatible // indirect
+	github.com/klauspost/cpuid/v2 v2.2.10 // indirect
+	github.com/zeebo/xxh3 v1.0.2 // indirect
+	golang.org/x/exp v0.0.0-20240909161429-701f63a606c0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+	golang.org/x/xerrors v0.0.0-20240903120638-7835f813f4da // indirect
+)
+
+replace github.com/l00pss/gotoon => ../
@@ -0,0 +1,90 @@
+//go:build js && wasm
+
+// Command toon-wasm exposes the TOON encoder and decoder to JavaScript.
+// Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o toon.wasm ./cmd/toon-wasm
+//
+// and load toon.wasm with the wasm_exec.js shipped in $(go env GOROOT)/lib/wasm.
+// Once running it defines a global toon object:
+//
+//	toon.encode(json, {delimiter: "tab", indent: 2}) // TOON text
+//	toon.decode(text)                                 // JSON text
+//
+// Both return an Error instead of throwing when the input is invalid.
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+var delimiters = map[string]toon.Delimiter{
+	"comma":     toon.DelimiterComma,
+	"tab":       toon.DelimiterTab,
+	"pipe":      toon.DelimiterPipe,
+	"semicolon": toon.DelimiterSemicolon,
+}
+
+func main() {
+	js.Global().Set("toon", js.ValueOf(map[string]any{
+		"encode": js.FuncOf(encode),
+		"decode": js.FuncOf(decode),
+	}))
+	select {}
+}
+
+// encode converts the JSON text in args[0] to TOON, applying the delimiter
+// and indent properties of the optional options object in args[1].
+func encode(_ js.Value, args []js.Value) any {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return jsError("toon.encode: expected a JSON string")
+	}
+	data := []byte(args[0].String())
+	if !json.Valid(data) {
+		return jsError("toon.encode: invalid JSON")
+	}
+
+	var options []toon.MarshalOption
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		if name := args[1].Get("delimiter"); name.Type() == js.TypeString {
+			delim, ok := delimiters[name.String()]
+			if !ok {
+				return jsError("toon.encode: unknown delimiter " + name.String())
+			}
+			options = append(options, toon.WithDelimiter(delim))
+		}
+		if indent := args[1].Get("indent"); indent.Type() == js.TypeNumber {
+			options = append(options, toon.WithIndent(indent.Int()))
+		}
+	}
+
+	out, err := toon.Marshal(json.RawMessage(data), options...)
+	if err != nil {
+		return jsError(err.Error())
+	}
+	return string(out)
+}
+
+// decode converts the TOON text in args[0] to JSON.
+func decode(_ js.Value, args []js.Value) any {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return jsError("toon.decode: expected a TOON string")
+	}
+
+	var v any
+	if err := toon.Unmarshal([]byte(args[0].String()), &v); err != nil {
+		return jsError(err.Error())
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return jsError(err.Error())
+	}
+	return string(out)
+}
+
+func jsError(msg string) js.Value {
+	return js.Global().Get("Error").New(msg)
+}
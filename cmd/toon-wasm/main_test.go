@@ -0,0 +1,28 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	got := encode(js.Undefined(), []js.Value{
+		js.ValueOf(`{"name":"Blue Lake","tags":["a","b"]}`),
+		js.ValueOf(map[string]any{"delimiter": "pipe"}),
+	})
+	if got != "name: Blue Lake\ntags[2|]: a|b\n" {
+		t.Errorf("encode = %v", got)
+	}
+
+	got = decode(js.Undefined(), []js.Value{js.ValueOf("name: Blue Lake\nstats:\n  km: 7.5\n")})
+	if got != `{"name":"Blue Lake","stats":{"km":7.5}}` {
+		t.Errorf("decode = %v", got)
+	}
+
+	err, ok := encode(js.Undefined(), []js.Value{js.ValueOf("{")}).(js.Value)
+	if !ok || !err.InstanceOf(js.Global().Get("Error")) {
+		t.Errorf("encode of invalid JSON = %v, want an Error", err)
+	}
+}
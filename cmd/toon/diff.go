@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+var diffCommand = command{
+	usage:   "toon diff old.toon new.toon",
+	summary: "list the values that differ between two documents",
+	run:     runDiff,
+}
+
+// runDiff prints one line per changed value, prefixed with + for added,
+// - for removed and ~ for modified values. Like diff(1) it exits with
+// status 1 when the documents differ.
+func runDiff(e *env, flags *flag.FlagSet, args []string) int {
+	if err := flags.Parse(args); err != nil {
+		return flagStatus(err)
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	var docs [2][]byte
+	for i, path := range flags.Args() {
+		data, err := e.readInput(path)
+		if err != nil {
+			e.errorf("%v", err)
+			return 2
+		}
+		docs[i] = data
+	}
+
+	changes, err := toon.Diff(docs[0], docs[1])
+	if err != nil {
+		e.errorf("%v", err)
+		return 2
+	}
+	for _, change := range changes {
+		fmt.Fprintln(e.stdout, change)
+	}
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
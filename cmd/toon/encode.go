@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+var encodeCommand = command{
+	usage:   "toon encode [-delimiter comma|tab|pipe|semicolon] [-indent n] [-stats] [file.json]",
+	summary: "convert JSON to TOON",
+	run:     runEncode,
+}
+
+// charsPerToken is the rough number of characters per token used to
+// estimate token counts, as in the README.
+const charsPerToken = 4
+
+var delimiters = map[string]toon.Delimiter{
+	"comma":     toon.DelimiterComma,
+	"tab":       toon.DelimiterTab,
+	"pipe":      toon.DelimiterPipe,
+	"semicolon": toon.DelimiterSemicolon,
+}
+
+// runEncode converts the JSON document in the named file, or standard
+// input, to TOON on standard output. With -stats the sizes of both and the
+// savings are written to standard error, keeping the output pipeable.
+func runEncode(e *env, flags *flag.FlagSet, args []string) int {
+	delimiter := flags.String("delimiter", "comma", "delimiter for arrays: comma, tab, pipe or semicolon")
+	indent := flags.Int("indent", 2, "spaces per indentation level")
+	stats := flags.Bool("stats", false, "print byte and estimated token savings to standard error")
+	if err := flags.Parse(args); err != nil {
+		return flagStatus(err)
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+
+	delim, ok := delimiters[*delimiter]
+	if !ok {
+		e.errorf("unknown delimiter %q", *delimiter)
+		return 2
+	}
+
+	path := "-"
+	if flags.NArg() == 1 {
+		path = flags.Arg(0)
+	}
+	data, err := e.readInput(path)
+	if err != nil {
+		e.errorf("%v", err)
+		return 2
+	}
+	if !json.Valid(data) {
+		e.errorf("%s: invalid JSON", path)
+		return 2
+	}
+
+	out, err := toon.Marshal(json.RawMessage(data), toon.WithDelimiter(delim), toon.WithIndent(*indent))
+	if err != nil {
+		e.errorf("%v", err)
+		return 2
+	}
+	e.stdout.Write(out)
+
+	if *stats {
+		printStats(e, len(data), len(out))
+	}
+	return 0
+}
+
+func printStats(e *env, jsonBytes, toonBytes int) {
+	jsonTokens := (jsonBytes + charsPerToken - 1) / charsPerToken
+	toonTokens := (toonBytes + charsPerToken - 1) / charsPerToken
+
+	change := "smaller"
+	savings := 0.0
+	if jsonBytes > 0 {
+		savings = float64(jsonBytes-toonBytes) / float64(jsonBytes) * 100
+	}
+	if savings < 0 {
+		change, savings = "larger", -savings
+	}
+	fmt.Fprintf(e.stderr, "json: %d bytes, ~%d tokens\n", jsonBytes, jsonTokens)
+	fmt.Fprintf(e.stderr, "toon: %d bytes, ~%d tokens (%.1f%% %s)\n", toonBytes, toonTokens, savings, change)
+}
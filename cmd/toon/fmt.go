@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+var fmtCommand = command{
+	usage:   "toon fmt [-w] [-l] [-check] [file ...]",
+	summary: "rewrite documents in the canonical layout",
+	run:     runFmt,
+}
+
+// runFmt formats the named files, or standard input when there are none,
+// printing the result unless -w, -l or -check says otherwise.
+func runFmt(e *env, flags *flag.FlagSet, args []string) int {
+	write := flags.Bool("w", false, "write the result back to each file instead of printing it")
+	list := flags.Bool("l", false, "list files whose formatting differs")
+	check := flags.Bool("check", false, "exit with status 1 if any file is not formatted, without changing it")
+	if err := flags.Parse(args); err != nil {
+		return flagStatus(err)
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			e.errorf("cannot use -w with standard input")
+			return 2
+		}
+		data, err := io.ReadAll(e.stdin)
+		if err != nil {
+			e.errorf("%v", err)
+			return 2
+		}
+		return formatFile(e, "<stdin>", data, false, *list, *check)
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			e.errorf("%v", err)
+			status = 2
+			continue
+		}
+		status = max(status, formatFile(e, path, data, *write, *list, *check))
+	}
+	return status
+}
+
+func formatFile(e *env, path string, data []byte, write, list, check bool) int {
+	formatted, err := toon.Format(data)
+	if err != nil {
+		e.errorf("%s: %v", path, err)
+		return 2
+	}
+
+	changed := !bytes.Equal(data, formatted)
+	if changed && (list || check) {
+		fmt.Fprintln(e.stdout, path)
+	}
+
+	switch {
+	case check:
+		if changed {
+			return 1
+		}
+	case write:
+		if changed {
+			if err := writeFile(path, formatted); err != nil {
+				e.errorf("%v", err)
+				return 2
+			}
+		}
+	case !list:
+		e.stdout.Write(formatted)
+	}
+	return 0
+}
+
+// writeFile replaces the content of path, keeping its permissions.
+func writeFile(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, info.Mode().Perm())
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+var getCommand = command{
+	usage:   "toon get file.toon path ...",
+	summary: "print the values at paths such as hikes[0].name",
+	run:     runGet,
+}
+
+// runGet prints the value at each path on a line of its own, with quotes
+// and escapes resolved. It exits with status 1 if any path is missing or
+// leads to a block rather than a value.
+func runGet(e *env, flags *flag.FlagSet, args []string) int {
+	if err := flags.Parse(args); err != nil {
+		return flagStatus(err)
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return 2
+	}
+
+	data, err := e.readInput(flags.Arg(0))
+	if err != nil {
+		e.errorf("%v", err)
+		return 2
+	}
+	paths := flags.Args()[1:]
+	values, err := toon.Extract(data, paths)
+	if err != nil {
+		e.errorf("%v", err)
+		return 2
+	}
+
+	status := 0
+	for _, path := range paths {
+		value, ok := values[path]
+		if !ok {
+			e.errorf("%s: no value at %s", flags.Arg(0), path)
+			status = 1
+			continue
+		}
+		fmt.Fprintln(e.stdout, value)
+	}
+	return status
+}
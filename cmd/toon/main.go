@@ -0,0 +1,120 @@
+// Command toon works with TOON documents from the shell.
+//
+// Usage:
+//
+//	toon fmt [-w] [-l] [-check] [file ...]
+//	toon diff old.toon new.toon
+//	toon get file.toon path ...
+//	toon encode [-delimiter tab] [-stats] [file.json]
+//	toon serve [-addr host:port] [-max-bytes n]
+//
+// Run "toon help <command>" for the flags of a command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// command is a subcommand. run returns the exit status: 0 on success, 1
+// when a check fails and 2 on errors.
+type command struct {
+	usage   string
+	summary string
+	run     func(e *env, flags *flag.FlagSet, args []string) int
+}
+
+var commands = map[string]command{
+	"diff":   diffCommand,
+	"encode": encodeCommand,
+	"fmt":    fmtCommand,
+	"get":    getCommand,
+	"serve":  serveCommand,
+}
+
+// env holds the standard streams, so commands can run in tests.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (e *env) errorf(format string, args ...any) {
+	fmt.Fprintf(e.stderr, "toon: "+format+"\n", args...)
+}
+
+// readInput reads the named file, or standard input for "-".
+func (e *env) readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(e.stdin)
+	}
+	return os.ReadFile(path)
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}))
+}
+
+func run(args []string, e *env) int {
+	if len(args) == 0 {
+		usage(e.stderr)
+		return 2
+	}
+
+	name, args := args[0], args[1:]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		if len(args) > 0 {
+			if cmd, ok := commands[args[0]]; ok {
+				cmd.run(e, newFlagSet(e, cmd), []string{"-h"})
+				return 0
+			}
+		}
+		usage(e.stdout)
+		return 0
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		e.errorf("unknown command %q", name)
+		usage(e.stderr)
+		return 2
+	}
+	return cmd.run(e, newFlagSet(e, cmd), args)
+}
+
+func newFlagSet(e *env, cmd command) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd.usage, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s\n\nFlags:\n", cmd.usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// flagStatus is the exit status for a failed flag parse: 0 after -h
+// printed the usage, 2 otherwise.
+func flagStatus(err error) int {
+	if err == flag.ErrHelp {
+		return 0
+	}
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: toon <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+}
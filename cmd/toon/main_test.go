@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCommand runs the CLI with args and stdin, returning its exit status
+// and output.
+func runCommand(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	status := run(args, &env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr})
+	return status, stdout.String(), stderr.String()
+}
+
+func TestUnknownCommand(t *testing.T) {
+	status, _, stderr := runCommand(t, "", "frobnicate")
+	if status != 2 || !strings.Contains(stderr, `unknown command "frobnicate"`) {
+		t.Errorf("status %d, stderr %q", status, stderr)
+	}
+}
+
+func TestFmt(t *testing.T) {
+	status, stdout, _ := runCommand(t, "name:   Alice\ntags[2]: a , b\n", "fmt")
+	if status != 0 || stdout != "name: Alice\ntags[2]: a,b\n" {
+		t.Errorf("fmt from stdin: status %d, output %q", status, stdout)
+	}
+
+	dir := t.TempDir()
+	messy := filepath.Join(dir, "messy.toon")
+	clean := filepath.Join(dir, "clean.toon")
+	os.WriteFile(messy, []byte("a:\n    b: 1\n"), 0o600)
+	os.WriteFile(clean, []byte("a:\n  b: 1\n"), 0o600)
+
+	status, stdout, _ = runCommand(t, "", "fmt", "-check", messy, clean)
+	if status != 1 || stdout != messy+"\n" {
+		t.Errorf("fmt -check: status %d, output %q", status, stdout)
+	}
+
+	status, _, _ = runCommand(t, "", "fmt", "-w", messy)
+	if data, _ := os.ReadFile(messy); status != 0 || string(data) != "a:\n  b: 1\n" {
+		t.Errorf("fmt -w: status %d, file %q", status, data)
+	}
+	if info, _ := os.Stat(messy); info.Mode().Perm() != 0o600 {
+		t.Errorf("fmt -w changed permissions to %v", info.Mode().Perm())
+	}
+
+	status, _, _ = runCommand(t, "", "fmt", "-check", messy, clean)
+	if status != 0 {
+		t.Errorf("fmt -check after -w: status %d", status)
+	}
+
+	status, _, stderr := runCommand(t, "not toon\n", "fmt")
+	if status != 2 || !strings.Contains(stderr, "<stdin>") {
+		t.Errorf("fmt of invalid input: status %d, stderr %q", status, stderr)
+	}
+}
+
+func TestDiffAndGet(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.toon")
+	os.WriteFile(old, []byte("context:\n  task: \"a, b\"\nhikes[2]{id,name}:\n  1,Blue\n  2,Red\n"), 0o600)
+	updated := "context:\n  task: \"a, b\"\nhikes[2]{id,name}:\n  1,Blue\n  2,Green\n"
+
+	status, stdout, _ := runCommand(t, updated, "diff", old, "-")
+	if status != 1 || stdout != "~ hikes[1].name: \"Red\" -> \"Green\"\n" {
+		t.Errorf("diff: status %d, output %q", status, stdout)
+	}
+
+	status, stdout, _ = runCommand(t, "", "diff", old, old)
+	if status != 0 || stdout != "" {
+		t.Errorf("diff of identical files: status %d, output %q", status, stdout)
+	}
+
+	status, stdout, _ = runCommand(t, "", "get", old, "context.task", "hikes[1].name")
+	if status != 0 || stdout != "a, b\nRed\n" {
+		t.Errorf("get: status %d, output %q", status, stdout)
+	}
+
+	status, _, stderr := runCommand(t, "", "get", old, "hikes[5].name")
+	if status != 1 || !strings.Contains(stderr, "no value at hikes[5].name") {
+		t.Errorf("get of missing path: status %d, stderr %q", status, stderr)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	input := `{"name": "Blue Lake", "tags": ["lake", "easy"], "stats": {"km": 7.5, "sunny": true}}`
+
+	status, stdout, stderr := runCommand(t, input, "encode", "--delimiter", "pipe", "--stats")
+	if status != 0 {
+		t.Fatalf("encode: status %d, stderr %q", status, stderr)
+	}
+	want := "name: Blue Lake\nstats:\n  km: 7.5\n  sunny: true\ntags[2|]: lake|easy\n"
+	if stdout != want {
+		t.Errorf("encode output = %q, want %q", stdout, want)
+	}
+	if stderr != "json: 84 bytes, ~21 tokens\ntoon: 67 bytes, ~17 tokens (20.2% smaller)\n" {
+		t.Errorf("encode stats = %q", stderr)
+	}
+
+	status, _, stderr = runCommand(t, "{", "encode")
+	if status != 2 || !strings.Contains(stderr, "invalid JSON") {
+		t.Errorf("encode of invalid JSON: status %d, stderr %q", status, stderr)
+	}
+
+	status, _, stderr = runCommand(t, "{}", "encode", "-delimiter", "space")
+	if status != 2 || !strings.Contains(stderr, `unknown delimiter "space"`) {
+		t.Errorf("encode with unknown delimiter: status %d, stderr %q", status, stderr)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+var serveCommand = command{
+	usage:   "toon serve [-addr host:port] [-max-bytes n]",
+	summary: "serve JSON to TOON conversion over HTTP",
+	run:     runServe,
+}
+
+// runServe listens for POST /encode, which turns a JSON body into TOON,
+// and POST /decode, which turns a TOON body into JSON. /encode accepts the
+// delimiter and indent query parameters of toon encode.
+func runServe(e *env, flags *flag.FlagSet, args []string) int {
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	maxBytes := flags.Int64("max-bytes", 1<<20, "largest request body accepted, in bytes")
+	if err := flags.Parse(args); err != nil {
+		return flagStatus(err)
+	}
+	if flags.NArg() > 0 || *maxBytes <= 0 {
+		flags.Usage()
+		return 2
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           newHandler(*maxBytes),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	e.errorf("listening on %s", *addr)
+	if err := server.ListenAndServe(); err != nil {
+		e.errorf("%v", err)
+		return 2
+	}
+	return 0
+}
+
+func newHandler(maxBytes int64) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /encode", func(w http.ResponseWriter, r *http.Request) {
+		data, ok := readBody(w, r, maxBytes)
+		if !ok {
+			return
+		}
+		if !json.Valid(data) {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		var options []toon.MarshalOption
+		if name := r.URL.Query().Get("delimiter"); name != "" {
+			delim, ok := delimiters[name]
+			if !ok {
+				http.Error(w, "unknown delimiter "+strconv.Quote(name), http.StatusBadRequest)
+				return
+			}
+			options = append(options, toon.WithDelimiter(delim))
+		}
+		if indent := r.URL.Query().Get("indent"); indent != "" {
+			n, err := strconv.Atoi(indent)
+			if err != nil {
+				http.Error(w, "invalid indent "+strconv.Quote(indent), http.StatusBadRequest)
+				return
+			}
+			options = append(options, toon.WithIndent(n))
+		}
+
+		out, err := toon.Marshal(json.RawMessage(data), options...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(out)
+	})
+
+	mux.HandleFunc("POST /decode", func(w http.ResponseWriter, r *http.Request) {
+		data, ok := readBody(w, r, maxBytes)
+		if !ok {
+			return
+		}
+
+		var v any
+		if err := toon.Unmarshal(data, &v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		out, err := json.Marshal(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(out, '\n'))
+	})
+	return mux
+}
+
+// readBody reads the request body, answering 413 when it exceeds maxBytes.
+func readBody(w http.ResponseWriter, r *http.Request, maxBytes int64) ([]byte, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		http.Error(w, "request body exceeds "+strconv.FormatInt(maxBytes, 10)+" bytes", http.StatusRequestEntityTooLarge)
+		return nil, false
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return data, true
+}
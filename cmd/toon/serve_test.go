@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	server := httptest.NewServer(newHandler(64))
+	defer server.Close()
+
+	post := func(path, body string) (int, string) {
+		t.Helper()
+		resp, err := http.Post(server.URL+path, "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	status, body := post("/encode?delimiter=pipe", `{"name":"Blue Lake","tags":["a","b"]}`)
+	if status != http.StatusOK || body != "name: Blue Lake\ntags[2|]: a|b\n" {
+		t.Errorf("/encode: %d %q", status, body)
+	}
+
+	status, body = post("/decode", "name: Blue Lake\nstats:\n  km: 7.5\n")
+	if status != http.StatusOK || body != `{"name":"Blue Lake","stats":{"km":7.5}}`+"\n" {
+		t.Errorf("/decode: %d %q", status, body)
+	}
+
+	if status, _ = post("/encode", "{"); status != http.StatusBadRequest {
+		t.Errorf("/encode of invalid JSON: %d", status)
+	}
+	if status, _ = post("/encode", `{"text":"`+strings.Repeat("x", 64)+`"}`); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("/encode of oversized body: %d", status)
+	}
+
+	resp, err := http.Get(server.URL + "/encode")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /encode: %d", resp.StatusCode)
+	}
+}
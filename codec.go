@@ -0,0 +1,62 @@
+package toon
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// maxPooledBuffer is the largest encoding buffer a Codec keeps for reuse,
+// so one huge document does not pin its memory for the life of the pool.
+const maxPooledBuffer = 64 << 10
+
+// Codec marshals and unmarshals with fixed options, reusing encoding
+// buffers across calls. It is safe for concurrent use and meant to be
+// created once and shared, for example by the handlers of a server.
+type Codec struct {
+	marshal   MarshalOptions
+	unmarshal UnmarshalOptions
+	encoders  sync.Pool
+}
+
+// NewCodec returns a Codec using mopts and uopts. Since calls may run
+// concurrently, uopts must not collect Warnings; any FieldFilter,
+// TransformValue or OnValue callback must itself be safe for concurrent
+// use.
+func NewCodec(mopts MarshalOptions, uopts UnmarshalOptions) (*Codec, error) {
+	if err := mopts.validate(); err != nil {
+		return nil, err
+	}
+	if uopts.Warnings != nil {
+		return nil, fmt.Errorf("%w: a Codec cannot collect Warnings shared between concurrent calls", ErrInvalidOptions)
+	}
+	return &Codec{marshal: mopts, unmarshal: uopts}, nil
+}
+
+// Marshal is MarshalWithOptions with the Codec's options.
+func (c *Codec) Marshal(v any) ([]byte, error) {
+	e, ok := c.encoders.Get().(*encoder)
+	if !ok {
+		e = newEncoder(c.marshal)
+	}
+	defer c.release(e)
+
+	data, err := e.encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(data), nil
+}
+
+// Unmarshal is UnmarshalWithOptions with the Codec's options.
+func (c *Codec) Unmarshal(data []byte, v any) error {
+	return UnmarshalWithOptions(data, v, c.unmarshal)
+}
+
+func (c *Codec) release(e *encoder) {
+	if e.buf.Cap() > maxPooledBuffer {
+		return
+	}
+	e.reset()
+	c.encoders.Put(e)
+}
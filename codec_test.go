@@ -0,0 +1,104 @@
+package toon_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestCodecConcurrentUse(t *testing.T) {
+	codec, err := toon.NewCodec(toon.DefaultMarshalOptions(), toon.DefaultUnmarshalOptions())
+	if err != nil {
+		t.Fatalf("NewCodec failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				in := HikesData{
+					Context: Context{Task: fmt.Sprintf("task %d-%d", g, i), Location: "Boulder"},
+					Friends: []string{"ana", fmt.Sprint(g)},
+					Hikes:   []Hike{{ID: i, Name: "Blue Lake", DistanceKm: float64(g) + 0.5}},
+				}
+
+				data, err := codec.Marshal(in)
+				if err != nil {
+					t.Errorf("Marshal failed: %v", err)
+					return
+				}
+				want, _ := toon.Marshal(in)
+				if string(data) != string(want) {
+					t.Errorf("Codec.Marshal = %q, want %q", data, want)
+					return
+				}
+
+				var out HikesData
+				if err := codec.Unmarshal(data, &out); err != nil {
+					t.Errorf("Unmarshal failed: %v", err)
+					return
+				}
+				if !reflect.DeepEqual(out, in) {
+					t.Errorf("round trip = %+v, want %+v", out, in)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestCodecOptions(t *testing.T) {
+	opts := toon.DefaultMarshalOptions()
+	opts.Indent = 0
+	if _, err := toon.NewCodec(opts, toon.DefaultUnmarshalOptions()); !errors.Is(err, toon.ErrInvalidOptions) {
+		t.Errorf("NewCodec with zero indent: %v", err)
+	}
+
+	uopts := toon.DefaultUnmarshalOptions()
+	uopts.Warnings = new([]toon.Warning)
+	if _, err := toon.NewCodec(toon.DefaultMarshalOptions(), uopts); !errors.Is(err, toon.ErrInvalidOptions) {
+		t.Errorf("NewCodec with Warnings: %v", err)
+	}
+
+	// Output must not alias the pooled buffer
+	codec, _ := toon.NewCodec(toon.DefaultMarshalOptions(), toon.DefaultUnmarshalOptions())
+	first, _ := codec.Marshal(map[string]int{"a": 1})
+	codec.Marshal(map[string]int{"b": 2})
+	if string(first) != "a: 1\n" {
+		t.Errorf("first result changed to %q", first)
+	}
+}
+
+func TestConcurrentTopLevelAPI(t *testing.T) {
+	data := []byte("context:\n  task: hiking\nfriends[2]: ana,luis\nhikes[1]{id,name}:\n  1,Blue Lake\n")
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			toon.RegisterTypeOptions(reflect.TypeOf(Hike{}), toon.TypeOptions{})
+			var out HikesData
+			if err := toon.Unmarshal(data, &out); err != nil {
+				t.Errorf("Unmarshal failed: %v", err)
+			}
+			if _, err := toon.Marshal(out); err != nil {
+				t.Errorf("Marshal failed: %v", err)
+			}
+			if err := toon.Validate(data, toon.LevelSchema(HikesData{})); err != nil {
+				t.Errorf("Validate failed: %v", err)
+			}
+			if _, err := toon.Extract(data, []string{"hikes[0].name"}); err != nil {
+				t.Errorf("Extract failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,77 @@
+package toon
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// The weak* helpers are the WeaklyTypedInput fallbacks for scalar values
+// that failed to parse as their target kind. Without the option they
+// return err unchanged.
+
+func (d *decoder) weakInt(s string, kind reflect.Kind, err error) (int64, error) {
+	f, ok := d.weakNumber(s, kind)
+	if !ok || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, err
+	}
+	return int64(f), nil
+}
+
+func (d *decoder) weakUint(s string, kind reflect.Kind, err error) (uint64, error) {
+	f, ok := d.weakNumber(s, kind)
+	if !ok || f < 0 || f >= math.MaxUint64 {
+		return 0, err
+	}
+	return uint64(f), nil
+}
+
+func (d *decoder) weakFloat(s string, kind reflect.Kind, err error) (float64, error) {
+	f, ok := d.weakNumber(s, kind)
+	if !ok {
+		return 0, err
+	}
+	return f, nil
+}
+
+func (d *decoder) weakBool(s string, err error) (bool, error) {
+	if !d.opts.WeaklyTypedInput {
+		return false, err
+	}
+	if s == "" {
+		return false, nil
+	}
+	f, perr := strconv.ParseFloat(s, 64)
+	if perr != nil {
+		return false, err
+	}
+	d.warn(d.consumed, "value %s coerced to bool", s)
+	return f != 0, nil
+}
+
+// weakNumber reads s as a float, a boolean (1 or 0) or an empty value (0).
+func (d *decoder) weakNumber(s string, kind reflect.Kind) (float64, bool) {
+	if !d.opts.WeaklyTypedInput {
+		return 0, false
+	}
+
+	switch s {
+	case "":
+		return 0, true
+	case "true":
+		d.warn(d.consumed, "value %s coerced to %s", s, kind)
+		return 1, true
+	case "false":
+		d.warn(d.consumed, "value %s coerced to %s", s, kind)
+		return 0, true
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	if kind != reflect.Float32 && kind != reflect.Float64 {
+		d.warn(d.consumed, "value %s truncated to %s", s, kind)
+	}
+	return f, true
+}
@@ -0,0 +1,49 @@
+package toon_test
+
+import (
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestWeaklyTypedInput(t *testing.T) {
+	type Record struct {
+		Count  int      `toon:"count"`
+		Size   uint     `toon:"size"`
+		Ratio  float64  `toon:"ratio"`
+		Active bool     `toon:"active"`
+		Label  string   `toon:"label"`
+		Tags   []string `toon:"tags"`
+	}
+
+	data := []byte("count: 3.9\nsize: true\nratio: false\nactive: 2\nlabel: 42\ntags: solo\n")
+
+	var strict Record
+	if err := toon.Unmarshal(data, &strict); err == nil {
+		t.Fatal("expected error without WeaklyTypedInput")
+	}
+
+	opts := toon.DefaultUnmarshalOptions()
+	opts.WeaklyTypedInput = true
+	var warnings []toon.Warning
+	opts.Warnings = &warnings
+
+	var out Record
+	if err := toon.UnmarshalWithOptions(data, &out, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := Record{Count: 3, Size: 1, Ratio: 0, Active: true, Label: "42", Tags: []string{"solo"}}
+	if out.Count != want.Count || out.Size != want.Size || out.Ratio != want.Ratio ||
+		out.Active != want.Active || out.Label != want.Label ||
+		len(out.Tags) != 1 || out.Tags[0] != "solo" {
+		t.Errorf("got %+v, want %+v", out, want)
+	}
+	if len(warnings) == 0 {
+		t.Error("expected coercion warnings")
+	}
+
+	var bad Record
+	if err := toon.UnmarshalWithOptions([]byte("count: many\n"), &bad, opts); err == nil {
+		t.Error("expected error for non-numeric count")
+	}
+}
@@ -0,0 +1,214 @@
+package toon
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+)
+
+// orderColumns moves the columns in which more than half of rows share one
+// value behind the others, keeping the relative order within both groups.
+func (e *encoder) orderColumns(fields []structField, rows []reflect.Value) []structField {
+	if len(rows) < 2 {
+		return fields
+	}
+
+	var varied, repetitive []structField
+	for _, field := range fields {
+		counts := make(map[string]int)
+		top := 0
+		for _, row := range rows {
+			text := ""
+			if row.IsValid() {
+				if fv, ok := field.valueOf(row); ok {
+					text = e.cellText(e.encodedFieldValue(field, fv))
+				}
+			}
+			counts[text]++
+			top = max(top, counts[text])
+		}
+
+		if top*2 > len(rows) {
+			repetitive = append(repetitive, field)
+		} else {
+			varied = append(varied, field)
+		}
+	}
+	return append(varied, repetitive...)
+}
+
+// foldedColumnSep joins a table's key and a column name in the lines that
+// hold folded constant columns, as in hikes.*.season: spring.
+const foldedColumnSep = ".*."
+
+// encodeFoldedTable writes the rows of a table, first lifting every column
+// that holds the same non-blank value in all rows out into a
+// "key.*.column: value" line above it. At least one column stays in the
+// table so it keeps its tabular header.
+func (e *encoder) encodeFoldedTable(rows []reflect.Value, fields []structField, depth int, key string) error {
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		e.pushIndex(i)
+		rowCells, err := e.rowCells(row, fields)
+		if err != nil {
+			return err
+		}
+		e.popPath()
+		cells[i] = rowCells
+	}
+
+	var kept []int
+	var names []string
+	for j, field := range fields {
+		if isConstantColumn(cells, j) && (len(kept) > 0 || j < len(fields)-1) {
+			e.writeIndent(depth)
+			e.buf.WriteString(key + foldedColumnSep + e.fieldKey(field.column) + e.opts.KeyValueSeparator + cells[0][j] + "\n")
+			continue
+		}
+		kept = append(kept, j)
+		names = append(names, e.columnName(field))
+	}
+
+	e.writeIndent(depth)
+	e.writeHeader(key, len(rows))
+	e.writeHeaderFields(names)
+	for _, row := range cells {
+		e.writeIndent(depth + 1)
+		for i, j := range kept {
+			if i > 0 {
+				e.buf.WriteString(string(e.opts.Delimiter))
+			}
+			e.buf.WriteString(row[j])
+		}
+		e.buf.WriteString("\n")
+	}
+	return nil
+}
+
+// rowCells renders the cells of one table row. A nil row has blank cells.
+func (e *encoder) rowCells(v reflect.Value, fields []structField) ([]string, error) {
+	cells := make([]string, len(fields))
+	if !v.IsValid() {
+		return cells, nil
+	}
+
+	defer e.enterType(v.Type())()
+	for i, field := range fields {
+		fieldValue, ok := field.valueOf(v)
+		if !ok {
+			continue
+		}
+
+		e.pushPath(field.name)
+		text, err := e.renderLeaf(e.encodedFieldValue(field, fieldValue))
+		if err != nil {
+			return nil, err
+		}
+		e.popPath()
+		cells[i] = text
+	}
+	return cells, nil
+}
+
+// renderLeaf returns what writeLeafValue would write for v.
+func (e *encoder) renderLeaf(v reflect.Value) (string, error) {
+	saved := e.buf
+	e.buf = bytes.Buffer{}
+	defer func() { e.buf = saved }()
+
+	err := e.writeLeafValue(v)
+	return e.buf.String(), err
+}
+
+func isConstantColumn(cells [][]string, j int) bool {
+	if len(cells) < 2 || cells[0][j] == "" {
+		return false
+	}
+	for _, row := range cells[1:] {
+		if row[j] != cells[0][j] {
+			return false
+		}
+	}
+	return true
+}
+
+// sparseField replaces the column list in the header of a sparse table,
+// whose rows hold column=value pairs for their non-zero fields only.
+const sparseField = "="
+
+// isSparseTable reports whether more than half of the cells of a table of
+// rows are zero values.
+func isSparseTable(rows []reflect.Value, fields []structField) bool {
+	zeros, total := 0, len(rows)*len(fields)
+	for _, row := range rows {
+		for _, field := range fields {
+			if !row.IsValid() {
+				zeros++
+			} else if fv, ok := field.valueOf(row); !ok || fv.IsZero() {
+				zeros++
+			}
+		}
+	}
+	return zeros*2 > total
+}
+
+func (e *encoder) encodeSparseTable(rows []reflect.Value, fields []structField, depth int, key string) error {
+	e.writeIndent(depth)
+	e.writeHeader(key, len(rows))
+	e.buf.WriteString("{" + sparseField + "}:\n")
+
+	for i, row := range rows {
+		e.writeIndent(depth + 1)
+		e.pushIndex(i)
+		if err := e.writeSparseRow(row, fields); err != nil {
+			return err
+		}
+		e.popPath()
+		e.buf.WriteString("\n")
+	}
+	return nil
+}
+
+// writeSparseRow writes the non-zero fields of v as column=value pairs. A
+// row with none writes its first field so that the line is not blank.
+func (e *encoder) writeSparseRow(v reflect.Value, fields []structField) error {
+	type cell struct {
+		field structField
+		value reflect.Value
+	}
+
+	var cells []cell
+	if v.IsValid() {
+		defer e.enterType(v.Type())()
+		for _, field := range fields {
+			if fv, ok := field.valueOf(v); ok && !fv.IsZero() {
+				cells = append(cells, cell{field, fv})
+			}
+		}
+	}
+	if len(cells) == 0 && len(fields) > 0 {
+		cells = append(cells, cell{field: fields[0]})
+	}
+
+	for i, c := range cells {
+		if i > 0 {
+			e.buf.WriteString(string(e.opts.Delimiter))
+		}
+
+		name := e.fieldKey(c.field.column)
+		if needsQuoting(name) || strings.Contains(name, sparseField) {
+			name = quoteString(name)
+		}
+		e.buf.WriteString(name)
+		e.buf.WriteString(sparseField)
+
+		if c.value.IsValid() {
+			e.pushPath(c.field.name)
+			if err := e.writeLeafValue(e.encodedFieldValue(c.field, c.value)); err != nil {
+				return err
+			}
+			e.popPath()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package toon
+
+import (
+	"bytes"
+	"strings"
+)
+
+// A '#' starts a comment only at the start of a line or after a space or
+// tab outside quotes, running to the end of the line. Elsewhere, as in
+// x#y or "a # b", it is part of the value. The encoder quotes strings that
+// would otherwise read back as holding a comment.
+
+// commentStart returns the index of the '#' starting a comment after the
+// content of line, or -1. Quotes open at the start of the line or of a
+// value or cell, as they do when splitting cells.
+func commentStart(line string) int {
+	inQuotes := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case inQuotes && c == '\\':
+			i++
+		case inQuotes && c == '"':
+			inQuotes = false
+		case c == '"' && (i == 0 || strings.IndexByte(" \t:=,|;{", line[i-1]) >= 0):
+			inQuotes = true
+		case !inQuotes && c == '#' && i > 0 && (line[i-1] == ' ' || line[i-1] == '\t'):
+			return i
+		}
+	}
+	return -1
+}
+
+// stripComment removes a comment after the content of line along with the
+// whitespace before it. Lines that are comments as a whole are returned as
+// they are.
+func stripComment(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return line
+	}
+	if i := commentStart(line); i >= 0 {
+		return strings.TrimRight(line[:i], " \t")
+	}
+	return line
+}
+
+// holdsComment reports whether s, written bare, would be read as starting
+// or holding a comment.
+func holdsComment(s string) bool {
+	return strings.HasPrefix(s, "#") || strings.Contains(s, " #") || strings.Contains(s, "\t#")
+}
+
+// comment returns the comment the toonComment tag gives the field, on a
+// single line.
+func (f structField) comment() string {
+	return strings.Join(strings.Fields(f.field.Tag.Get("toonComment")), " ")
+}
+
+// writeComment appends comment to the first line written from offset start
+// of the output, if any.
+func (e *encoder) writeComment(start int, comment string) {
+	if comment == "" || e.buf.Len() == start {
+		return
+	}
+	end := len(e.buf.Bytes())
+	if i := bytes.IndexByte(e.buf.Bytes()[start:], '\n'); i >= 0 {
+		end = start + i
+	}
+	rest := append([]byte(nil), e.buf.Bytes()[end:]...)
+	e.buf.Truncate(end)
+	e.buf.WriteString(" # " + comment)
+	e.buf.Write(rest)
+}
+
+// stripComments removes comments after content from every line before
+// decoding.
+func (d *decoder) stripComments() {
+	for i, line := range d.lines {
+		if strings.IndexByte(line, '#') >= 0 {
+			d.lines[i] = stripComment(line)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package toon_test
+
+import (
+	"reflect"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestHashInValues(t *testing.T) {
+	type Color struct {
+		Name string `toon:"name"`
+		Hex  string `toon:"hex"`
+	}
+	type Palette struct {
+		Title  string   `toon:"title"`
+		Tags   []string `toon:"tags"`
+		Colors []Color  `toon:"colors"`
+	}
+
+	in := Palette{
+		Title:  "#1 pick # of the day",
+		Tags:   []string{"#warm", "c#", "a # b"},
+		Colors: []Color{{Name: "#red", Hex: "#f00"}, {Name: "blue", Hex: "x#y"}},
+	}
+	data, err := toon.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `title: "#1 pick # of the day"
+tags[3]: "#warm",c#,"a # b"
+colors[2]{name,hex}:
+  "#red","#f00"
+  blue,x#y
+`
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+
+	var out Palette
+	if err := toon.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestTrailingComments(t *testing.T) {
+	type Hike struct {
+		Name string `toon:"name"`
+		Km   int    `toon:"km"`
+	}
+	type Config struct {
+		Host  string   `toon:"host"`
+		Port  int      `toon:"port"`
+		Path  string   `toon:"path"`
+		Tags  []string `toon:"tags"`
+		Hikes []Hike   `toon:"hikes"`
+	}
+
+	data := []byte(`# settings
+host: "db # primary" # quoted hash stays
+port: 5432	# tab before the comment
+path: /a#b # no space, no comment
+tags[2]: x,y # two tags
+hikes[2]{name,km}: # table
+  Lake,7 # short
+  # a whole line
+  Ridge,9
+`)
+	var out Config
+	if err := toon.UnmarshalWithOptions(data, &out, toon.UnmarshalOptions{Strict: true}); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := Config{
+		Host:  "db # primary",
+		Port:  5432,
+		Path:  "/a#b",
+		Tags:  []string{"x", "y"},
+		Hikes: []Hike{{"Lake", 7}, {"Ridge", 9}},
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("Unmarshal = %+v, want %+v", out, want)
+	}
+
+	doc, err := toon.ParseDocument(data)
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	for path, want := range map[string]string{"host": "db # primary", "port": "5432", "tags[1]": "y", "hikes[0].km": "7"} {
+		if got, ok := doc.Get(path); !ok || got != want {
+			t.Errorf("Get(%q) = %q, %v, want %q", path, got, ok, want)
+		}
+	}
+}
+
+func TestHashAfterEqualsSeparator(t *testing.T) {
+	type Config struct {
+		Note string   `toon:"note"`
+		Tags []string `toon:"tags"`
+	}
+
+	in := Config{Note: "a #b", Tags: []string{"c #d"}}
+	for _, sep := range []string{"=", " = "} {
+		data, err := toon.Marshal(in, toon.WithKeyValueSeparator(sep))
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var out Config
+		if err := toon.Unmarshal(data, &out); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if !reflect.DeepEqual(out, in) {
+			t.Errorf("separator %q: round trip of %q = %+v, want %+v", sep, data, out, in)
+		}
+	}
+}
+
+func TestCommentTag(t *testing.T) {
+	type Hike struct {
+		Name string `toon:"name" toonComment:"as signposted"`
+		Km   int    `toon:"km"`
+	}
+	type Config struct {
+		Port    int    `toon:"port" toonComment:"default 5432"`
+		Empty   string `toon:"empty,omitempty" toonComment:"never written"`
+		Hikes   []Hike `toon:"hikes" toonComment:"last season,\n newest first"`
+		Longest Hike   `toon:"longest" toonComment:"by distance"`
+		Routes  []any  `toon:"routes"`
+	}
+
+	in := Config{
+		Port:    5432,
+		Hikes:   []Hike{{"Lake", 7}, {"Ridge", 9}},
+		Longest: Hike{"Ridge", 9},
+		Routes:  []any{Hike{"Loop", 5}},
+	}
+	data, err := toon.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `port: 5432 # default 5432
+hikes[2]{name,km}: # last season, newest first
+  Lake,7
+  Ridge,9
+longest: # by distance
+  name: Ridge # as signposted
+  km: 9
+routes[1]:
+  - name: Loop # as signposted
+    km: 5
+`
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+
+	var out Config
+	if err := toon.UnmarshalWithOptions(data, &out, toon.UnmarshalOptions{Strict: true}); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Port != in.Port || !reflect.DeepEqual(out.Hikes, in.Hikes) || out.Longest != in.Longest {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
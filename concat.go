@@ -0,0 +1,59 @@
+package toon
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Concat joins documents into one holding the top-level keys of all of
+// them in order, for assembling context from several sources. A key found
+// in more than one document fails with an error wrapping ErrKeyCollision;
+// encoding the sources with distinct MarshalOptions.KeyPrefix values keeps
+// their keys apart. Documents whose root is an array have no keys and
+// cannot be joined. Comments are kept, the first "#toon" directive is
+// moved to the top and "#crc32" footers are dropped since they no longer
+// match.
+func Concat(docs ...[]byte) ([]byte, error) {
+	var version string
+	var lines []string
+	owners := make(map[string]int)
+
+	for i, data := range docs {
+		doc, err := ParseDocument(data)
+		if err != nil {
+			return nil, fmt.Errorf("toon: document %d: %w", i, err)
+		}
+
+		for _, n := range doc.nodes {
+			if n.key == "" || strings.HasPrefix(n.key, "[") {
+				return nil, fmt.Errorf("toon: document %d: cannot concatenate a root array", i)
+			}
+			// Folded columns such as hikes.*.season belong to their table
+			key, _, _ := strings.Cut(n.key, foldedColumnSep)
+			if owner, ok := owners[key]; ok && owner != i {
+				return nil, fmt.Errorf("%w %q in documents %d and %d", ErrKeyCollision, key, owner, i)
+			}
+			owners[key] = i
+		}
+
+		for _, line := range doc.lines[:doc.contentEnd()] {
+			switch {
+			case strings.HasPrefix(line, versionDirective):
+				if version == "" {
+					version = line
+				}
+			case isChecksumFooter(line):
+			default:
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	if version != "" {
+		lines = append([]string{version}, lines...)
+	}
+	if len(lines) == 0 {
+		return []byte{}, nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
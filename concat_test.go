@@ -0,0 +1,64 @@
+package toon_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestConcat(t *testing.T) {
+	weather := "#toon 1.0\n# from the weather service\nforecast:\n  high: 21\n  sunny: true\n#crc32: 00000000\n"
+	trails := "#toon 1.0\ntrails.*.open: true\ntrails[2]{id,name}:\n  1,Blue Lake\n  2,Ridge\n\n"
+
+	data, err := toon.Concat([]byte(weather), []byte(trails))
+	if err != nil {
+		t.Fatalf("Concat failed: %v", err)
+	}
+	want := "#toon 1.0\n# from the weather service\nforecast:\n  high: 21\n  sunny: true\n" +
+		"trails.*.open: true\ntrails[2]{id,name}:\n  1,Blue Lake\n  2,Ridge\n"
+	if string(data) != want {
+		t.Fatalf("Concat = %q, want %q", data, want)
+	}
+
+	var out struct {
+		Forecast struct {
+			High int `toon:"high"`
+		} `toon:"forecast"`
+		Trails []struct {
+			Name string `toon:"name"`
+			Open bool   `toon:"open"`
+		} `toon:"trails"`
+	}
+	if err := toon.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Forecast.High != 21 || len(out.Trails) != 2 || !out.Trails[1].Open {
+		t.Errorf("Unmarshal = %+v", out)
+	}
+}
+
+func TestConcatErrors(t *testing.T) {
+	_, err := toon.Concat([]byte("name: a\n"), []byte("id: 1\n"), []byte("name: b\n"))
+	if !errors.Is(err, toon.ErrKeyCollision) || !strings.Contains(err.Error(), `"name" in documents 0 and 2`) {
+		t.Errorf("Concat with a shared key = %v, want ErrKeyCollision", err)
+	}
+
+	// A folded column collides with the table it belongs to
+	_, err = toon.Concat([]byte("hikes[1]{id}:\n  1\n"), []byte("hikes.*.season: spring\n"))
+	if !errors.Is(err, toon.ErrKeyCollision) {
+		t.Errorf("Concat with a folded column = %v, want ErrKeyCollision", err)
+	}
+
+	// Namespaced sources do not collide
+	a, _ := toon.Marshal(map[string]string{"name": "a"}, toon.WithKeyPrefix("app."))
+	b, _ := toon.Marshal(map[string]string{"name": "b"}, toon.WithKeyPrefix("billing."))
+	if data, err := toon.Concat(a, b); err != nil || string(data) != "app.name: a\nbilling.name: b\n" {
+		t.Errorf("Concat of prefixed documents = %q, %v", data, err)
+	}
+
+	if _, err := toon.Concat([]byte("[2]: a,b\n")); err == nil {
+		t.Error("Concat of a root array succeeded")
+	}
+}
@@ -0,0 +1,124 @@
+// Package corpus holds TOON documents showing the mistakes language models
+// commonly make when asked for TOON output, along with the values they
+// should decode to. The tests of the toon package check that the tolerant
+// decoder handles every sample, and the samples seed its fuzz tests.
+//
+// Applications can run their own decoder options against the samples, or
+// extend the corpus with documents collected from their own models by
+// loading a directory of the same layout with Load.
+package corpus
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+//go:embed samples
+var samples embed.FS
+
+// Sample is a document as a model might write it.
+type Sample struct {
+	// Name is the file name of the document without its extension.
+	Name string
+
+	// Mistake describes what is wrong with the document, from its
+	// "# mistake:" comment.
+	Mistake string
+
+	// Input is the document itself.
+	Input []byte
+
+	// Want is the JSON encoding of the Trip that Input should decode to.
+	Want []byte
+}
+
+// Trip is the shape every sample was requested in.
+type Trip struct {
+	Context struct {
+		Task     string `json:"task,omitempty"`
+		Location string `json:"location,omitempty"`
+		Season   string `json:"season,omitempty"`
+	} `json:"context"`
+	Friends []string `json:"friends,omitempty"`
+	Hikes   []Hike   `json:"hikes,omitempty"`
+}
+
+type Hike struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	DistanceKm    float64 `json:"distanceKm,omitempty"`
+	ElevationGain int     `json:"elevationGain,omitempty"`
+	Companion     string  `json:"companion,omitempty"`
+	WasSunny      bool    `json:"wasSunny,omitempty"`
+}
+
+const mistakePrefix = "# mistake:"
+
+// Samples returns the samples shipped with the package, ordered by name.
+func Samples() []Sample {
+	s, err := Load(samples, "samples")
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Load reads the samples in dir of fsys: every name.toon file together
+// with a name.json file holding the Trip it should decode to. The first
+// line of each document may describe its mistake in a "# mistake:"
+// comment.
+func Load(fsys fs.FS, dir string) ([]Sample, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.toon"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var samples []Sample
+	for _, file := range files {
+		input, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		want, err := fs.ReadFile(fsys, strings.TrimSuffix(file, ".toon")+".json")
+		if err != nil {
+			return nil, err
+		}
+
+		sample := Sample{Name: strings.TrimSuffix(path.Base(file), ".toon"), Input: input, Want: want}
+		first, _, _ := bytes.Cut(input, []byte("\n"))
+		if mistake, ok := strings.CutPrefix(strings.TrimSpace(string(first)), mistakePrefix); ok {
+			sample.Mistake = strings.TrimSpace(mistake)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+// Check decodes the sample's input into a Trip with opts and reports how
+// it differs from the expected value.
+func (s Sample) Check(opts toon.UnmarshalOptions) error {
+	var want Trip
+	if err := json.Unmarshal(s.Want, &want); err != nil {
+		return fmt.Errorf("%s: invalid expected value: %w", s.Name, err)
+	}
+
+	var got Trip
+	if err := toon.UnmarshalWithOptions(s.Input, &got, opts); err != nil {
+		return fmt.Errorf("%s: %w", s.Name, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		return fmt.Errorf("%s: decoded %s, want %s", s.Name, gotJSON, bytes.TrimSpace(s.Want))
+	}
+	return nil
+}
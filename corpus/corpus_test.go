@@ -0,0 +1,72 @@
+package corpus_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	toon "github.com/l00pss/gotoon"
+	"github.com/l00pss/gotoon/corpus"
+)
+
+// TestTolerantDecoding checks that every sample decodes to its expected
+// value with the lenient settings meant for model output.
+func TestTolerantDecoding(t *testing.T) {
+	opts := toon.DefaultUnmarshalOptions()
+	opts.WeaklyTypedInput = true
+
+	samples := corpus.Samples()
+	if len(samples) == 0 {
+		t.Fatal("no samples")
+	}
+	for _, sample := range samples {
+		t.Run(sample.Name, func(t *testing.T) {
+			if sample.Mistake == "" {
+				t.Error("sample does not describe its mistake")
+			}
+			if err := sample.Check(opts); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestStrictDecodingRejects checks that the samples are actually wrong:
+// strict decoding fails on or misreads most of them.
+func TestStrictDecodingRejects(t *testing.T) {
+	opts := toon.DefaultUnmarshalOptions()
+	opts.Strict = true
+	opts.StrictTypes = true
+
+	rejected := 0
+	for _, sample := range corpus.Samples() {
+		if sample.Check(opts) != nil {
+			rejected++
+		}
+	}
+	if rejected == 0 {
+		t.Error("strict decoding accepted every sample")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"mine/yaml-list.toon": {Data: []byte("# mistake: a YAML list instead of an inline array\nfriends[2]:\n  - ana\n  - luis\n")},
+		"mine/yaml-list.json": {Data: []byte(`{"friends":["ana","luis"]}`)},
+	}
+
+	samples, err := corpus.Load(fsys, "mine")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(samples) != 1 || samples[0].Name != "yaml-list" || samples[0].Mistake != "a YAML list instead of an inline array" {
+		t.Fatalf("Load = %+v", samples)
+	}
+	if err := samples[0].Check(toon.DefaultUnmarshalOptions()); err != nil {
+		t.Error(err)
+	}
+
+	delete(fsys, "mine/yaml-list.json")
+	if _, err := corpus.Load(fsys, "mine"); err == nil {
+		t.Error("Load succeeded without the expected value")
+	}
+}
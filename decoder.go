@@ -1,6 +1,9 @@
 package toon
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -12,19 +15,79 @@ type decoder struct {
 	data  []byte
 	lines []string
 	pos   int
+	opts  UnmarshalOptions
+
+	// consumed is the 1-based number of the last line advanced past
+	consumed int
+
+	// offsets holds the byte offset in data at which each line starts
+	offsets []int
+
+	// errs collects the problems decoding carried on past
+	errs []error
+
+	path []string
+
+	// folded holds the constant columns lifted out of the table about to
+	// be decoded
+	folded []foldedColumn
+
+	// delim is the delimiter declared in the header of the array being
+	// decoded, if any
+	delim Delimiter
+
+	// ordered makes blocks decoded without a Go type OrderedMaps rather
+	// than map[string]any, below an OrderedMap and in ToJSON
+	ordered bool
+
+	// numbers makes bare numbers decoded without a Go type json.Numbers,
+	// which keep their spelling, rather than int64, uint64 or float64, in
+	// ToJSON
+	numbers bool
+
+	// ids maps the references in a document written with ShortenIDs to
+	// their identifiers when ResolveIDs is set
+	ids map[string]string
+
+	// topKeys, when set, holds the top-level keys decoded so far, which a
+	// Decoder carries across the pieces it reads so duplicates are found
+	topKeys map[string]bool
+
+	// lineBase and offsetBase are the number of lines and bytes of the
+	// input before data, which a Decoder reads in pieces
+	lineBase, offsetBase int
+}
+
+// foldedColumn is a "key.*.column: value" line read ahead of its table.
+type foldedColumn struct {
+	column string
+	value  string
+	line   int
 }
 
-func newDecoder(data []byte) *decoder {
+func newDecoder(data []byte, opts UnmarshalOptions) *decoder {
+	if opts.TabWidth <= 0 {
+		opts.TabWidth = DefaultUnmarshalOptions().TabWidth
+	}
+
 	input := string(data)
 	lines := strings.Split(input, "\n")
+
+	offsets := make([]int, len(lines))
+	for i := 1; i < len(lines); i++ {
+		offsets[i] = offsets[i-1] + len(lines[i-1]) + 1
+	}
+
 	return &decoder{
-		data:  data,
-		lines: lines,
-		pos:   0,
+		data:    data,
+		lines:   lines,
+		pos:     0,
+		opts:    opts,
+		offsets: offsets,
 	}
 }
 
-func (d *decoder) decode(v any) error {
+func (d *decoder) decode(v any) (err error) {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr {
 		return ErrUnmarshalType
@@ -32,8 +95,204 @@ func (d *decoder) decode(v any) error {
 	if rv.IsNil() {
 		return ErrNilPointer
 	}
+	return d.run(func() error {
+		return d.decodeValue(rv.Elem(), 0)
+	})
+}
 
-	return d.decodeValue(rv.Elem(), 0)
+// isReflectPanic reports whether r was raised by the reflect package, which
+// panics with a *reflect.ValueError or a message naming the package.
+func isReflectPanic(r any) bool {
+	switch r := r.(type) {
+	case *reflect.ValueError:
+		return true
+	case string:
+		return strings.HasPrefix(r, "reflect")
+	}
+	return false
+}
+
+// run prepares the document as the options ask, verifying, stripping and
+// resolving what they name, then calls body to decode it.
+func (d *decoder) run(body func() error) (err error) {
+	// Malformed input can drive reflect into states it panics on; report
+	// those as syntax errors at the line and path being decoded instead.
+	// Other panics are bugs and are not hidden.
+	defer func() {
+		if r := recover(); r != nil {
+			if !isReflectPanic(r) {
+				panic(r)
+			}
+			msg := fmt.Sprint(r)
+			if path := formatPath(d.path); path != "" {
+				msg += " at " + path
+			}
+			d.errs = append(d.errs, d.syntaxError(max(d.consumed, 1), msg))
+			err = d.err()
+		}
+	}()
+
+	if d.opts.MaxLineBytes > 0 {
+		if err := d.checkLineLengths(); err != nil {
+			return err
+		}
+	}
+	if d.opts.VerifyChecksum {
+		if err := d.verifyChecksum(); err != nil {
+			return err
+		}
+	}
+	if err := d.checkVersion(); err != nil {
+		return err
+	}
+	d.stripComments()
+	if d.opts.KeyPrefix != "" {
+		d.stripKeyPrefix()
+	}
+	if d.opts.ResolveIDs {
+		d.readIDLegend()
+	}
+	if d.opts.AutoDetectDelimiter && d.opts.Delimiter == "" {
+		delim, err := d.detectDelimiter()
+		if err != nil {
+			return err
+		}
+		d.opts.Delimiter = delim
+	}
+
+	if err := body(); err != nil {
+		d.errs = append(d.errs, err)
+	}
+	return d.err()
+}
+
+// err returns the problems found while decoding: nil, the only one, or
+// Errors holding them all in the order they were found.
+func (d *decoder) err() error {
+	switch len(d.errs) {
+	case 0:
+		return nil
+	case 1:
+		return d.errs[0]
+	}
+	return Errors(d.errs)
+}
+
+// collect records err and lets decoding carry on when it is an
+// *UnmarshalTypeError, which only leaves one value unset. Other errors are
+// returned for the caller to abort on.
+func (d *decoder) collect(err error) error {
+	var typeErr *UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		d.errs = append(d.errs, err)
+		return nil
+	}
+	return err
+}
+
+// tolerate handles an irregularity decoding can recover from, such as a
+// blank cell: in strict mode it is recorded as a *SyntaxError, otherwise it
+// becomes a warning saying what happened to the value.
+func (d *decoder) tolerate(line int, msg, outcome string) {
+	if d.opts.Strict {
+		d.errs = append(d.errs, d.syntaxError(line, msg))
+		return
+	}
+	if outcome != "" {
+		msg += " " + outcome
+	}
+	d.warn(line, "%s", msg)
+}
+
+// verifyChecksum checks the "#crc32" footer on the last non-empty line
+// against the bytes that precede it.
+func (d *decoder) verifyChecksum() error {
+	last := len(d.lines) - 1
+	for last >= 0 && strings.TrimSpace(d.lines[last]) == "" {
+		last--
+	}
+	footer, ok := "", false
+	if last >= 0 {
+		footer, ok = cutChecksumFooter(d.lines[last])
+	}
+	if !ok {
+		err := d.syntaxError(last+2, fmt.Sprintf("missing %q footer", strings.TrimSpace(checksumDirective)))
+		err.Err = ErrChecksum
+		return err
+	}
+
+	want, err := strconv.ParseUint(footer, 16, 32)
+	if err != nil {
+		return d.syntaxError(last+1, fmt.Sprintf("malformed checksum %q", footer))
+	}
+
+	offset := 0
+	for _, line := range d.lines[:last] {
+		offset += len(line) + 1
+	}
+	if got := checksum(d.data[:offset]); got != uint32(want) {
+		err := d.syntaxError(last+1, fmt.Sprintf("footer has %08X, content has %08X", want, got))
+		err.Err = ErrChecksum
+		return err
+	}
+	return nil
+}
+
+// checkVersion validates the "#toon <major>.<minor>" directive on the first
+// line. Documents of a newer minor version are accepted since minor
+// revisions only add syntax old documents never use; other majors are not.
+func (d *decoder) checkVersion() error {
+	if len(d.lines) == 0 || !strings.HasPrefix(d.lines[0], versionDirective) {
+		if d.opts.RequireVersion {
+			err := d.syntaxError(1, fmt.Sprintf("missing %q directive", strings.TrimSpace(versionDirective)))
+			err.Err = ErrVersion
+			return err
+		}
+		return nil
+	}
+
+	version := strings.TrimSpace(strings.TrimPrefix(d.lines[0], versionDirective))
+	major, _, ok := strings.Cut(version, ".")
+	if _, err := strconv.Atoi(major); !ok || err != nil {
+		return d.syntaxError(1, fmt.Sprintf("malformed version directive %q", d.lines[0]))
+	}
+
+	supported, _, _ := strings.Cut(FormatVersion, ".")
+	if major != supported {
+		err := d.syntaxError(1, fmt.Sprintf("unsupported format version %s (supported: %s)", version, FormatVersion))
+		err.Err = ErrVersion
+		return err
+	}
+	return nil
+}
+
+// stripKeyPrefix removes KeyPrefix from the keys of unindented lines.
+func (d *decoder) stripKeyPrefix() {
+	for i, line := range d.lines {
+		if rest, ok := strings.CutPrefix(line, d.opts.KeyPrefix); ok {
+			d.lines[i] = rest
+		}
+	}
+}
+
+// fieldKey returns the struct field name key stands for, as given by
+// KeyTranslator when set.
+func (d *decoder) fieldKey(key string) string {
+	if d.opts.KeyTranslator == nil {
+		return key
+	}
+	return d.opts.KeyTranslator(key)
+}
+
+// columnKey is fieldKey for a column name, which loses its unit first.
+func (d *decoder) columnKey(name string) string {
+	if d.opts.KeyTranslator == nil {
+		return name
+	}
+	if i := strings.LastIndex(name, "("); i > 0 && strings.HasSuffix(name, ")") {
+		name = name[:i]
+	}
+	return d.opts.KeyTranslator(name)
 }
 
 func (d *decoder) hasMore() bool {
@@ -54,6 +313,7 @@ func (d *decoder) currentLine() string {
 
 func (d *decoder) advance() {
 	d.pos++
+	d.consumed = d.pos
 }
 
 func (d *decoder) skipEmptyLines() {
@@ -66,13 +326,28 @@ func (d *decoder) skipEmptyLines() {
 	}
 }
 
+// skipBlock advances past the lines nested below a key at indent, such as
+// the fields, rows or items of one that is not decoded.
+func (d *decoder) skipBlock(indent int) {
+	for d.hasMore() {
+		line := d.currentLine()
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") && d.getIndent(line) <= indent {
+			return
+		}
+		d.advance()
+	}
+}
+
 func (d *decoder) getIndent(line string) int {
 	count := 0
 	for _, ch := range line {
-		if ch == ' ' {
+		switch ch {
+		case ' ':
 			count++
-		} else {
-			break
+		case '\t':
+			count += d.opts.TabWidth
+		default:
+			return count
 		}
 	}
 	return count
@@ -81,15 +356,27 @@ func (d *decoder) getIndent(line string) int {
 func (d *decoder) decodeValue(v reflect.Value, expectedIndent int) error {
 	d.skipEmptyLines()
 	if !d.hasMore() {
+		decodeEmpty(v)
 		return nil
 	}
+	if v.Type() == orderedMapType {
+		return d.decodeOrderedMap(v, expectedIndent)
+	}
 
 	switch v.Kind() {
 	case reflect.Struct:
 		return d.decodeStruct(v, expectedIndent)
 	case reflect.Map:
 		return d.decodeMap(v, expectedIndent)
-	case reflect.Slice:
+	case reflect.Slice, reflect.Array:
+		if header, ok := d.keylessArray(d.currentLine()); ok {
+			indent := d.getIndent(d.currentLine())
+			d.advance()
+			return d.decodeArrayField(v, header.length, header.fields, header.delim, header.value, indent)
+		}
+		if v.Kind() == reflect.Array {
+			return d.typeError(strings.TrimSpace(d.currentLine()), v.Type())
+		}
 		return d.decodeSlice(v, expectedIndent)
 	case reflect.Ptr:
 		if v.IsNil() {
@@ -97,12 +384,17 @@ func (d *decoder) decodeValue(v reflect.Value, expectedIndent int) error {
 		}
 		return d.decodeValue(v.Elem(), expectedIndent)
 	case reflect.Interface:
-		m := make(map[string]any)
-		mv := reflect.ValueOf(&m).Elem()
-		if err := d.decodeMap(mv, expectedIndent); err != nil {
+		if existing, ok := v.Interface().(map[string]any); d.opts.MergeMaps && ok && existing != nil {
+			return d.decodeMap(reflect.ValueOf(existing), expectedIndent)
+		}
+		if v.NumMethod() > 0 {
+			return d.syntaxError(max(d.consumed, 1), fmt.Sprintf("cannot decode into %s", v.Type()))
+		}
+		value, err := d.decodeDynamic(expectedIndent)
+		if err != nil {
 			return err
 		}
-		v.Set(mv)
+		setDynamic(v, value)
 		return nil
 	default:
 		d.skipEmptyLines()
@@ -112,25 +404,50 @@ func (d *decoder) decodeValue(v reflect.Value, expectedIndent int) error {
 		line := d.currentLine()
 		trimmed := strings.TrimSpace(line)
 		d.advance()
-		return d.setPrimitiveValue(v, trimmed)
+		return d.setValue(v, trimmed)
 	}
 }
 
-func (d *decoder) decodeStruct(v reflect.Value, expectedIndent int) error {
-	t := v.Type()
-	fieldMap := make(map[string]int)
-
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if !field.IsExported() {
-			continue
+// decodeEmpty decodes a block without any lines into v, which is what an
+// empty document or a trailing "key:" holds. Like a block whose keys are
+// all missing, it leaves structs and scalars unchanged; maps and slices end
+// up empty but non-nil, interfaces hold an empty map[string]any, and nil
+// pointers to structs, maps and slices are allocated.
+func decodeEmpty(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
 		}
-
-		name := getFieldName(field)
-		if name != "-" {
-			fieldMap[name] = i
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(map[string]any{}))
+		}
+	case reflect.Ptr:
+		switch t := derefType(v.Type()); t.Kind() {
+		case reflect.Struct, reflect.Map, reflect.Slice:
+			if !isScalarType(t) {
+				decodeEmpty(indirect(v))
+			}
 		}
 	}
+}
+
+// seenKeys returns the set recording the keys decoded in a block at
+// indent, shared with earlier pieces of the input at the top level.
+func (d *decoder) seenKeys(indent int) map[string]bool {
+	if indent == 0 && d.topKeys != nil {
+		return d.topKeys
+	}
+	return make(map[string]bool)
+}
+
+func (d *decoder) decodeStruct(v reflect.Value, expectedIndent int) error {
+	fieldMap := fieldsByName(v.Type())
+	seen := d.seenKeys(expectedIndent)
+	folds := make(map[string][]foldedColumn)
 
 	for d.hasMore() {
 		d.skipEmptyLines()
@@ -146,47 +463,65 @@ func (d *decoder) decodeStruct(v reflect.Value, expectedIndent int) error {
 		}
 
 		trimmed := strings.TrimSpace(line)
-		if !strings.Contains(trimmed, ":") {
-			d.advance()
-			continue
-		}
-
-		parts := strings.SplitN(trimmed, ":", 2)
-		if len(parts) != 2 {
+		key, value, ok := cutKeyValue(trimmed)
+		if !ok {
 			d.advance()
+			d.tolerate(d.pos, "line without a key", "skipped")
 			continue
 		}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
 
-		arrayLen, fieldNames := d.parseArrayDeclaration(key)
-		if arrayLen >= 0 {
+		arrayLen, fieldNames, delim := d.parseArrayDeclaration(key)
+		if arrayLen != notArray {
 			key = d.extractKeyFromArray(key)
+		} else if table, column, ok := strings.Cut(key, foldedColumnSep); ok {
+			table = d.fieldKey(table)
+			folds[table] = append(folds[table], foldedColumn{column: column, value: value, line: d.pos + 1})
+			d.advance()
+			continue
 		}
 
-		fieldIdx, ok := fieldMap[key]
+		key = d.fieldKey(key)
+		field, ok := fieldMap[key]
 		if !ok {
+			if d.opts.DisallowUnknownFields {
+				d.errs = append(d.errs, d.syntaxError(d.pos+1, fmt.Sprintf("unknown key %q for %s", key, v.Type())))
+			}
 			d.advance()
+			d.skipBlock(indent)
 			continue
 		}
 
-		fieldValue := v.Field(fieldIdx)
 		d.advance()
+		if seen[key] {
+			d.warn(d.pos, "duplicate key %q overrides earlier value", key)
+		}
+		seen[key] = true
+		fieldValue := fieldByIndexAlloc(v, field.index)
 
-		if arrayLen >= 0 {
-			if err := d.decodeArrayField(fieldValue, arrayLen, fieldNames, value, indent); err != nil {
+		d.pushPath(key)
+		if fieldValue.Type() == rawMessageType {
+			if err := d.decodeRawMessage(fieldValue, value, indent); err != nil {
 				return err
 			}
+		} else if arrayLen != notArray {
+			d.folded = folds[key]
+			if err := d.decodeArrayField(fieldValue, arrayLen, fieldNames, delim, value, indent); err != nil {
+				return err
+			}
+			d.folded = nil
 		} else if value == "" {
 			if err := d.decodeValue(fieldValue, indent+2); err != nil {
 				return err
 			}
 		} else {
-			if err := d.setPrimitiveValue(fieldValue, value); err != nil {
+			if err := d.setFieldValue(fieldValue, field, value); err != nil {
 				return err
 			}
 		}
+		d.popPath()
 	}
 
 	return nil
@@ -199,7 +534,89 @@ func (d *decoder) decodeMap(v reflect.Value, expectedIndent int) error {
 
 	keyType := v.Type().Key()
 	elemType := v.Type().Elem()
+	seen := d.seenKeys(expectedIndent)
+	folds := make(map[string][]foldedColumn)
 
+	return d.decodeEntries(expectedIndent, func(keyStr, valueStr string, indent int) error {
+		d.advance()
+		arrayLen, fieldNames, delim := d.parseArrayDeclaration(keyStr)
+		if arrayLen != notArray {
+			keyStr = d.extractKeyFromArray(keyStr)
+		} else if table, column, ok := strings.Cut(keyStr, foldedColumnSep); ok {
+			folds[table] = append(folds[table], foldedColumn{column: column, value: valueStr, line: d.pos})
+			return nil
+		}
+
+		key := reflect.New(keyType).Elem()
+		if err := d.setMapKey(key, keyStr); err != nil {
+			return err
+		}
+
+		elem := reflect.New(elemType).Elem()
+		if existing := v.MapIndex(key); d.opts.MergeMaps && valueStr == "" && existing.IsValid() {
+			elem.Set(existing)
+		}
+		if seen[keyStr] {
+			d.warn(d.pos, "duplicate key %q overrides earlier value", keyStr)
+		}
+		seen[keyStr] = true
+
+		d.pushPath(keyStr)
+		if elemType == rawMessageType {
+			if err := d.decodeRawMessage(elem, valueStr, indent); err != nil {
+				return err
+			}
+		} else if arrayLen != notArray {
+			d.folded = folds[keyStr]
+			if err := d.decodeArrayField(elem, arrayLen, fieldNames, delim, valueStr, indent); err != nil {
+				return err
+			}
+			d.folded = nil
+		} else if valueStr == "" {
+			if err := d.decodeValue(elem, indent+2); err != nil {
+				return err
+			}
+		} else {
+			if err := d.setValue(elem, valueStr); err != nil {
+				return err
+			}
+		}
+		d.popPath()
+
+		v.SetMapIndex(key, elem)
+		return nil
+	})
+}
+
+// setMapKey parses s into the map key v. Integer keys must be integers
+// that fit the key type, without the coercions values go through, so that
+// distinct keys never collapse into one.
+func (d *decoder) setMapKey(v reflect.Value, s string) error {
+	raw := strings.TrimSpace(s)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(unquote(raw), 10, v.Type().Bits())
+		if err != nil {
+			return d.typeError(raw, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(unquote(raw), 10, v.Type().Bits())
+		if err != nil {
+			return d.typeError(raw, v.Type())
+		}
+		v.SetUint(u)
+	default:
+		return d.setPrimitiveValue(v, s)
+	}
+	return nil
+}
+
+// decodeEntries calls entry with the key, the text after the colon and
+// the indentation of every key indented at least expectedIndent. entry
+// must advance past the line. Lines without a colon are tolerated and
+// skipped.
+func (d *decoder) decodeEntries(expectedIndent int, entry func(key, value string, indent int) error) error {
 	for d.hasMore() {
 		d.skipEmptyLines()
 		if !d.hasMore() {
@@ -214,39 +631,16 @@ func (d *decoder) decodeMap(v reflect.Value, expectedIndent int) error {
 		}
 
 		trimmed := strings.TrimSpace(line)
-		if !strings.Contains(trimmed, ":") {
-			d.advance()
-			continue
-		}
-
-		parts := strings.SplitN(trimmed, ":", 2)
-		if len(parts) != 2 {
+		key, value, ok := cutKeyValue(trimmed)
+		if !ok {
 			d.advance()
+			d.tolerate(d.pos, "line without a key", "skipped")
 			continue
 		}
 
-		keyStr := strings.TrimSpace(parts[0])
-		valueStr := strings.TrimSpace(parts[1])
-
-		key := reflect.New(keyType).Elem()
-		if err := d.setPrimitiveValue(key, keyStr); err != nil {
+		if err := entry(strings.TrimSpace(key), strings.TrimSpace(value), indent); err != nil {
 			return err
 		}
-
-		elem := reflect.New(elemType).Elem()
-		d.advance()
-
-		if valueStr == "" {
-			if err := d.decodeValue(elem, indent+2); err != nil {
-				return err
-			}
-		} else {
-			if err := d.setPrimitiveValue(elem, valueStr); err != nil {
-				return err
-			}
-		}
-
-		v.SetMapIndex(key, elem)
 	}
 
 	return nil
@@ -270,6 +664,17 @@ func (d *decoder) decodeSlice(v reflect.Value, expectedIndent int) error {
 		}
 
 		trimmed := strings.TrimSpace(line)
+		if trimmed == "-" {
+			d.advance()
+			elem := reflect.New(elemType).Elem()
+			d.pushIndex(slice.Len())
+			if err := d.decodeBareListItem(elem, indent+1); err != nil {
+				return err
+			}
+			d.popPath()
+			slice = reflect.Append(slice, elem)
+			continue
+		}
 		if !strings.HasPrefix(trimmed, "- ") {
 			break
 		}
@@ -280,20 +685,31 @@ func (d *decoder) decodeSlice(v reflect.Value, expectedIndent int) error {
 
 		elem := reflect.New(elemType).Elem()
 
-		if elemType.Kind() == reflect.Struct {
+		d.pushIndex(slice.Len())
+		if header, ok := d.keylessArray(itemContent); ok && isArrayTarget(elemType) {
+			if err := d.decodeArrayField(elem, header.length, header.fields, header.delim, header.value, indent); err != nil {
+				return err
+			}
+		} else if target := indirect(elem); target.Kind() == reflect.Struct && !isScalarType(target.Type()) {
 			// For struct, parse the first field inline, then continue with nested fields
-			if strings.Contains(itemContent, ":") {
+			if _, _, ok := cutKeyValue(itemContent); ok {
 				// Decode as struct with first field inline
-				if err := d.decodeStructFromListItem(elem, itemContent, indent+2); err != nil {
+				if err := d.decodeStructFromListItem(target, itemContent, indent+2); err != nil {
 					return err
 				}
 			}
+		} else if _, _, ok := cutKeyValue(itemContent); ok && target.Kind() == reflect.Map {
+			d.unreadListItem(itemContent, indent+2)
+			if err := d.decodeMap(target, indent+2); err != nil {
+				return err
+			}
 		} else {
 			// For primitive, set value directly
-			if err := d.setPrimitiveValue(elem, itemContent); err != nil {
+			if err := d.setValue(elem, itemContent); err != nil {
 				return err
 			}
 		}
+		d.popPath()
 
 		slice = reflect.Append(slice, elem)
 	}
@@ -302,43 +718,106 @@ func (d *decoder) decodeSlice(v reflect.Value, expectedIndent int) error {
 	return nil
 }
 
-func (d *decoder) decodeArrayField(v reflect.Value, length int, fieldNames []string, value string, indent int) error {
+func (d *decoder) decodeArrayField(v reflect.Value, length int, fieldNames []string, delim Delimiter, value string, indent int) error {
+	// The header line has already been consumed, so d.pos is its 1-based number
+	line := d.pos
+	v = indirect(v)
+	if t := v.Type(); isEmptyInterface(t) || t.Kind() == reflect.Slice && isEmptyInterface(t.Elem()) {
+		value, err := d.decodeDynamicArray(length, fieldNames, delim, value, indent)
+		if err != nil {
+			return err
+		}
+		if value != nil && !reflect.TypeOf(value).AssignableTo(t) {
+			return d.syntaxError(line, fmt.Sprintf("table keyed by %s cannot be decoded into %s", tabularKeyField, t))
+		}
+		setDynamic(v, value)
+		return nil
+	}
+	switch {
+	case v.Kind() == reflect.Array:
+		return d.decodeFixedArray(v, length, fieldNames, delim, value, indent)
+	case v.Kind() == reflect.Slice, v.Kind() == reflect.Map && len(fieldNames) > 0:
+	default:
+		// A header aimed at a field that holds no array leaves it unset
+		if value == "" {
+			value = strings.TrimSpace(d.lines[line-1])
+		}
+		typeErr := d.typeError(value, v.Type())
+		d.skipBlock(indent)
+		return d.collect(typeErr)
+	}
+	defer d.declareDelimiter(delim)()
+
+	var err error
 	if len(fieldNames) > 0 {
 		// Tabular format
-		return d.decodeTabularArray(v, length, fieldNames, indent)
+		err = d.decodeTabularArray(v, length, fieldNames, indent)
 	} else if value != "" {
 		// Inline format
-		return d.decodeInlineArray(v, value)
+		err = d.decodeInlineArray(v, length, value)
 	} else {
 		// List format
-		return d.decodeValue(v, indent+2)
+		err = d.decodeValue(v, indent+2)
+	}
+	if err != nil {
+		return err
+	}
+
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && length != openLength && v.Len() != length {
+		d.tolerate(line, fmt.Sprintf("array declares %d items, found %d", length, v.Len()), "")
 	}
+	return nil
 }
 
-func (d *decoder) decodeInlineArray(v reflect.Value, value string) error {
-	// Split by delimiter (comma, tab, or pipe)
-	var parts []string
-	if strings.Contains(value, "\t") {
-		parts = strings.Split(value, "\t")
-	} else if strings.Contains(value, "|") {
-		parts = strings.Split(value, "|")
-	} else {
-		parts = strings.Split(value, ",")
+// decodeFixedArray decodes an array into the Go array v through a slice,
+// failing unless it holds exactly as many items as v.
+func (d *decoder) decodeFixedArray(v reflect.Value, length int, fieldNames []string, delim Delimiter, value string, indent int) error {
+	line := d.pos
+	items := reflect.New(reflect.SliceOf(v.Type().Elem())).Elem()
+	if err := d.decodeArrayField(items, length, fieldNames, delim, value, indent); err != nil {
+		return err
+	}
+	if items.Len() != v.Len() {
+		typeErr := d.typeError(strings.TrimSpace(d.lines[line-1]), v.Type())
+		typeErr.Line = line + d.lineBase
+		return d.collect(typeErr)
 	}
+	reflect.Copy(v, items)
+	return nil
+}
+
+// indirect allocates any nil pointers along v and returns the value they
+// ultimately point to, so decoding can target it directly.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func (d *decoder) decodeInlineArray(v reflect.Value, length int, value string) error {
+	// The header line has already been consumed, so d.pos is its 1-based number
+	line := d.pos
+
+	parts := d.trimTrailingDelimiter(d.splitValues(value), length, line)
 
 	elemType := v.Type().Elem()
 	slice := reflect.MakeSlice(v.Type(), 0, len(parts))
 
-	for _, part := range parts {
+	for i, part := range parts {
 		part = strings.TrimSpace(part)
-		if part == "" {
-			continue
-		}
 
 		elem := reflect.New(elemType).Elem()
-		if err := d.setPrimitiveValue(elem, part); err != nil {
+		d.pushIndex(i)
+		if part == "" {
+			d.tolerate(line, "blank value in inline array", "left as zero value")
+		} else if err := d.setValue(elem, part); err != nil {
 			return err
 		}
+		d.popPath()
 		slice = reflect.Append(slice, elem)
 	}
 
@@ -347,27 +826,59 @@ func (d *decoder) decodeInlineArray(v reflect.Value, value string) error {
 }
 
 func (d *decoder) decodeTabularArray(v reflect.Value, length int, fieldNames []string, indent int) error {
+	isMap := v.Kind() == reflect.Map
 	elemType := v.Type().Elem()
-	if elemType.Kind() != reflect.Struct {
-		return fmt.Errorf("tabular arrays require struct elements")
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return d.syntaxError(d.consumed, fmt.Sprintf("table rows cannot be decoded into %s", elemType))
 	}
 
-	// Build field mapping
-	fieldMap := make(map[string]int)
-	t := elemType
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if !field.IsExported() {
-			continue
+	fieldMap := fieldsByColumn(structType)
+	folded := d.folded
+	d.folded = nil
+	sparse := len(fieldNames) == 1 && fieldNames[0] == sparseField
+
+	// Columns are checked once, against the header or the row that first
+	// names them in a sparse table
+	checked := make(map[string]bool)
+	checkColumn := func(line int, name string) {
+		if !d.opts.DisallowUnknownFields || checked[name] || isMap && name == tabularKeyField {
+			return
+		}
+		checked[name] = true
+		if _, ok := fieldMap[d.columnKey(name)]; !ok {
+			d.errs = append(d.errs, d.syntaxError(line, fmt.Sprintf("unknown column %q for %s", name, structType)))
+		}
+	}
+	if !sparse {
+		for _, name := range fieldNames {
+			checkColumn(d.consumed, name)
 		}
-		name := getFieldName(field)
-		fieldMap[name] = i
+	}
+	for _, fold := range folded {
+		checkColumn(fold.line, fold.column)
 	}
 
-	slice := reflect.MakeSlice(v.Type(), 0, length)
+	// Each row takes a line, so never trust the header beyond what is left
+	capacity := len(d.lines) - d.pos
+	if length != openLength {
+		capacity = min(length, capacity)
+	}
 
-	// Read tabular data
-	for i := 0; i < length && d.hasMore(); i++ {
+	var slice reflect.Value
+	if isMap {
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), capacity))
+		}
+	} else {
+		slice = reflect.MakeSlice(v.Type(), 0, capacity)
+	}
+
+	// Read tabular data until the indentation returns to the header's level
+	for d.hasMore() {
 		d.skipEmptyLines()
 		if !d.hasMore() {
 			break
@@ -375,138 +886,412 @@ func (d *decoder) decodeTabularArray(v reflect.Value, length int, fieldNames []s
 
 		line := d.currentLine()
 		if d.getIndent(line) <= indent {
-			if strings.TrimSpace(line) == "" {
-				d.advance()
-				continue
-			}
+			break
 		}
 
 		rowData := strings.TrimSpace(line)
 		d.advance()
+		lineNum := d.pos
 
-		// Split by delimiter
+		names := fieldNames
 		var values []string
-		if strings.Contains(rowData, "\t") {
-			values = strings.Split(rowData, "\t")
-		} else if strings.Contains(rowData, "|") {
-			values = strings.Split(rowData, "|")
+		var err error
+		if sparse {
+			names, values = d.splitSparseRow(rowData, lineNum)
 		} else {
-			values = strings.Split(rowData, ",")
+			values = d.trimTrailingDelimiter(d.splitValues(rowData), len(fieldNames), lineNum)
+			if extra := len(values) - len(fieldNames); extra > 0 {
+				d.warn(lineNum, "%d extra cells ignored", extra)
+			}
 		}
 
 		elem := reflect.New(elemType).Elem()
+		row := indirect(elem)
+		if !isMap {
+			d.pushIndex(slice.Len())
+		}
 
-		// Map values to fields
-		for j, fieldName := range fieldNames {
-			if j < len(values) {
-				if fieldIdx, ok := fieldMap[fieldName]; ok {
-					fieldValue := elem.Field(fieldIdx)
-					value := strings.TrimSpace(values[j])
-					if err := d.setPrimitiveValue(fieldValue, value); err != nil {
-						return err
-					}
-				}
+		var key reflect.Value
+		if isMap {
+			key = reflect.New(v.Type().Key()).Elem()
+		}
+
+		// Map values to fields; blank cells leave the zero value in place
+		for j, fieldName := range names {
+			if j >= len(values) {
+				break
+			}
+
+			if sparse {
+				checkColumn(lineNum, fieldName)
 			}
+			isKey := isMap && fieldName == tabularKeyField
+			field, ok := fieldMap[d.columnKey(fieldName)]
+			if !isKey && !ok {
+				continue
+			}
+
+			value := strings.TrimSpace(values[j])
+			if value == "" {
+				d.tolerate(lineNum, fmt.Sprintf("blank cell for field %q", fieldName), "left as zero value")
+				continue
+			}
+
+			d.pushPath(fieldName)
+			if isKey {
+				err = d.setMapKey(key, value)
+			} else {
+				err = d.setFieldValue(fieldByIndexAlloc(row, field.index), field, value)
+			}
+			if err != nil {
+				return err
+			}
+			d.popPath()
 		}
 
-		slice = reflect.Append(slice, elem)
+		for _, fold := range folded {
+			field, ok := fieldMap[d.columnKey(fold.column)]
+			if !ok {
+				continue
+			}
+			d.pushPath(fold.column)
+			if err := d.setFieldValue(fieldByIndexAlloc(row, field.index), field, fold.value); err != nil {
+				return err
+			}
+			d.popPath()
+		}
+
+		if isMap {
+			v.SetMapIndex(key, elem)
+		} else {
+			d.popPath()
+			slice = reflect.Append(slice, elem)
+		}
 	}
 
-	v.Set(slice)
+	if !isMap {
+		v.Set(slice)
+	}
 	return nil
 }
 
 func (d *decoder) decodeStructFromListItem(v reflect.Value, firstLine string, expectedIndent int) error {
-	t := v.Type()
-	fieldMap := make(map[string]int)
+	d.unreadListItem(firstLine, expectedIndent)
+	return d.decodeStruct(v, expectedIndent)
+}
+
+// decodeBareListItem decodes a list item whose dash stands alone on its
+// line, with all of its fields on the lines indented below it.
+func (d *decoder) decodeBareListItem(v reflect.Value, expectedIndent int) error {
+	target := indirect(v)
+	switch {
+	case target.Kind() == reflect.Struct && !isScalarType(target.Type()):
+		return d.decodeStruct(target, expectedIndent)
+	case target.Kind() == reflect.Map:
+		return d.decodeMap(target, expectedIndent)
+	}
+
+	d.tolerate(d.pos, "empty list item", "left as zero value")
+	return nil
+}
 
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if !field.IsExported() {
+// unreadListItem steps back onto the dash line just consumed and replaces
+// it with content indented to indent, so the fields of a list item,
+// including nested arrays and blocks, decode like any other block.
+func (d *decoder) unreadListItem(content string, indent int) {
+	d.pos--
+	d.lines[d.pos] = strings.Repeat(" ", indent) + content
+}
+
+// declareDelimiter splits the cells of the array about to be decoded on
+// delim, the delimiter declared in its header, until the returned function
+// is called. An empty delim leaves splitting to the options or guessing.
+func (d *decoder) declareDelimiter(delim Delimiter) func() {
+	saved := d.delim
+	d.delim = delim
+	return func() { d.delim = saved }
+}
+
+// delimiterFor returns the delimiter to split s on: the one declared in the
+// array's header, else the configured one, else one guessed from s.
+func (d *decoder) delimiterFor(s string) Delimiter {
+	switch {
+	case d.delim != "":
+		return d.delim
+	case d.opts.Delimiter != "":
+		return d.opts.Delimiter
+	}
+	return guessDelimiter(s)
+}
+
+// splitSparseRow splits a row of a sparse table into the column names and
+// values of its column=value pairs. Cells that are not pairs are recorded
+// as errors and skipped.
+func (d *decoder) splitSparseRow(row string, line int) ([]string, []string) {
+	delim := d.delimiterFor(row)
+
+	// Values are quoted after the "=", so quotes open there too
+	pairs := splitCells(row, string(delim), func(before string) bool {
+		before = strings.TrimSpace(before)
+		return before == "" || strings.HasSuffix(before, sparseField)
+	})
+
+	var names, values []string
+	for _, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
 			continue
 		}
-		name := getFieldName(field)
-		if name != "-" {
-			fieldMap[name] = i
+
+		name, value, ok := cutSparsePair(pair)
+		if !ok {
+			d.errs = append(d.errs, d.syntaxError(line, fmt.Sprintf("expected column=value, found %q", pair)))
+			continue
 		}
+		names = append(names, name)
+		values = append(values, value)
 	}
+	return names, values
+}
 
-	// Parse first line
-	if strings.Contains(firstLine, ":") {
-		parts := strings.SplitN(firstLine, ":", 2)
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		if fieldIdx, ok := fieldMap[key]; ok {
-			if err := d.setPrimitiveValue(v.Field(fieldIdx), value); err != nil {
-				return err
+// cutSparsePair splits pair at the first "=" after its possibly quoted
+// column name.
+func cutSparsePair(pair string) (name, value string, ok bool) {
+	end := 0
+	if strings.HasPrefix(pair, `"`) {
+		for end = 1; end < len(pair) && pair[end] != '"'; end++ {
+			if pair[end] == '\\' {
+				end++
 			}
 		}
+		end = min(end+1, len(pair))
 	}
 
-	// Parse remaining fields from subsequent lines
-	for d.hasMore() {
-		d.skipEmptyLines()
-		if !d.hasMore() {
-			break
-		}
+	i := strings.Index(pair[end:], sparseField)
+	if i < 0 {
+		return "", "", false
+	}
+	i += end
+	return unquote(strings.TrimSpace(pair[:i])), strings.TrimSpace(pair[i+1:]), true
+}
 
-		line := d.currentLine()
-		indent := d.getIndent(line)
+// trimTrailingDelimiter drops the empty cell left behind by a delimiter at
+// the end of a row, i.e. when there is exactly one cell more than expected
+// and it is blank.
+func (d *decoder) trimTrailingDelimiter(cells []string, expected int, line int) []string {
+	if len(cells) != expected+1 || strings.TrimSpace(cells[len(cells)-1]) != "" {
+		return cells
+	}
+	d.tolerate(line, "trailing delimiter", "ignored")
+	return cells[:len(cells)-1]
+}
 
-		if indent < expectedIndent {
-			break
-		}
+// warn records a non-fatal issue when the caller asked for warnings.
+func (d *decoder) warn(line int, format string, args ...any) {
+	if d.opts.Warnings == nil {
+		return
+	}
+	*d.opts.Warnings = append(*d.opts.Warnings, Warning{
+		Line:    line + d.lineBase,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
 
-		trimmed := strings.TrimSpace(line)
-		if !strings.Contains(trimmed, ":") {
-			break
-		}
+func (d *decoder) typeError(value string, t reflect.Type) *UnmarshalTypeError {
+	_, offset := d.position(d.consumed)
+	return &UnmarshalTypeError{Value: value, Type: t, Path: formatPath(d.path), Line: d.consumed + d.lineBase, Offset: offset}
+}
 
-		parts := strings.SplitN(trimmed, ":", 2)
-		if len(parts) != 2 {
-			d.advance()
-			continue
-		}
+// conversionError is typeError for a value the parser or unmarshaler of t
+// rejected with err.
+func (d *decoder) conversionError(value string, t reflect.Type, err error) error {
+	typeErr := d.typeError(value, t)
+	typeErr.Err = err
+	return typeErr
+}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
+func (d *decoder) pushPath(segment string) {
+	d.path = append(d.path, segment)
+}
 
-		if fieldIdx, ok := fieldMap[key]; ok {
-			if err := d.setPrimitiveValue(v.Field(fieldIdx), value); err != nil {
-				return err
-			}
+func (d *decoder) pushIndex(i int) {
+	d.path = append(d.path, fmt.Sprintf("[%d]", i))
+}
+
+func (d *decoder) popPath() {
+	d.path = d.path[:len(d.path)-1]
+}
+
+// syntaxError returns the error for line, numbered from 1, pointing at
+// its first non-blank character.
+func (d *decoder) syntaxError(line int, msg string) *SyntaxError {
+	column, offset := d.position(line)
+	return &SyntaxError{
+		Line:    line + d.lineBase,
+		Column:  column,
+		Offset:  offset,
+		Message: msg,
+		Content: strings.TrimSpace(d.source(line)),
+	}
+}
+
+// position returns the 1-based column and the byte offset of the first
+// non-blank character of line as it appears in the input.
+func (d *decoder) position(line int) (column, offset int) {
+	if line <= 0 || line > len(d.offsets) {
+		return 1, 0
+	}
+	raw := d.source(line)
+	indent := len(raw) - len(strings.TrimLeft(raw, " \t"))
+	return indent + 1, d.offsetBase + d.offsets[line-1] + indent
+}
+
+// source returns line as it appears in the input, which may differ from
+// d.lines once a list item has been rewritten, or "" past its end.
+func (d *decoder) source(line int) string {
+	if line <= 0 || line > len(d.offsets) {
+		return ""
+	}
+	start, end := d.offsets[line-1], len(d.data)
+	if line < len(d.offsets) {
+		end = d.offsets[line] - 1
+	}
+	return strings.TrimSuffix(string(d.data[start:end]), "\r")
+}
+
+func (d *decoder) splitValues(s string) []string {
+	return splitQuoted(s, string(d.delimiterFor(s)))
+}
+
+// guessDelimiter infers the delimiter of a row when none was configured:
+// tab, then pipe, then semicolon, then comma. Quoted values are not
+// considered.
+func guessDelimiter(s string) Delimiter {
+	inQuotes := false
+	hasPipe := false
+	hasSemicolon := false
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case inQuotes && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case s[i] == '\t':
+			return DelimiterTab
+		case s[i] == '|':
+			hasPipe = true
+		case s[i] == ';':
+			hasSemicolon = true
 		}
+	}
 
-		d.advance()
+	if hasPipe {
+		return DelimiterPipe
+	} else if hasSemicolon {
+		return DelimiterSemicolon
 	}
+	return DelimiterComma
+}
 
-	return nil
+// splitQuoted splits s on delim, ignoring delimiters inside double-quoted
+// cells. Cells are returned as-is, including their quotes.
+func splitQuoted(s string, delim string) []string {
+	return splitCells(s, delim, func(before string) bool {
+		return strings.TrimSpace(before) == ""
+	})
 }
 
-func (d *decoder) parseArrayDeclaration(key string) (int, []string) {
-	// Match patterns like: key[3], key[3,], key[3|], key[3]{field1,field2}
-	re := regexp.MustCompile(`^(.+?)\[(\d+)(?:[,\t|])?\](?:\{([^}]+)\})?`)
+// splitCells splits s on delim like splitQuoted, with a quote starting a
+// quoted section wherever opens reports true for the text of the cell
+// before it.
+func splitCells(s string, delim string, opens func(before string) bool) []string {
+	var cells []string
+	start := 0
+	inQuotes := false
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case inQuotes && s[i] == '\\':
+			i++
+		case inQuotes && s[i] == '"':
+			inQuotes = false
+		case s[i] == '"' && opens(s[start:i]):
+			inQuotes = true
+		case !inQuotes && strings.HasPrefix(s[i:], delim):
+			cells = append(cells, s[start:i])
+			start = i + len(delim)
+			i += len(delim) - 1
+		}
+	}
+	return append(cells, s[start:])
+}
+
+// cutKeyValue splits line content at the separator after its key: the
+// first ':' or '=' outside the brackets and field braces of an array
+// header, so "key = value" reads like "key: value".
+func cutKeyValue(s string) (key, value string, ok bool) {
+	depth, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quoted = false
+			}
+		case c == '"' && depth > 0:
+			quoted = true
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth = max(depth-1, 0)
+		case depth == 0 && (c == ':' || c == '='):
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// Lengths reported by parseArrayDeclaration besides actual counts.
+const (
+	notArray = -1
+
+	// openLength is the length of an array header without a count, such
+	// as key[] or key[?]
+	openLength = -2
+)
+
+// parseArrayDeclaration parses an array header such as key[3],
+// key[3|]{field1|field2} or key[], returning its length, its fields for a
+// table and the delimiter it declares, if any.
+func (d *decoder) parseArrayDeclaration(key string) (int, []string, Delimiter) {
+	re := regexp.MustCompile(`^(.*?)\[(\d+|\?)?([,\t|;])?\](?:\{((?:"(?:[^"\\]|\\.)*"|[^}"])+)\})?`)
 	matches := re.FindStringSubmatch(key)
 	if len(matches) == 0 {
-		return -1, nil
+		return notArray, nil, ""
 	}
 
-	length, _ := strconv.Atoi(matches[2])
+	length := openLength
+	if n, err := strconv.Atoi(matches[2]); err == nil {
+		length = n
+	}
+	delim := Delimiter(matches[3])
 
 	var fieldNames []string
-	if len(matches) > 3 && matches[3] != "" {
-		fields := strings.Split(matches[3], ",")
-		for _, field := range fields {
-			fieldNames = append(fieldNames, strings.TrimSpace(field))
+	if matches[4] != "" {
+		defer d.declareDelimiter(delim)()
+		for _, field := range d.splitValues(matches[4]) {
+			fieldNames = append(fieldNames, unquote(strings.TrimSpace(field)))
 		}
 	}
 
-	return length, fieldNames
+	return length, fieldNames, delim
 }
 
 func (d *decoder) extractKeyFromArray(key string) string {
-	re := regexp.MustCompile(`^(.+?)\[`)
+	re := regexp.MustCompile(`^(.*?)\[`)
 	matches := re.FindStringSubmatch(key)
 	if len(matches) > 1 {
 		return matches[1]
@@ -514,13 +1299,50 @@ func (d *decoder) extractKeyFromArray(key string) string {
 	return key
 }
 
+// setValue is setPrimitiveValue for the value at the current path,
+// reporting it to OnValue first.
+func (d *decoder) setValue(v reflect.Value, s string) error {
+	if err := d.observe(s); err != nil {
+		return err
+	}
+	return d.collect(d.setPrimitiveValue(v, s))
+}
+
+func (d *decoder) observe(raw string) error {
+	if d.opts.OnValue == nil {
+		return nil
+	}
+	return d.opts.OnValue(formatPath(d.path), strings.TrimSpace(raw))
+}
+
 func (d *decoder) setPrimitiveValue(v reflect.Value, s string) error {
-	s = strings.TrimSpace(s)
+	if d.ids != nil {
+		s = d.resolveID(s)
+	}
+	if u, ok := unmarshalerFor(v); ok {
+		if err := u.UnmarshalTOON([]byte(strings.TrimSpace(s))); err != nil {
+			return d.conversionError(strings.TrimSpace(s), v.Type(), err)
+		}
+		return nil
+	}
+	raw := strings.TrimSpace(s)
+	quoted := isQuoted(raw)
+	s = unquote(raw)
 
-	// Handle quoted strings
-	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
-		s = s[1 : len(s)-1]
-		s = strings.ReplaceAll(s, "\\\"", "\"")
+	if isScalarType(v.Type()) {
+		return d.setScalarValue(v, raw, s)
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.Bool:
+		if quoted {
+			if d.opts.StrictTypes {
+				return d.typeError(raw, v.Type())
+			}
+			d.warn(d.consumed, "quoted value %s coerced to %s", raw, v.Kind())
+		}
 	}
 
 	switch v.Kind() {
@@ -529,64 +1351,108 @@ func (d *decoder) setPrimitiveValue(v reflect.Value, s string) error {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		i, err := strconv.ParseInt(s, 10, 64)
 		if err != nil {
-			return err
+			if d.opts.StrictTypes {
+				return d.typeError(raw, v.Type())
+			}
+			if i, err = d.weakInt(s, v.Kind(), err); err != nil {
+				return d.typeError(raw, v.Type())
+			}
+		}
+		if v.OverflowInt(i) {
+			return d.typeError(raw, v.Type())
 		}
 		v.SetInt(i)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		u, err := strconv.ParseUint(s, 10, 64)
 		if err != nil {
-			return err
+			if d.opts.StrictTypes {
+				return d.typeError(raw, v.Type())
+			}
+			if u, err = d.weakUint(s, v.Kind(), err); err != nil {
+				return d.typeError(raw, v.Type())
+			}
+		}
+		if v.OverflowUint(u) {
+			return d.typeError(raw, v.Type())
 		}
 		v.SetUint(u)
 	case reflect.Float32, reflect.Float64:
 		f, err := strconv.ParseFloat(s, 64)
 		if err != nil {
-			return err
+			if d.opts.StrictTypes {
+				return d.typeError(raw, v.Type())
+			}
+			if f, err = d.weakFloat(s, v.Kind(), err); err != nil {
+				return d.typeError(raw, v.Type())
+			}
 		}
 		v.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		c, err := strconv.ParseComplex(s, v.Type().Bits())
+		if err != nil {
+			return d.typeError(raw, v.Type())
+		}
+		v.SetComplex(c)
 	case reflect.Bool:
 		b, err := strconv.ParseBool(s)
+		if d.opts.StrictTypes && s != "true" && s != "false" {
+			return d.typeError(raw, v.Type())
+		}
 		if err != nil {
-			return err
+			if b, err = d.weakBool(s, err); err != nil {
+				return d.typeError(raw, v.Type())
+			}
+		} else if s != "true" && s != "false" {
+			d.warn(d.consumed, "value %s coerced to bool", s)
 		}
 		v.SetBool(b)
 	case reflect.Interface:
-		// Try to determine type
-		if s == "null" {
+		// Quoted values are always strings; bare ones are typed by content
+		if quoted {
+			v.Set(reflect.ValueOf(s))
+		} else if lit, ok := parseLiteral(s); ok && lit == nil {
 			v.Set(reflect.Zero(v.Type()))
-		} else if i, err := strconv.ParseInt(s, 10, 64); err == nil {
-			v.Set(reflect.ValueOf(i))
-		} else if f, err := strconv.ParseFloat(s, 64); err == nil {
-			v.Set(reflect.ValueOf(f))
-		} else if b, err := strconv.ParseBool(s); err == nil {
-			v.Set(reflect.ValueOf(b))
+		} else if ok && d.numbers && lit != true && lit != false {
+			v.Set(reflect.ValueOf(json.Number(s)))
+		} else if ok {
+			v.Set(reflect.ValueOf(lit))
 		} else {
 			v.Set(reflect.ValueOf(s))
 		}
 	case reflect.Ptr:
+		if raw == "null" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
 		if v.IsNil() {
 			v.Set(reflect.New(v.Type().Elem()))
 		}
-		return d.setPrimitiveValue(v.Elem(), s)
+		return d.setPrimitiveValue(v.Elem(), raw)
+	case reflect.Slice:
+		if isByteSlice(v.Type()) {
+			if !quoted && s == "null" {
+				v.Set(reflect.Zero(v.Type()))
+				return nil
+			}
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return d.conversionError(raw, v.Type(), err)
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		if !d.opts.WeaklyTypedInput || d.opts.StrictTypes {
+			return d.typeError(raw, v.Type())
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := d.setPrimitiveValue(elem, raw); err != nil {
+			return err
+		}
+		d.warn(d.consumed, "value %s coerced to one-element slice", raw)
+		v.Set(reflect.Append(reflect.MakeSlice(v.Type(), 0, 1), elem))
 	default:
-		return fmt.Errorf("unsupported type: %v", v.Kind())
+		return d.typeError(raw, v.Type())
 	}
 
 	return nil
 }
-
-func getFieldName(field reflect.StructField) string {
-	if tag := field.Tag.Get("toon"); tag != "" {
-		parts := strings.Split(tag, ",")
-		return parts[0]
-	}
-	if tag := field.Tag.Get("json"); tag != "" {
-		parts := strings.Split(tag, ",")
-		return parts[0]
-	}
-	name := field.Name
-	if len(name) > 0 {
-		return strings.ToLower(name[:1]) + name[1:]
-	}
-	return name
-}
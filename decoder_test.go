@@ -0,0 +1,837 @@
+package toon_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestUnmarshalLenientInput(t *testing.T) {
+	type Doc struct {
+		Context Context  `toon:"context"`
+		Friends []string `toon:"friends"`
+		Hikes   []Hike   `toon:"hikes"`
+		Numbers []int    `toon:"numbers"`
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		opts  toon.UnmarshalOptions
+		want  Doc
+	}{
+		{
+			name:  "tab indentation",
+			input: "context:\n\ttask: Our favorite hikes together\n\tlocation: Boulder\nfriends[2]: ana,luis\n",
+			opts:  toon.UnmarshalOptions{TabWidth: 4},
+			want: Doc{
+				Context: Context{Task: "Our favorite hikes together", Location: "Boulder"},
+				Friends: []string{"ana", "luis"},
+			},
+		},
+		{
+			name: "blank cells and trailing delimiters",
+			input: "hikes[2]{id,name,distanceKm,elevationGain,companion,wasSunny}:\n" +
+				"  1,,7.5,320,ana,true,\n  2,Ridge Overlook,,540,,false\nnumbers[3]: 1,,3,\n",
+			opts: toon.DefaultUnmarshalOptions(),
+			want: Doc{
+				Hikes: []Hike{
+					{ID: 1, DistanceKm: 7.5, ElevationGain: 320, Companion: "ana", WasSunny: true},
+					{ID: 2, Name: "Ridge Overlook", ElevationGain: 540},
+				},
+				Numbers: []int{1, 0, 3},
+			},
+		},
+		{
+			// A table with fewer rows than declared stops at the next key
+			name: "short table",
+			input: "hikes[3]{id,name,distanceKm,elevationGain,companion,wasSunny}:\n" +
+				"  1,Blue Lake Trail,7.5,320,ana,true\n  2,Ridge Overlook,9.2,540,luis,false\nfriends[2]: ana,luis\n",
+			opts: toon.DefaultUnmarshalOptions(),
+			want: Doc{
+				Hikes: []Hike{
+					{ID: 1, Name: "Blue Lake Trail", DistanceKm: 7.5, ElevationGain: 320, Companion: "ana", WasSunny: true},
+					{ID: 2, Name: "Ridge Overlook", DistanceKm: 9.2, ElevationGain: 540, Companion: "luis"},
+				},
+				Friends: []string{"ana", "luis"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out Doc
+			if err := toon.UnmarshalWithOptions([]byte(tt.input), &out, tt.opts); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(out, tt.want) {
+				t.Errorf("Unmarshal = %+v, want %+v", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalPointerDestinations(t *testing.T) {
+	input := `friends[2]: ana,luis
+hikes[1]{id,name,distanceKm,elevationGain,companion,wasSunny}:
+  1,Blue Lake Trail,7.5,320,ana,true
+extra:
+  season: spring_2025
+trail[1]:
+  - id: 2
+    name: Ridge Overlook
+`
+
+	var result struct {
+		Friends *[]string       `toon:"friends"`
+		Hikes   *[]*Hike        `toon:"hikes"`
+		Extra   *map[string]any `toon:"extra"`
+		Trail   []*Hike         `toon:"trail"`
+	}
+
+	if err := toon.Unmarshal([]byte(input), &result); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if result.Friends == nil || len(*result.Friends) != 2 {
+		t.Errorf("Expected 2 friends, got %v", result.Friends)
+	}
+	if result.Hikes == nil || len(*result.Hikes) != 1 || (*result.Hikes)[0].Name != "Blue Lake Trail" {
+		t.Errorf("Expected 1 hike, got %v", result.Hikes)
+	}
+	if result.Extra == nil || (*result.Extra)["season"] != "spring_2025" {
+		t.Errorf("Expected extra.season, got %v", result.Extra)
+	}
+	if len(result.Trail) != 1 || result.Trail[0].Name != "Ridge Overlook" {
+		t.Errorf("Expected 1 trail, got %v", result.Trail)
+	}
+}
+
+func TestBareDashListItems(t *testing.T) {
+	type Stop struct {
+		Name string   `toon:"name"`
+		Km   int      `toon:"km"`
+		Tags []string `toon:"tags"`
+	}
+	type Trail struct {
+		Stops []Stop           `toon:"stops"`
+		Notes []map[string]int `toon:"notes"`
+		Names []string         `toon:"names"`
+	}
+
+	input := `stops[3]:
+  -
+    name: hut
+    km: 3
+  - name: peak
+    km: 7
+  -
+      name: lake
+      tags[2]: a,b
+notes[1]:
+  -
+    x: 1
+names[2]:
+  - ana
+  -
+`
+
+	var warnings []toon.Warning
+	opts := toon.DefaultUnmarshalOptions()
+	opts.Warnings = &warnings
+
+	var out Trail
+	if err := toon.UnmarshalWithOptions([]byte(input), &out, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := Trail{
+		Stops: []Stop{{Name: "hut", Km: 3}, {Name: "peak", Km: 7}, {Name: "lake", Tags: []string{"a", "b"}}},
+		Notes: []map[string]int{{"x": 1}},
+		Names: []string{"ana", ""},
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("Unmarshal = %+v, want %+v", out, want)
+	}
+	if len(warnings) != 1 || warnings[0].Line != 15 {
+		t.Errorf("warnings = %v, want one for the empty item on line 15", warnings)
+	}
+}
+
+func TestEmptyDocuments(t *testing.T) {
+	type Empty struct{}
+	type Config struct {
+		Name string `toon:"name"`
+	}
+
+	for _, v := range []any{Empty{}, map[string]int{}, []int{}, (*Config)(nil)} {
+		data, err := toon.Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal(%#v) failed: %v", v, err)
+		}
+		if len(data) != 0 || !toon.Valid(data, toon.LevelStructure) {
+			t.Errorf("Marshal(%#v) = %q, want a valid empty document", v, data)
+		}
+	}
+
+	for _, input := range []string{"", "\n\n", "#toon 1.0\n"} {
+		config := Config{Name: "kept"}
+		var m map[string]int
+		s := []int{1, 2}
+		var a any
+		var p *Config
+		var n *int
+
+		for _, target := range []any{&config, &m, &s, &a, &p, &n} {
+			if err := toon.Unmarshal([]byte(input), target); err != nil {
+				t.Fatalf("Unmarshal(%q) into %T failed: %v", input, target, err)
+			}
+		}
+
+		if config.Name != "kept" {
+			t.Errorf("Unmarshal(%q) changed struct to %+v", input, config)
+		}
+		if m == nil || len(m) != 0 {
+			t.Errorf("Unmarshal(%q) map = %#v, want empty non-nil map", input, m)
+		}
+		if s == nil || len(s) != 0 {
+			t.Errorf("Unmarshal(%q) slice = %#v, want empty non-nil slice", input, s)
+		}
+		if got, ok := a.(map[string]any); !ok || len(got) != 0 {
+			t.Errorf("Unmarshal(%q) interface = %#v, want empty map[string]any", input, a)
+		}
+		if p == nil || *p != (Config{}) {
+			t.Errorf("Unmarshal(%q) struct pointer = %v, want zero Config", input, p)
+		}
+		if n != nil {
+			t.Errorf("Unmarshal(%q) int pointer = %v, want nil", input, n)
+		}
+	}
+}
+
+func TestUnmarshalWarnings(t *testing.T) {
+	input := `context:
+  task: hike
+  task: run
+hikes[3]{id,name,distanceKm,elevationGain,companion,wasSunny}:
+  1,Blue Lake Trail,7.5,"320",ana,true,extra
+  2,Ridge Overlook,,540,luis,false
+`
+
+	var warnings []toon.Warning
+	opts := toon.DefaultUnmarshalOptions()
+	opts.Warnings = &warnings
+
+	var result HikesData
+	if err := toon.UnmarshalWithOptions([]byte(input), &result, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	expected := []string{
+		`line 3: duplicate key "task" overrides earlier value`,
+		`line 5: 1 extra cells ignored`,
+		`line 5: quoted value "320" coerced to int`,
+		`line 6: blank cell for field "distanceKm" left as zero value`,
+		`line 4: array declares 3 items, found 2`,
+	}
+	if len(warnings) != len(expected) {
+		t.Fatalf("Expected %d warnings, got %v", len(expected), warnings)
+	}
+	for i, want := range expected {
+		if got := warnings[i].String(); got != want {
+			t.Errorf("Warning %d: expected %q, got %q", i, want, got)
+		}
+	}
+}
+
+func TestStrictTypes(t *testing.T) {
+	type Hike struct {
+		ID         int     `toon:"id"`
+		DistanceKm int     `toon:"distanceKm"`
+		Price      float64 `toon:"price"`
+		Sunny      bool    `toon:"sunny"`
+	}
+	type Trip struct {
+		Hikes []Hike `toon:"hikes"`
+	}
+
+	opts := toon.DefaultUnmarshalOptions()
+	opts.StrictTypes = true
+
+	tests := []struct {
+		name  string
+		input string
+		path  string
+	}{
+		{"float into int", "hikes[2]{id,distanceKm,price,sunny}:\n  1,7,1.5,true\n  2,9.2,2.5,false\n", "hikes[1].distanceKm"},
+		{"quoted number", "hikes[1]{id,distanceKm,price,sunny}:\n  1,7,\"1.5\",true\n", "hikes[0].price"},
+		{"numeric bool", "hikes[1]{id,distanceKm,price,sunny}:\n  1,7,1.5,1\n", "hikes[0].sunny"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var trip Trip
+			err := toon.UnmarshalWithOptions([]byte(tt.input), &trip, opts)
+			var typeErr *toon.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				t.Fatalf("expected *UnmarshalTypeError, got %v", err)
+			}
+			if typeErr.Path != tt.path {
+				t.Errorf("Path = %q, want %q", typeErr.Path, tt.path)
+			}
+		})
+	}
+
+	var trip Trip
+	valid := "hikes[1]{id,distanceKm,price,sunny}:\n  1,7,1.5,true\n"
+	if err := toon.UnmarshalWithOptions([]byte(valid), &trip, opts); err != nil {
+		t.Errorf("valid input failed: %v", err)
+	}
+}
+
+func TestDisallowUnknownFields(t *testing.T) {
+	type Hike struct {
+		ID   int    `toon:"id"`
+		Name string `toon:"name"`
+	}
+	type Trip struct {
+		Owner string `toon:"owner"`
+		Hikes []Hike `toon:"hikes"`
+		Stops []Hike `toon:"stops"`
+	}
+
+	input := `ownr: ana
+meta:
+  owner: nested
+owner: ana
+hikes[2]{id,name,dist}:
+  1,Blue Lake,7.5
+  2,Ridge,9
+stops[1]:
+  - id: 3
+    nmae: Hut
+`
+	want := Trip{
+		Owner: "ana",
+		Hikes: []Hike{{1, "Blue Lake"}, {2, "Ridge"}},
+		Stops: []Hike{{ID: 3}},
+	}
+
+	// Unknown keys are skipped with their nested lines by default
+	var trip Trip
+	if err := toon.Unmarshal([]byte(input), &trip); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(trip, want) {
+		t.Errorf("Unmarshal = %+v, want %+v", trip, want)
+	}
+
+	opts := toon.DefaultUnmarshalOptions()
+	opts.DisallowUnknownFields = true
+	trip = Trip{}
+	err := toon.UnmarshalWithOptions([]byte(input), &trip, opts)
+	var errs toon.Errors
+	if !errors.As(err, &errs) || len(errs) != 4 {
+		t.Fatalf("err = %v, want 4 errors", err)
+	}
+	wantErrs := []string{
+		`toon: syntax error at line 1, column 1: unknown key "ownr" for toon_test.Trip`,
+		`toon: syntax error at line 2, column 1: unknown key "meta" for toon_test.Trip`,
+		`toon: syntax error at line 5, column 1: unknown column "dist" for toon_test.Hike`,
+		`toon: syntax error at line 10, column 5: unknown key "nmae" for toon_test.Hike`,
+	}
+	for i, want := range wantErrs {
+		if errs[i].Error() != want {
+			t.Errorf("errs[%d] = %q, want %q", i, errs[i], want)
+		}
+	}
+	if !reflect.DeepEqual(trip, want) {
+		t.Errorf("known fields = %+v, want %+v", trip, want)
+	}
+
+	sparse := "hikes[2]{=}:\n  id=1,name=A\n  id=2,rating=5\n"
+	err = toon.UnmarshalWithOptions([]byte(sparse), &trip, opts)
+	var syntaxErr *toon.SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Line != 3 || !strings.Contains(err.Error(), `unknown column "rating"`) {
+		t.Errorf("sparse table: err = %v, want an unknown column at line 3", err)
+	}
+
+	var m map[string]Hike
+	if err := toon.UnmarshalWithOptions([]byte("b:\n  id: 1\nc:\n  id: 2\n"), &m, opts); err != nil {
+		t.Errorf("map keys rejected: %v", err)
+	}
+}
+
+func TestUnmarshalOnValue(t *testing.T) {
+	input := "context:\n  task: \"hikes\"\n  location: Boulder\nfriends[2]: ana,luis\nhikes[1]{id,name}:\n  1,Blue Lake\n"
+
+	var seen []string
+	opts := toon.DefaultUnmarshalOptions()
+	opts.OnValue = func(path, raw string) error {
+		seen = append(seen, path+"="+raw)
+		return nil
+	}
+
+	var data HikesData
+	if err := toon.UnmarshalWithOptions([]byte(input), &data, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := []string{
+		`context.task="hikes"`,
+		"context.location=Boulder",
+		"friends[0]=ana",
+		"friends[1]=luis",
+		"hikes[0].id=1",
+		"hikes[0].name=Blue Lake",
+	}
+	if strings.Join(seen, "\n") != strings.Join(want, "\n") {
+		t.Errorf("OnValue saw %q, want %q", seen, want)
+	}
+
+	errBlocked := errors.New("blocked")
+	opts.OnValue = func(path, raw string) error {
+		if path == "friends[1]" {
+			return errBlocked
+		}
+		return nil
+	}
+	if err := toon.UnmarshalWithOptions([]byte(input), &data, opts); !errors.Is(err, errBlocked) {
+		t.Errorf("expected OnValue error, got %v", err)
+	}
+}
+
+func TestMergeMaps(t *testing.T) {
+	type Limits struct {
+		Rate  int `toon:"rate"`
+		Burst int `toon:"burst"`
+	}
+	type Config struct {
+		Services map[string]map[string]string `toon:"services"`
+		Limits   map[string]Limits            `toon:"limits"`
+		Extra    map[string]any               `toon:"extra"`
+	}
+
+	base := "services:\n  api:\n    host: a.local\n    port: \"80\"\nlimits:\n  api:\n    rate: 10\n    burst: 20\n" +
+		"extra:\n  flags:\n    beta: true\n"
+	override := "services:\n  api:\n    port: \"8080\"\nlimits:\n  api:\n    burst: 50\nextra:\n  flags:\n    dark: false\n"
+
+	opts := toon.DefaultUnmarshalOptions()
+	opts.MergeMaps = true
+	var cfg Config
+	for _, doc := range []string{base, override} {
+		if err := toon.UnmarshalWithOptions([]byte(doc), &cfg, opts); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+	}
+	want := Config{
+		Services: map[string]map[string]string{"api": {"host": "a.local", "port": "8080"}},
+		Limits:   map[string]Limits{"api": {Rate: 10, Burst: 50}},
+		Extra:    map[string]any{"flags": map[string]any{"beta": true, "dark": false}},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("merged = %+v, want %+v", cfg, want)
+	}
+
+	// Without the option each key replaces its element
+	if err := toon.Unmarshal([]byte(override), &cfg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if cfg.Limits["api"] != (Limits{Burst: 50}) || len(cfg.Services["api"]) != 1 {
+		t.Errorf("replaced = %+v", cfg)
+	}
+}
+
+func TestVersionHeader(t *testing.T) {
+	// Directives that are rejected are covered by TestSyntaxErrorDetails
+	for _, tt := range []struct {
+		input string
+		opts  toon.UnmarshalOptions
+	}{
+		{"#toon 1.0\nname: Alice\n", toon.UnmarshalOptions{RequireVersion: true}},
+		{"#toon 1.7\nname: Alice\n", toon.DefaultUnmarshalOptions()},
+		{"name: Alice\n", toon.DefaultUnmarshalOptions()},
+	} {
+		var out struct {
+			Name string `toon:"name"`
+		}
+		if err := toon.UnmarshalWithOptions([]byte(tt.input), &out, tt.opts); err != nil || out.Name != "Alice" {
+			t.Errorf("Unmarshal(%q) = %+v, %v", tt.input, out, err)
+		}
+	}
+}
+
+func TestChecksumFooter(t *testing.T) {
+	data := struct {
+		Name    string   `toon:"name"`
+		Friends []string `toon:"friends"`
+	}{Name: "Alice", Friends: []string{"ana", "luis"}}
+
+	result, err := toon.Marshal(data, toon.WithChecksum(true), toon.WithVersionHeader(true))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(result), "\n#crc32: ") {
+		t.Fatalf("Expected checksum footer, got:\n%s", result)
+	}
+
+	opts := toon.DefaultUnmarshalOptions()
+	opts.VerifyChecksum = true
+	for _, tt := range []struct {
+		name  string
+		input string
+		err   error
+	}{
+		{"as written", string(result), nil},
+		{"CRLF copy", strings.ReplaceAll(string(result), "\n", "\r\n"), nil},
+		{"tampered", strings.Replace(string(result), "Alice", "Alicia", 1), toon.ErrChecksum},
+		{"missing footer", "name: Alice\n", toon.ErrChecksum},
+	} {
+		if err := toon.UnmarshalWithOptions([]byte(tt.input), &data, opts); !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestOpenEndedArrayHeaders(t *testing.T) {
+	type Row struct {
+		ID   int    `toon:"id"`
+		Name string `toon:"name"`
+	}
+	type Feed struct {
+		Rows []Row    `toon:"rows"`
+		Tags []string `toon:"tags"`
+	}
+
+	// Headers written by WithOmitArrayCounts are covered by
+	// TestMarshalOptionLayouts; a question mark stands for the count too
+	opts := toon.DefaultUnmarshalOptions()
+	opts.Strict = true
+	var out Feed
+	input := "rows[?]{id,name}:\n  3,c\ntags[?|]: p|q|r\n"
+	if err := toon.UnmarshalWithOptions([]byte(input), &out, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if want := (Feed{Rows: []Row{{3, "c"}}, Tags: []string{"p", "q", "r"}}); !reflect.DeepEqual(out, want) {
+		t.Errorf("Unmarshal = %+v, want %+v", out, want)
+	}
+}
+
+func TestErrorOffsets(t *testing.T) {
+	type Stop struct {
+		Name string `toon:"name"`
+		Km   int    `toon:"km"`
+	}
+	type Trail struct {
+		Stops []Stop `toon:"stops"`
+	}
+
+	input := "stops[2]{name,km}:\n  hut,3\n  peak,\n"
+	opts := toon.DefaultUnmarshalOptions()
+	opts.Strict = true
+
+	var out Trail
+	err := toon.UnmarshalWithOptions([]byte(input), &out, opts)
+	var syntaxErr *toon.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Unmarshal = %v, want *SyntaxError", err)
+	}
+	if syntaxErr.Line != 3 || syntaxErr.Column != 3 || syntaxErr.Offset != strings.Index(input, "peak") {
+		t.Errorf("error at line %d, column %d, offset %d, want line 3, column 3, offset %d",
+			syntaxErr.Line, syntaxErr.Column, syntaxErr.Offset, strings.Index(input, "peak"))
+	}
+
+	// Offsets point into the original input even for rewritten list items
+	input = "stops[2]:\n  - name: hut\n    km: 3\n  - name: peak\n    km: far\n"
+	opts.StrictTypes = true
+	err = toon.UnmarshalWithOptions([]byte(input), &out, opts)
+	var typeErr *toon.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Unmarshal = %v, want *UnmarshalTypeError", err)
+	}
+	if want := strings.Index(input, "km: far"); typeErr.Line != 5 || typeErr.Offset != want {
+		t.Errorf("error at line %d, offset %d, want line 5, offset %d", typeErr.Line, typeErr.Offset, want)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	type Stop struct {
+		Name string `toon:"name"`
+		Km   int    `toon:"km"`
+	}
+	type Trail struct {
+		Stops []Stop `toon:"stops"`
+		Days  int    `toon:"days"`
+	}
+
+	input := "stops[3]{name,km}:\n  hut,\n  peak,7\n  ,9\ndays: many\n"
+	opts := toon.DefaultUnmarshalOptions()
+	opts.Strict = true
+	opts.StrictTypes = true
+
+	var out Trail
+	err := toon.UnmarshalWithOptions([]byte(input), &out, opts)
+
+	var errs toon.Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("Unmarshal = %v, want toon.Errors with 3 errors", err)
+	}
+	var lines []int
+	for _, e := range errs {
+		var syntaxErr *toon.SyntaxError
+		if errors.As(e, &syntaxErr) {
+			lines = append(lines, syntaxErr.Line)
+		}
+	}
+	if !reflect.DeepEqual(lines, []int{2, 4}) {
+		t.Errorf("syntax errors on lines %v, want [2 4]", lines)
+	}
+	var typeErr *toon.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Path != "days" {
+		t.Errorf("Unmarshal = %v, want an *UnmarshalTypeError at days", err)
+	}
+
+	// Decoding carries on past each problem
+	if len(out.Stops) != 3 || out.Stops[1] != (Stop{"peak", 7}) || out.Stops[2].Km != 9 {
+		t.Errorf("Unmarshal = %+v, want the valid cells decoded", out)
+	}
+
+	// A single problem is returned on its own
+	err = toon.UnmarshalWithOptions([]byte("days: many\n"), &out, opts)
+	if !errors.As(err, &typeErr) || errors.As(err, &errs) {
+		t.Errorf("Unmarshal = %v, want a bare *UnmarshalTypeError", err)
+	}
+}
+
+var errNotAGrade = errors.New("not a grade")
+
+type letterGrade string
+
+func (g *letterGrade) UnmarshalTOON(data []byte) error {
+	if len(data) != 1 || data[0] < 'A' || data[0] > 'F' {
+		return errNotAGrade
+	}
+	*g = letterGrade(data)
+	return nil
+}
+
+func TestUnmarshalTypeErrorPaths(t *testing.T) {
+	type Hike struct {
+		Name       string      `toon:"name"`
+		DistanceKm int         `toon:"distanceKm"`
+		Day        time.Time   `toon:"day,format=2006-01-02"`
+		Grade      letterGrade `toon:"grade"`
+	}
+	type Trip struct {
+		Hikes []Hike         `toon:"hikes"`
+		Stops []Hike         `toon:"stops"`
+		Days  map[string]int `toon:"days"`
+		Seats uint8          `toon:"seats"`
+	}
+
+	input := `hikes[3]{name,distanceKm,day,grade}:
+  Blue Lake,7,2024-05-01,A
+  Ridge,9,2024-05-02,B
+  Saddle,abc,May 3,Z
+stops[1]:
+  - name: Hut
+    distanceKm: far
+days:
+  spring: many
+seats: 300
+`
+	var trip Trip
+	err := toon.Unmarshal([]byte(input), &trip)
+	var errs toon.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Unmarshal = %v, want toon.Errors", err)
+	}
+
+	want := []struct {
+		path string
+		line int
+		typ  string
+	}{
+		{"hikes[2].distanceKm", 4, "int"},
+		{"hikes[2].day", 4, "time.Time"},
+		{"hikes[2].grade", 4, "toon_test.letterGrade"},
+		{"stops[0].distanceKm", 7, "int"},
+		{"days.spring", 9, "int"},
+		{"seats", 10, "uint8"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Unmarshal = %v, want %d errors", err, len(want))
+	}
+	for i, w := range want {
+		var typeErr *toon.UnmarshalTypeError
+		if !errors.As(errs[i], &typeErr) {
+			t.Errorf("errs[%d] = %v, want *UnmarshalTypeError", i, errs[i])
+			continue
+		}
+		if typeErr.Path != w.path || typeErr.Line != w.line || typeErr.Type.String() != w.typ {
+			t.Errorf("errs[%d] at %s, line %d, type %s, want %s, line %d, type %s",
+				i, typeErr.Path, typeErr.Line, typeErr.Type, w.path, w.line, w.typ)
+		}
+	}
+
+	if want := "toon: cannot unmarshal abc into int at hikes[2].distanceKm, line 4"; errs[0].Error() != want {
+		t.Errorf("errs[0] = %q, want %q", errs[0], want)
+	}
+	// The reason a parser or unmarshaler gave is kept
+	var parseErr *time.ParseError
+	if !errors.As(errs[1], &parseErr) {
+		t.Errorf("errs[1] = %v, want it to wrap a *time.ParseError", errs[1])
+	}
+	if !errors.Is(errs[2], errNotAGrade) || !strings.HasSuffix(errs[2].Error(), ": not a grade") {
+		t.Errorf("errs[2] = %v, want it to wrap errNotAGrade", errs[2])
+	}
+
+	// The values around each problem are still decoded
+	if trip.Hikes[2].Name != "Saddle" || trip.Hikes[1].DistanceKm != 9 || trip.Stops[0].Name != "Hut" {
+		t.Errorf("Unmarshal = %+v, want the valid values decoded", trip)
+	}
+}
+
+func TestMapKeyTypeErrors(t *testing.T) {
+	type Stage struct {
+		Name string `toon:"name"`
+	}
+	type Tour struct {
+		Scores map[int]string  `toon:"scores"`
+		Stages map[int64]Stage `toon:"stages"`
+		Counts map[uint8]int   `toon:"counts"`
+	}
+
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"scores:\n  ten: x\n", "toon: cannot unmarshal ten into int at scores, line 2"},
+		{"scores:\n  1.5: x\n", "toon: cannot unmarshal 1.5 into int at scores, line 2"},
+		{"counts:\n  256: 1\n", "toon: cannot unmarshal 256 into uint8 at counts, line 2"},
+		{"counts:\n  -1: 1\n", "toon: cannot unmarshal -1 into uint8 at counts, line 2"},
+		{"stages[1]{_key,name}:\n  x,Col\n", "toon: cannot unmarshal x into int64 at stages._key, line 2"},
+	}
+	for _, tt := range tests {
+		var out Tour
+		err := toon.UnmarshalWithOptions([]byte(tt.data), &out, toon.UnmarshalOptions{WeaklyTypedInput: true})
+		var typeErr *toon.UnmarshalTypeError
+		if !errors.As(err, &typeErr) || err.Error() != tt.want {
+			t.Errorf("Unmarshal(%q) = %v, want %s", tt.data, err, tt.want)
+		}
+	}
+}
+
+func TestArrayHeaderTypeErrors(t *testing.T) {
+	type Doc struct {
+		Name  string            `toon:"name"`
+		N     int               `toon:"n"`
+		Tags  map[string]string `toon:"tags"`
+		Pair  [2]int            `toon:"pair"`
+		After string            `toon:"after"`
+	}
+
+	tests := []struct {
+		input string
+		path  string
+		typ   string
+	}{
+		{"name[2]: a,b\n", "name", "string"},
+		{"n[1]: 3\n", "n", "int"},
+		{"name[2]:\n  - a\n  - b\n", "name", "string"},
+		{"n[1]{x}:\n  1\n", "n", "int"},
+		{"tags[2]: v\n", "tags", "map[string]string"},
+		{"pair[3]: 1,2,3\n", "pair", "[2]int"},
+	}
+	for _, tt := range tests {
+		var doc Doc
+		err := toon.Unmarshal([]byte(tt.input+"after: ok\n"), &doc)
+		var typeErr *toon.UnmarshalTypeError
+		if !errors.As(err, &typeErr) || typeErr.Path != tt.path || typeErr.Type.String() != tt.typ || typeErr.Line != 1 {
+			t.Errorf("Unmarshal(%q) = %v, want *UnmarshalTypeError for %s at %s, line 1", tt.input, err, tt.typ, tt.path)
+		}
+		if doc.After != "ok" {
+			t.Errorf("Unmarshal(%q) stopped before the following keys", tt.input)
+		}
+	}
+
+	// Go arrays take every array form when the counts match
+	type Grid struct {
+		Pair  [2]int      `toon:"pair"`
+		Rows  [2]Hike     `toon:"rows"`
+		Cells [2][]string `toon:"cells"`
+	}
+	in := Grid{Pair: [2]int{1, 2}, Rows: [2]Hike{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, Cells: [2][]string{{"x"}, {"y", "z"}}}
+	data, err := toon.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var out Grid
+	if err := toon.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal(%q) failed: %v", data, err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestSyntaxErrorDetails(t *testing.T) {
+	type Doc struct {
+		Name    string   `toon:"name"`
+		IDs     []int    `toon:"ids"`
+		Hikes   []Hike   `toon:"hikes"`
+		Friends []string `toon:"friends"`
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		opts     toon.UnmarshalOptions
+		line     int
+		column   int
+		content  string
+		sentinel error
+	}{
+		{"line without a key", "name: a\n  oops\n", toon.UnmarshalOptions{Strict: true}, 2, 3, "oops", nil},
+		{"table into ints", "name: a\nids[1]{a}:\n  1\n", toon.UnmarshalOptions{}, 2, 1, "ids[1]{a}:", nil},
+		{"trailing delimiter", "hikes[1]{id,name}:\n  1,a,\n", toon.UnmarshalOptions{Strict: true}, 2, 3, "1,a,", nil},
+		{"blank cell", "hikes[1]{id,name}:\n  1,\n", toon.UnmarshalOptions{Strict: true}, 2, 3, "1,", nil},
+		{"short table", "hikes[3]{id,name}:\n  1,a\n  2,b\nfriends[2]: ana,luis\n", toon.UnmarshalOptions{Strict: true}, 1, 1, "hikes[3]{id,name}:", nil},
+		{"sparse row without pairs", "hikes[1]{=}:\n  Lake\n", toon.UnmarshalOptions{}, 2, 3, "Lake", nil},
+		{"unsupported version", "#toon 2.0\nname: a\n", toon.UnmarshalOptions{}, 1, 1, "#toon 2.0", toon.ErrVersion},
+		{"malformed version", "#toon one\nname: a\n", toon.UnmarshalOptions{}, 1, 1, "#toon one", nil},
+		{"missing version", "name: a\n", toon.UnmarshalOptions{RequireVersion: true}, 1, 1, "name: a", toon.ErrVersion},
+		{"checksum mismatch", "name: a\n#crc32: 00000000\n", toon.UnmarshalOptions{VerifyChecksum: true}, 2, 1, "#crc32: 00000000", toon.ErrChecksum},
+		{"missing checksum", "name: a\n", toon.UnmarshalOptions{VerifyChecksum: true}, 2, 1, "", toon.ErrChecksum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out Doc
+			err := toon.UnmarshalWithOptions([]byte(tt.input), &out, tt.opts)
+			var syntaxErr *toon.SyntaxError
+			if !errors.As(err, &syntaxErr) || !errors.Is(err, toon.ErrInvalidSyntax) {
+				t.Fatalf("err = %v, want a *SyntaxError", err)
+			}
+			if syntaxErr.Line != tt.line || syntaxErr.Column != tt.column || syntaxErr.Content != tt.content {
+				t.Errorf("error at line %d, column %d, content %q, want line %d, column %d, content %q",
+					syntaxErr.Line, syntaxErr.Column, syntaxErr.Content, tt.line, tt.column, tt.content)
+			}
+			if tt.sentinel != nil && !errors.Is(err, tt.sentinel) {
+				t.Errorf("err = %v, want it to match %v", err, tt.sentinel)
+			}
+		})
+	}
+
+	// Lines without a key are only warned about by default
+	var warnings []toon.Warning
+	var out Doc
+	if err := toon.UnmarshalWithOptions([]byte("name: a\n  oops\n"), &out, toon.UnmarshalOptions{Warnings: &warnings}); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].String() != "line 2: line without a key skipped" {
+		t.Errorf("warnings = %v", warnings)
+	}
+}
@@ -0,0 +1,143 @@
+package toon
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// delimiterSample is the number of table rows and inline arrays
+// AutoDetectDelimiter checks before settling on a delimiter.
+const delimiterSample = 64
+
+// candidateDelimiters are the delimiters detection chooses among, in the
+// order ties are broken.
+var candidateDelimiters = []Delimiter{DelimiterTab, DelimiterPipe, DelimiterSemicolon, DelimiterComma}
+
+// arrayHeaderPattern matches an array header line, optionally as a list
+// item, capturing its count, its column list and the text after the colon.
+var arrayHeaderPattern = regexp.MustCompile(`^(?:- )?[^\[:]+\[(\d+|\?)?[,\t|;]?\](?:\{((?:"(?:[^"\\]|\\.)*"|[^}"])+)\})?:(.*)$`)
+
+// delimiterSampleLine is a line whose number of cells is known up front: a
+// table row needs as many cells as its header has columns, an inline array
+// as many values as its header declares.
+type delimiterSampleLine struct {
+	line    int
+	columns string // the header's column list, for table rows
+	count   int    // the declared count, for inline arrays
+	text    string
+}
+
+// cells returns how many cells delim splits the line into and how many it
+// should have.
+func (s delimiterSampleLine) cells(delim Delimiter) (got, want int) {
+	got = len(splitQuoted(s.text, string(delim)))
+	if s.columns == "" {
+		return got, s.count
+	}
+	return got, len(splitQuoted(s.columns, string(delim)))
+}
+
+// detectDelimiter picks the delimiter that splits every sampled row and
+// inline array into the expected number of cells, preferring the one that
+// splits the most of them. It returns "" when no line is split by any
+// delimiter, leaving rows to be guessed one by one.
+func (d *decoder) detectDelimiter() (Delimiter, error) {
+	samples := d.delimiterSamples()
+
+	type result struct {
+		delim     Delimiter
+		evidence  int
+		mismatch  *delimiterSampleLine
+		got, want int
+	}
+	results := make([]result, len(candidateDelimiters))
+
+	for i, delim := range candidateDelimiters {
+		r := &results[i]
+		r.delim = delim
+		for j := range samples {
+			got, want := samples[j].cells(delim)
+			if got > 1 || (samples[j].columns != "" && want > 1) {
+				r.evidence++
+			}
+			if got != want && r.mismatch == nil {
+				r.mismatch, r.got, r.want = &samples[j], got, want
+			}
+		}
+	}
+
+	var best *result
+	for i := range results {
+		r := &results[i]
+		if r.mismatch == nil && r.evidence > 0 && (best == nil || r.evidence > best.evidence) {
+			best = r
+		}
+	}
+	if best != nil {
+		return best.delim, nil
+	}
+
+	// Report every delimiter found in the document, locating the error at
+	// the one found most often
+	var diagnostics []string
+	var likely *result
+	for i := range results {
+		r := &results[i]
+		if r.evidence == 0 {
+			continue
+		}
+		diagnostics = append(diagnostics, fmt.Sprintf("%s splits line %d into %d cells, expected %d",
+			strconv.Quote(string(r.delim)), r.mismatch.line, r.got, r.want))
+		if likely == nil || r.evidence > likely.evidence {
+			likely = r
+		}
+	}
+	if likely == nil {
+		return "", nil
+	}
+	return "", d.syntaxError(likely.mismatch.line, "cannot detect delimiter: "+strings.Join(diagnostics, "; "))
+}
+
+// delimiterSamples collects up to delimiterSample table rows and inline
+// arrays with a count, in document order. Sparse tables are skipped since
+// their rows hold any number of cells.
+func (d *decoder) delimiterSamples() []delimiterSampleLine {
+	var samples []delimiterSampleLine
+	columns, tableIndent := "", -1
+
+	for i, line := range d.lines {
+		if len(samples) == delimiterSample {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		indent := d.getIndent(line)
+		if tableIndent >= 0 {
+			if indent > tableIndent {
+				samples = append(samples, delimiterSampleLine{line: i + 1, columns: columns, text: trimmed})
+				continue
+			}
+			tableIndent = -1
+		}
+
+		m := arrayHeaderPattern.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		value := strings.TrimSpace(m[3])
+		switch {
+		case m[2] != "" && m[2] != sparseField && value == "":
+			columns, tableIndent = m[2], indent
+		case m[2] == "" && value != "":
+			if count, err := strconv.Atoi(m[1]); err == nil {
+				samples = append(samples, delimiterSampleLine{line: i + 1, count: count, text: value})
+			}
+		}
+	}
+	return samples
+}
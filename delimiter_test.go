@@ -0,0 +1,161 @@
+package toon_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestAutoDetectDelimiter(t *testing.T) {
+	type Note struct {
+		ID   int    `toon:"id"`
+		Text string `toon:"text"`
+	}
+	type Doc struct {
+		Notes []Note   `toon:"notes"`
+		Tags  []string `toon:"tags"`
+	}
+
+	opts := toon.DefaultUnmarshalOptions()
+	opts.AutoDetectDelimiter = true
+
+	tests := []struct {
+		name  string
+		input string
+		want  Doc
+	}{
+		{
+			name:  "stray pipe in comma document",
+			input: "notes[2]{id,text}:\n  1,a|b\n  2,c\ntags[2]: x|y,z\n",
+			want:  Doc{Notes: []Note{{1, "a|b"}, {2, "c"}}, Tags: []string{"x|y", "z"}},
+		},
+		{
+			name:  "commas in pipe document",
+			input: "notes[2]{id|text}:\n  1|Boulder, CO\n  2|plain\ntags[2]: a, b|c\n",
+			want:  Doc{Notes: []Note{{1, "Boulder, CO"}, {2, "plain"}}, Tags: []string{"a, b", "c"}},
+		},
+		{
+			name:  "nothing to split",
+			input: "notes[1]{id}:\n  1\ntags[1]: x\n",
+			want:  Doc{Notes: []Note{{ID: 1}}, Tags: []string{"x"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Doc
+			if err := toon.UnmarshalWithOptions([]byte(tt.input), &got, opts); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unmarshal = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoDetectDelimiterFailure(t *testing.T) {
+	type Row struct {
+		A int `toon:"a"`
+		B int `toon:"b"`
+	}
+	var out struct {
+		Rows []Row `toon:"rows"`
+	}
+
+	opts := toon.DefaultUnmarshalOptions()
+	opts.AutoDetectDelimiter = true
+
+	input := "rows[2]{a,b}:\n  1,2\n  3,4,5\n"
+	err := toon.UnmarshalWithOptions([]byte(input), &out, opts)
+
+	var syntaxErr *toon.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected *SyntaxError, got %v", err)
+	}
+	if syntaxErr.Line != 3 {
+		t.Errorf("Line = %d, want 3", syntaxErr.Line)
+	}
+	if !strings.Contains(syntaxErr.Message, `"," splits line 3 into 3 cells, expected 2`) {
+		t.Errorf("Message = %q", syntaxErr.Message)
+	}
+}
+
+func TestTabularHeaderDelimiters(t *testing.T) {
+	type Row struct {
+		City  string `toon:"city|state"`
+		Count int    `toon:"count"`
+	}
+	data := struct {
+		Rows []Row `toon:"rows"`
+	}{
+		Rows: []Row{{City: "Boulder, CO", Count: 3}, {City: "Denver", Count: 5}},
+	}
+
+	for _, delim := range []toon.Delimiter{toon.DelimiterComma, toon.DelimiterTab, toon.DelimiterPipe} {
+		opts := toon.DefaultMarshalOptions()
+		opts.Delimiter = delim
+
+		result, err := toon.MarshalWithOptions(data, opts)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+
+		hint := string(delim)
+		if delim == toon.DelimiterComma {
+			hint = ""
+		}
+		expectedHeader := "rows[2" + hint + "]{\"city|state\"" + string(delim) + "count}:"
+		if !strings.HasPrefix(string(result), expectedHeader) {
+			t.Errorf("Expected header %q, got:\n%s", expectedHeader, result)
+		}
+
+		var decoded struct {
+			Rows []Row `toon:"rows"`
+		}
+		if err := toon.Unmarshal(result, &decoded); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if len(decoded.Rows) != 2 || decoded.Rows[0] != data.Rows[0] || decoded.Rows[1] != data.Rows[1] {
+			t.Errorf("Round trip with %q failed: %+v", delim, decoded.Rows)
+		}
+	}
+}
+
+func TestUnmarshalDeclaredDelimiter(t *testing.T) {
+	type Row struct {
+		A string `toon:"a"`
+		B string `toon:"b"`
+	}
+	type Doc struct {
+		Tags []string `toon:"tags"`
+		Rows []Row    `toon:"rows"`
+	}
+
+	// A guess would split on whichever delimiter looks likelier; the
+	// option and a hint in the header say otherwise
+	opts := toon.DefaultUnmarshalOptions()
+	opts.Delimiter = toon.DelimiterComma
+	for _, tt := range []struct {
+		name  string
+		input string
+		want  Doc
+	}{
+		{"option", "tags[2]: a|b,c\n", Doc{Tags: []string{"a|b", "c"}}},
+		{"header hint", "tags[2|]: a,b|c\nrows[2|]{a|b}:\n  x,y|z\n  1|2,3\n", Doc{
+			Tags: []string{"a,b", "c"},
+			Rows: []Row{{A: "x,y", B: "z"}, {A: "1", B: "2,3"}},
+		}},
+	} {
+		var out Doc
+		if err := toon.UnmarshalWithOptions([]byte(tt.input), &out, opts); err != nil {
+			t.Fatalf("%s: Unmarshal failed: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(out, tt.want) {
+			t.Errorf("%s: Unmarshal = %+v, want %+v", tt.name, out, tt.want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package toon
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind says how a value differs between the two documents.
+type ChangeKind int
+
+const (
+	Modified ChangeKind = iota
+	Added
+	Removed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	}
+	return "modified"
+}
+
+// Change is a value that differs between two documents compared by Diff.
+// Values are typed as when decoding into any: strings, int64, float64,
+// bool or nil, with empty arrays and blocks as []any{} and
+// map[string]any{}. Old is unset for added values and New for removed
+// ones.
+type Change struct {
+	Kind ChangeKind
+	Path string
+	Old  any
+	New  any
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, describeValue(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, describeValue(c.Old))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", c.Path, describeValue(c.Old), describeValue(c.New))
+}
+
+// describeValue writes a value of a Change the way it would appear in a
+// document, quoting strings so they cannot be mistaken for other types.
+func describeValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		return "[]"
+	case map[string]any:
+		return "{}"
+	}
+	return fmt.Sprint(v)
+}
+
+// Diff compares two documents by the values at their paths, such as
+// hikes[0].name, rather than by their lines, so layout, quoting that does
+// not change a value's type, comments and the choice between table and
+// list form do not count as changes. Changes are listed in the order of
+// a, followed by the values only b has in the order of b.
+func Diff(a, b []byte) ([]Change, error) {
+	oldPaths, oldValues, err := documentValues(a)
+	if err != nil {
+		return nil, err
+	}
+	newPaths, newValues, err := documentValues(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, path := range oldPaths {
+		old := oldValues[path]
+		value, ok := newValues[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: Removed, Path: path, Old: old})
+		case !reflect.DeepEqual(old, value):
+			changes = append(changes, Change{Kind: Modified, Path: path, Old: old, New: value})
+		}
+	}
+	for _, path := range newPaths {
+		if _, ok := oldValues[path]; !ok {
+			changes = append(changes, Change{Kind: Added, Path: path, New: newValues[path]})
+		}
+	}
+	return changes, nil
+}
+
+// documentValues parses data and returns the path of every scalar and
+// empty collection in document order, along with their values.
+func documentValues(data []byte) ([]string, map[string]any, error) {
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w := &valueWalker{doc: doc, d: newDecoder(data, DefaultUnmarshalOptions()), values: make(map[string]any)}
+	if err := w.nodes(doc.nodes); err != nil {
+		return nil, nil, err
+	}
+	return w.paths, w.values, nil
+}
+
+type valueWalker struct {
+	doc    *Document
+	d      *decoder
+	paths  []string
+	values map[string]any
+}
+
+func (w *valueWalker) add(path string, value any) {
+	if _, ok := w.values[path]; !ok {
+		w.paths = append(w.paths, path)
+	}
+	w.values[path] = value
+}
+
+// scalar records the raw text of a value typed as decoding into any would.
+func (w *valueWalker) scalar(path, raw string) error {
+	var value any
+	if err := w.d.setPrimitiveValue(reflect.ValueOf(&value).Elem(), strings.TrimSpace(raw)); err != nil {
+		return err
+	}
+	w.add(path, value)
+	return nil
+}
+
+func (w *valueWalker) nodes(nodes []*node) error {
+	for _, n := range nodes {
+		if err := w.node(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *valueWalker) node(n *node) error {
+	switch n.kind {
+	case scalarNode:
+		return w.scalar(n.path, w.doc.value(n))
+	case blockNode:
+		if len(n.children) == 0 {
+			w.add(n.path, map[string]any{})
+		}
+		return w.nodes(n.children)
+	case inlineNode:
+		for i, cell := range w.doc.cells(n) {
+			if err := w.scalar(fmt.Sprintf("%s[%d]", n.path, i), cell); err != nil {
+				return err
+			}
+		}
+		return nil
+	case tableNode:
+		if len(n.children) == 0 {
+			w.add(n.path, []any{})
+		}
+		for _, row := range n.children {
+			if err := w.row(n, row); err != nil {
+				return err
+			}
+		}
+		return nil
+	case listNode:
+		if len(n.children) == 0 {
+			w.add(n.path, []any{})
+		}
+		for _, item := range n.children {
+			if len(item.children) == 0 {
+				if err := w.scalar(item.path, w.doc.value(item)); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := w.nodes(item.children); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *valueWalker) row(table, row *node) error {
+	cells := w.doc.cells(row)
+	if table.fields[0] == sparseField {
+		for _, cell := range cells {
+			if name, value, ok := cutSparsePair(strings.TrimSpace(cell)); ok {
+				if err := w.scalar(joinPath(row.path, name), value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for i, field := range table.fields {
+		if i < len(cells) {
+			if err := w.scalar(joinPath(row.path, field), cells[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package toon_test
+
+import (
+	"reflect"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestDiff(t *testing.T) {
+	a := "context:\n  task: hiking\nfriends[3]: ana,luis,sam\nhikes[2]{id,name}:\n  1,Blue Lake\n  2,Ridge\n" +
+		"items[2]:\n  - id: 1\n    code: \"5\"\n  - 7\nempty[0]:\n"
+	b := "# reformatted\ncontext:\n    task: \"hiking\"\nfriends[2]: ana,tom\nhikes[2]:\n  - id: 1\n    name: Blue Lake\n" +
+		"  - id: 2\n    name: Green Valley\nitems[2]:\n  - id: 1\n    code: 5\n  - 7\nextra: null\n"
+
+	changes, err := toon.Diff([]byte(a), []byte(b))
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	want := []toon.Change{
+		{Kind: toon.Modified, Path: "friends[1]", Old: "luis", New: "tom"},
+		{Kind: toon.Removed, Path: "friends[2]", Old: "sam"},
+		{Kind: toon.Modified, Path: "hikes[1].name", Old: "Ridge", New: "Green Valley"},
+		{Kind: toon.Modified, Path: "items[0].code", Old: "5", New: int64(5)},
+		{Kind: toon.Removed, Path: "empty", Old: []any{}},
+		{Kind: toon.Added, Path: "extra"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Diff = %v, want %v", changes, want)
+	}
+
+	if got := changes[3].String(); got != `~ items[0].code: "5" -> 5` {
+		t.Errorf("String = %q", got)
+	}
+
+	changes, err = toon.Diff([]byte(a), []byte(a))
+	if err != nil || len(changes) != 0 {
+		t.Errorf("Diff of identical documents = %v, %v", changes, err)
+	}
+}
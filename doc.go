@@ -0,0 +1,19 @@
+// Package toon encodes and decodes TOON (Token-Oriented Object Notation),
+// a compact, line-based format for passing structured data to language
+// models.
+//
+// # Concurrency
+//
+// Marshal, Unmarshal, their WithOptions variants, Extract, Format, Diff,
+// Valid and Validate keep no state between calls and may be called from
+// any number of goroutines. Options passed to concurrent calls may be
+// shared as long as their callbacks are safe for concurrent use and they
+// do not collect Warnings, which are appended without locking.
+// RegisterTypeOptions may be called at any time; encodings already
+// running may or may not see the change.
+//
+// A Codec is safe for concurrent use and reuses encoding buffers between
+// calls, which suits servers. A Document is not: it must not be read
+// while it is being edited, and edits must not run concurrently. Neither
+// is a Decoder, which reads from its input on every Decode call.
+package toon
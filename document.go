@@ -0,0 +1,697 @@
+package toon
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrPathNotFound is returned by Document methods when a path does not
+// lead to an existing node.
+var ErrPathNotFound = errors.New("toon: path not found")
+
+type nodeKind int
+
+const (
+	scalarNode nodeKind = iota // key: value
+	blockNode                  // key: followed by nested keys
+	inlineNode                 // key[N]: a,b,c
+	tableNode                  // key[N]{f1,f2}: followed by rows
+	listNode                   // key[N]: followed by "- " items
+	rowNode                    // one row of a table
+	itemNode                   // one "- " item of a list
+)
+
+// node is an entry of a Document. Its lines are lines[line:end]; blank and
+// comment lines after the last content line are not part of it.
+type node struct {
+	kind   nodeKind
+	key    string
+	path   string
+	line   int
+	end    int
+	indent int
+
+	// prefix is the text of the first line before the value, for scalar
+	// nodes, inline arrays and scalar list items
+	prefix string
+
+	fields   []string
+	children []*node
+}
+
+// Document is a parsed TOON document that can be edited in place. Bytes
+// reproduces every line an edit did not touch byte for byte, so scripts can
+// patch files with minimal diffs.
+type Document struct {
+	lines []string
+	nodes []*node
+	unit  int
+}
+
+var arrayCountPattern = regexp.MustCompile(`\[(\d+)`)
+
+// ParseDocument parses data into a Document.
+func ParseDocument(data []byte) (*Document, error) {
+	doc := &Document{lines: strings.Split(string(data), "\n")}
+	if err := doc.parse(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Bytes returns the document as TOON text.
+func (doc *Document) Bytes() []byte {
+	return []byte(strings.Join(doc.lines, "\n"))
+}
+
+// Get returns the value at path, such as context.season, friends[1] or
+// hikes[0].name, with quotes and escapes resolved. It reports false when
+// the path does not lead to a value.
+func (doc *Document) Get(path string) (string, bool) {
+	n, cell, err := doc.resolve(path)
+	if err != nil {
+		return "", false
+	}
+
+	switch {
+	case cell >= 0:
+		return unquote(strings.TrimSpace(doc.cells(n)[cell])), true
+	case n.kind == scalarNode || n.kind == itemNode && n.prefix != "":
+		return unquote(strings.TrimSpace(doc.value(n))), true
+	}
+	return "", false
+}
+
+// value returns the text of line n after its prefix, without any comment
+// following it.
+func (doc *Document) value(n *node) string {
+	line := stripComment(doc.lines[n.line])
+	if len(line) < len(n.prefix) {
+		return ""
+	}
+	return line[len(n.prefix):]
+}
+
+// Set replaces the value at path with v, or adds it when path does not
+// exist yet, creating missing parent blocks. Scalars replace the value in
+// place; structs, maps and slices replace the whole entry with their
+// encoded form.
+func (doc *Document) Set(path string, v any) error {
+	n, cell, err := doc.resolve(path)
+	if errors.Is(err, ErrPathNotFound) {
+		return doc.insert(path, v)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case cell >= 0:
+		if !isScalarValue(v) {
+			return fmt.Errorf("toon: %s holds a single cell, cannot set it to %T", path, v)
+		}
+		text, err := formatScalar(v, doc.delimiter(n))
+		if err != nil {
+			return err
+		}
+		cells := doc.cells(n)
+		cells[cell] = text
+		doc.setCells(n, cells)
+	case isScalarValue(v) && n.prefix != "":
+		text, err := formatScalar(v, DelimiterComma)
+		if err != nil {
+			return err
+		}
+		doc.lines[n.line] = n.prefix + text
+	case n.kind == rowNode || n.kind == itemNode || n.line == doc.itemLine(n):
+		return fmt.Errorf("toon: cannot replace %s with %T in place", path, v)
+	default:
+		lines, err := doc.encode(n.key, v, leadingSpace(doc.lines[n.line]))
+		if err != nil {
+			return err
+		}
+		doc.splice(n.line, n.end, lines)
+	}
+	return doc.parse()
+}
+
+// Delete removes the entry at path. Deleting a table row, list item or
+// inline array element also updates the array's declared length.
+func (doc *Document) Delete(path string) error {
+	n, cell, err := doc.resolve(path)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case cell >= 0 && n.kind == inlineNode:
+		cells := doc.cells(n)
+		doc.setCells(n, append(cells[:cell], cells[cell+1:]...))
+		doc.setCount(n, len(cells)-1)
+	case cell >= 0:
+		return fmt.Errorf("toon: cannot delete a single cell of a table row at %s", path)
+	case n.kind != itemNode && n.line == doc.itemLine(n):
+		return fmt.Errorf("toon: cannot delete the first field of a list item at %s", path)
+	default:
+		doc.splice(n.line, n.end, nil)
+		if parent := doc.parent(n); parent != nil && (n.kind == rowNode || n.kind == itemNode) {
+			doc.setCount(parent, len(parent.children)-1)
+		}
+	}
+	return doc.parse()
+}
+
+// AppendRow adds row to the end of the table at path. row may be a struct,
+// whose fields are matched to columns by name, a map keyed by column name,
+// or a slice holding the cells in column order.
+func (doc *Document) AppendRow(path string, row any) error {
+	n, cell, err := doc.resolve(path)
+	if err != nil {
+		return err
+	}
+	if n.kind != tableNode || cell >= 0 {
+		return fmt.Errorf("toon: %s is not a table", path)
+	}
+
+	values, err := rowValues(reflect.ValueOf(row), n.fields)
+	if err != nil {
+		return err
+	}
+
+	delim := doc.delimiter(n)
+	cells := make([]string, len(values))
+	for i, value := range values {
+		if !value.IsValid() {
+			continue
+		}
+		if cells[i], err = formatScalar(value.Interface(), delim); err != nil {
+			return err
+		}
+	}
+
+	indent := strings.Repeat(" ", doc.indentOf(n.line)+doc.unit)
+	if len(n.children) > 0 {
+		indent = leadingSpace(doc.lines[n.children[0].line])
+	}
+	doc.splice(n.end, n.end, []string{indent + strings.Join(cells, string(delim))})
+	doc.setCount(n, len(n.children)+1)
+	return doc.parse()
+}
+
+// insert adds v at a path that does not exist yet, below its deepest
+// existing ancestor block.
+func (doc *Document) insert(path string, v any) error {
+	segments := splitPath(path)
+
+	var parent *node
+	depth := 0
+	for ; depth < len(segments)-1; depth++ {
+		n, _, err := doc.resolve(joinSegments(segments[:depth+1]))
+		if err != nil {
+			break
+		}
+		parent = n
+	}
+	for _, segment := range segments[depth:] {
+		if strings.HasPrefix(segment, "[") {
+			return fmt.Errorf("%w: %s", ErrPathNotFound, path)
+		}
+	}
+
+	at := doc.contentEnd()
+	indent := ""
+	if parent != nil {
+		if parent.kind != blockNode {
+			return fmt.Errorf("toon: cannot add %s below a %s", path, describeKind(parent.kind))
+		}
+		at = parent.end
+		indent = strings.Repeat(" ", parent.indent+doc.unit)
+	}
+
+	var lines []string
+	for _, segment := range segments[depth : len(segments)-1] {
+		lines = append(lines, indent+segment+":")
+		indent += strings.Repeat(" ", doc.unit)
+	}
+	encoded, err := doc.encode(segments[len(segments)-1], v, indent)
+	if err != nil {
+		return err
+	}
+	doc.splice(at, at, append(lines, encoded...))
+	return doc.parse()
+}
+
+// encode renders v under key with every line prefixed by indent.
+func (doc *Document) encode(key string, v any, indent string) ([]string, error) {
+	opts := DefaultMarshalOptions()
+	opts.Indent = doc.unit
+	e := newEncoder(opts)
+	if err := e.encodeValue(reflect.ValueOf(&v).Elem(), 0, key); err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(e.buf.String(), "\n"), "\n")
+	for i := range lines {
+		lines[i] = indent + lines[i]
+	}
+	return lines, nil
+}
+
+func (doc *Document) splice(start, end int, lines []string) {
+	doc.lines = append(doc.lines[:start], append(lines, doc.lines[end:]...)...)
+}
+
+// contentEnd is the index after the last non-blank line.
+func (doc *Document) contentEnd() int {
+	end := len(doc.lines)
+	for end > 0 && strings.TrimSpace(doc.lines[end-1]) == "" {
+		end--
+	}
+	return end
+}
+
+func (doc *Document) indentOf(line int) int {
+	return newDecoder(nil, DefaultUnmarshalOptions()).getIndent(doc.lines[line])
+}
+
+// itemLine returns the line of the list item n is the first field of, or
+// -1.
+func (doc *Document) itemLine(n *node) int {
+	if parent := doc.parent(n); parent != nil && parent.kind == itemNode {
+		return parent.line
+	}
+	return -1
+}
+
+// cells returns the raw cells of an inline array or table row.
+func (doc *Document) cells(n *node) []string {
+	text := strings.TrimLeft(stripComment(doc.lines[n.line]), " \t")
+	if n.kind == inlineNode {
+		text = doc.value(n)
+	}
+	return splitQuoted(text, string(doc.delimiter(n)))
+}
+
+func (doc *Document) setCells(n *node, cells []string) {
+	prefix := n.prefix
+	if n.kind != inlineNode {
+		prefix = leadingSpace(doc.lines[n.line])
+	}
+	doc.lines[n.line] = prefix + strings.Join(cells, string(doc.delimiter(n)))
+}
+
+// setCount rewrites the declared length in the header of array n.
+func (doc *Document) setCount(n *node, count int) {
+	line := doc.lines[n.line]
+	loc := arrayCountPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return
+	}
+	doc.lines[n.line] = line[:loc[2]] + strconv.Itoa(count) + line[loc[3]:]
+}
+
+// delimiter returns the delimiter of the array that n is or belongs to:
+// the one declared in its header, else the one guessed from its first row.
+func (doc *Document) delimiter(n *node) Delimiter {
+	if n.kind == rowNode {
+		n = doc.parent(n)
+	}
+
+	header := doc.lines[n.line]
+	if m := regexp.MustCompile(`\[(?:\d+|\?)?([,\t|;])\]`).FindStringSubmatch(header); m != nil {
+		return Delimiter(m[1])
+	}
+	if n.kind == inlineNode {
+		return guessDelimiter(header[len(n.prefix):])
+	}
+	if len(n.children) > 0 {
+		return guessDelimiter(strings.TrimSpace(doc.lines[n.children[0].line]))
+	}
+	return DelimiterComma
+}
+
+func (doc *Document) parent(n *node) *node {
+	var find func(nodes []*node, parent *node) *node
+	find = func(nodes []*node, parent *node) *node {
+		for _, c := range nodes {
+			if c == n {
+				return parent
+			}
+			if p := find(c.children, c); p != nil {
+				return p
+			}
+		}
+		return nil
+	}
+	return find(doc.nodes, nil)
+}
+
+// resolve finds the node at path. For an element of an inline array or a
+// cell of a table row it returns the array or row and the cell index;
+// otherwise the index is -1.
+func (doc *Document) resolve(path string) (*node, int, error) {
+	segments := splitPath(path)
+	if len(segments) == 0 {
+		return nil, -1, fmt.Errorf("%w: %q", ErrPathNotFound, path)
+	}
+
+	nodes := doc.nodes
+	var current *node
+	for i, segment := range segments {
+		if current != nil && current.kind == rowNode {
+			for j, field := range current.fields {
+				if field == segment && i == len(segments)-1 {
+					return current, j, nil
+				}
+			}
+			return nil, -1, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+		}
+
+		var next *node
+		if index, ok := parseIndex(segment); ok {
+			if current != nil && current.kind == inlineNode && i == len(segments)-1 {
+				if index < len(doc.cells(current)) {
+					return current, index, nil
+				}
+			} else if current != nil && index < len(current.children) &&
+				(current.kind == tableNode || current.kind == listNode) {
+				next = current.children[index]
+			}
+		} else {
+			for _, c := range nodes {
+				if c.key == segment {
+					next = c
+				}
+			}
+		}
+		if next == nil {
+			return nil, -1, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+		}
+		current = next
+		nodes = current.children
+	}
+	return current, -1, nil
+}
+
+func (doc *Document) parse() error {
+	p := &docParser{doc: doc, d: newDecoder(doc.Bytes(), DefaultUnmarshalOptions())}
+	doc.unit = 0
+	nodes, err := p.entries("", -1)
+	if err != nil {
+		return err
+	}
+	doc.nodes = nodes
+	if doc.unit == 0 {
+		doc.unit = 2
+	}
+	return nil
+}
+
+type docParser struct {
+	doc *Document
+	d   *decoder
+	i   int
+}
+
+// skip moves past blank and comment lines.
+func (p *docParser) skip() {
+	for p.i < len(p.doc.lines) {
+		trimmed := strings.TrimSpace(p.doc.lines[p.i])
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return
+		}
+		p.i++
+	}
+}
+
+// next returns the indentation of the next content line, or -1 at the end.
+func (p *docParser) next() int {
+	p.skip()
+	if p.i >= len(p.doc.lines) {
+		return -1
+	}
+	indent := p.d.getIndent(p.doc.lines[p.i])
+	if indent > 0 && (p.doc.unit == 0 || indent < p.doc.unit) {
+		p.doc.unit = indent
+	}
+	return indent
+}
+
+// entries parses the keys indented deeper than parentIndent.
+func (p *docParser) entries(parentPath string, parentIndent int) ([]*node, error) {
+	var nodes []*node
+	for {
+		indent := p.next()
+		if indent < 0 || indent <= parentIndent {
+			return nodes, nil
+		}
+		line := p.doc.lines[p.i]
+		text := strings.TrimSpace(line)
+		n, err := p.entry(parentPath, p.i, indent, leadOf(line, text), text)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+}
+
+// entry parses the key at line. text is the line without its indentation
+// or list dash, which make up lead.
+func (p *docParser) entry(parentPath string, line, indent int, lead, text string) (*node, error) {
+	rawKey, value, ok := cutKeyValue(text)
+	if !ok {
+		return nil, p.d.syntaxError(line+1, "expected key: value")
+	}
+	key := strings.TrimSpace(rawKey)
+	value = strings.TrimSpace(value)
+	p.i = line + 1
+
+	n := &node{key: key, line: line, indent: indent}
+	length, fields, _ := p.d.parseArrayDeclaration(key)
+	if length != notArray {
+		n.key = p.d.extractKeyFromArray(key)
+	}
+	n.path = joinPath(parentPath, n.key)
+
+	switch {
+	case length != notArray && len(fields) > 0:
+		n.kind = tableNode
+		n.fields = fields
+		for {
+			rowIndent := p.next()
+			if rowIndent < 0 || rowIndent <= indent {
+				break
+			}
+			n.children = append(n.children, &node{
+				kind:   rowNode,
+				path:   fmt.Sprintf("%s[%d]", n.path, len(n.children)),
+				line:   p.i,
+				end:    p.i + 1,
+				indent: rowIndent,
+				fields: fields,
+			})
+			p.i++
+		}
+	case length != notArray && value != "":
+		n.kind = inlineNode
+		n.prefix = lead + text[:len(text)-len(strings.TrimLeft(text[len(rawKey)+1:], " "))]
+	case length != notArray:
+		n.kind = listNode
+		if err := p.items(n); err != nil {
+			return nil, err
+		}
+	case value == "":
+		n.kind = blockNode
+		children, err := p.entries(n.path, indent)
+		if err != nil {
+			return nil, err
+		}
+		n.children = children
+	default:
+		n.kind = scalarNode
+		n.prefix = lead + text[:len(text)-len(strings.TrimLeft(text[len(rawKey)+1:], " "))]
+	}
+
+	n.end = p.contentEnd(line)
+	return n, nil
+}
+
+// items parses the "- " items of list n.
+func (p *docParser) items(n *node) error {
+	for {
+		indent := p.next()
+		if indent < 0 || indent <= n.indent {
+			return nil
+		}
+		line := p.doc.lines[p.i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "-" && !strings.HasPrefix(trimmed, "- ") {
+			return p.d.syntaxError(p.i+1, "expected list item")
+		}
+
+		item := &node{
+			kind:   itemNode,
+			path:   fmt.Sprintf("%s[%d]", n.path, len(n.children)),
+			line:   p.i,
+			indent: indent,
+		}
+		content := strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
+		lead := leadOf(line, content)
+		if _, _, ok := cutKeyValue(content); !ok || isQuoted(content) {
+			item.prefix = lead
+			p.i++
+		} else {
+			// The first field shares the dash line and is indented like
+			// the fields below it
+			first, err := p.entry(item.path, p.i, indent+2, lead, content)
+			if err != nil {
+				return err
+			}
+			rest, err := p.entries(item.path, indent)
+			if err != nil {
+				return err
+			}
+			item.children = append([]*node{first}, rest...)
+		}
+		item.end = p.contentEnd(item.line)
+		n.children = append(n.children, item)
+	}
+}
+
+// contentEnd is the index after the last content line parsed so far,
+// ignoring trailing blank and comment lines.
+func (p *docParser) contentEnd(start int) int {
+	end := p.i
+	for end > start+1 {
+		trimmed := strings.TrimSpace(p.doc.lines[end-1])
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+		end--
+	}
+	return end
+}
+
+func leadingSpace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// leadOf returns the part of line before text, which ends it up to
+// trailing whitespace.
+func leadOf(line, text string) string {
+	if text == "" {
+		return strings.TrimRight(line, " \t") + " "
+	}
+	return line[:strings.Index(line, text)]
+}
+
+// rowValues returns the values of row in the order of columns.
+func rowValues(row reflect.Value, columns []string) ([]reflect.Value, error) {
+	row = derefValue(row)
+	values := make([]reflect.Value, len(columns))
+
+	switch row.Kind() {
+	case reflect.Struct:
+		fields := fieldsByColumn(row.Type())
+		for i, column := range columns {
+			if f, ok := fields[column]; ok {
+				if v, ok := fieldByIndex(row, f.index); ok {
+					values[i] = v
+				}
+			}
+		}
+	case reflect.Map:
+		if row.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("toon: row maps must have string keys, got %s", row.Type())
+		}
+		for i, column := range columns {
+			if v := row.MapIndex(reflect.ValueOf(column).Convert(row.Type().Key())); v.IsValid() {
+				values[i] = v
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if row.Len() > len(columns) {
+			return nil, fmt.Errorf("toon: row has %d cells, table has %d columns", row.Len(), len(columns))
+		}
+		for i := 0; i < row.Len(); i++ {
+			values[i] = row.Index(i)
+		}
+	default:
+		return nil, fmt.Errorf("toon: cannot use %s as a table row", row.Kind())
+	}
+	return values, nil
+}
+
+// formatScalar renders v as a single TOON value for the given delimiter.
+func formatScalar(v any, delim Delimiter) (string, error) {
+	opts := DefaultMarshalOptions()
+	opts.Delimiter = delim
+	e := newEncoder(opts)
+	if err := e.writePrimitiveValue(reflect.ValueOf(&v).Elem()); err != nil {
+		return "", err
+	}
+	return e.buf.String(), nil
+}
+
+// isScalarValue reports whether v is written as a single value.
+func isScalarValue(v any) bool {
+	rv := derefValue(reflect.ValueOf(v))
+	if !rv.IsValid() || isScalarType(rv.Type()) || isByteSlice(rv.Type()) {
+		return true
+	}
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return false
+	}
+	return true
+}
+
+// splitPath splits a path like hikes[2].name into hikes, [2] and name.
+func splitPath(path string) []string {
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		for part != "" {
+			i := strings.IndexByte(part[1:], '[') + 1
+			if i == 0 {
+				i = len(part)
+			}
+			segments = append(segments, part[:i])
+			part = part[i:]
+		}
+	}
+	return segments
+}
+
+func joinSegments(segments []string) string {
+	path := ""
+	for _, segment := range segments {
+		path = joinPath(path, segment)
+	}
+	return path
+}
+
+func parseIndex(segment string) (int, bool) {
+	if !strings.HasPrefix(segment, "[") || !strings.HasSuffix(segment, "]") {
+		return 0, false
+	}
+	index, err := strconv.Atoi(segment[1 : len(segment)-1])
+	return index, err == nil && index >= 0
+}
+
+func describeKind(kind nodeKind) string {
+	switch kind {
+	case scalarNode:
+		return "value"
+	case inlineNode, tableNode, listNode:
+		return "array"
+	case rowNode:
+		return "table row"
+	case itemNode:
+		return "list item"
+	}
+	return "block"
+}
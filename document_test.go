@@ -0,0 +1,160 @@
+package toon_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+const documentInput = `# trip notes
+context:
+  task: Our favorite hikes
+  season: spring_2025
+
+friends[3]: ana,luis,sam
+hikes[2]{id,name,distanceKm}:
+  1,Blue Lake Trail,7.5
+  2,"Ridge Overlook, North",9.2
+stops[2]:
+  - name: Trailhead
+    kind: parking
+  - name: Summit
+`
+
+func parseDocument(t *testing.T) *toon.Document {
+	t.Helper()
+	doc, err := toon.ParseDocument([]byte(documentInput))
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	return doc
+}
+
+func TestDocumentRoundTrip(t *testing.T) {
+	doc := parseDocument(t)
+	if got := string(doc.Bytes()); got != documentInput {
+		t.Errorf("Bytes changed an unedited document:\n%s", got)
+	}
+
+	for path, want := range map[string]string{
+		"context.season": "spring_2025",
+		"friends[1]":     "luis",
+		"hikes[1].name":  "Ridge Overlook, North",
+		"stops[0].kind":  "parking",
+	} {
+		if got, ok := doc.Get(path); !ok || got != want {
+			t.Errorf("Get(%q) = %q, %v; want %q", path, got, ok, want)
+		}
+	}
+	if _, ok := doc.Get("context"); ok {
+		t.Error("Get on a block should report false")
+	}
+}
+
+func TestDocumentSet(t *testing.T) {
+	doc := parseDocument(t)
+
+	edits := map[string]any{
+		"context.season":   "summer_2025",
+		"friends[2]":       "sam, jr",
+		"hikes[0].name":    "Blue Lake",
+		"stops[1].name":    "Peak",
+		"context.leader":   "ana",
+		"meta.source.file": "trips.toon",
+	}
+	for path, value := range edits {
+		if err := doc.Set(path, value); err != nil {
+			t.Fatalf("Set(%q) failed: %v", path, err)
+		}
+	}
+
+	want := `# trip notes
+context:
+  task: Our favorite hikes
+  season: summer_2025
+  leader: ana
+
+friends[3]: ana,luis,"sam, jr"
+hikes[2]{id,name,distanceKm}:
+  1,Blue Lake,7.5
+  2,"Ridge Overlook, North",9.2
+stops[2]:
+  - name: Trailhead
+    kind: parking
+  - name: Peak
+meta:
+  source:
+    file: trips.toon
+`
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("Bytes after Set:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := doc.Set("friends", []string{"ana", "ben"}); err != nil {
+		t.Fatalf("Set of an array failed: %v", err)
+	}
+	if !strings.Contains(string(doc.Bytes()), "\nfriends[2]: ana,ben\n") {
+		t.Errorf("array not replaced:\n%s", doc.Bytes())
+	}
+}
+
+func TestDocumentDelete(t *testing.T) {
+	doc := parseDocument(t)
+
+	for _, path := range []string{"context.task", "friends[0]", "hikes[0]", "stops[0]"} {
+		if err := doc.Delete(path); err != nil {
+			t.Fatalf("Delete(%q) failed: %v", path, err)
+		}
+	}
+
+	want := `# trip notes
+context:
+  season: spring_2025
+
+friends[2]: luis,sam
+hikes[1]{id,name,distanceKm}:
+  2,"Ridge Overlook, North",9.2
+stops[1]:
+  - name: Summit
+`
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("Bytes after Delete:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := doc.Delete("nothing.here"); !errors.Is(err, toon.ErrPathNotFound) {
+		t.Errorf("expected ErrPathNotFound, got %v", err)
+	}
+}
+
+func TestDocumentAppendRow(t *testing.T) {
+	doc := parseDocument(t)
+
+	type row struct {
+		ID         int     `toon:"id"`
+		Name       string  `toon:"name"`
+		DistanceKm float64 `toon:"distanceKm"`
+	}
+	if err := doc.AppendRow("hikes", row{ID: 3, Name: "Wildflower Loop", DistanceKm: 5.1}); err != nil {
+		t.Fatalf("AppendRow failed: %v", err)
+	}
+	if err := doc.AppendRow("hikes", map[string]any{"id": 4, "name": "Creek, East"}); err != nil {
+		t.Fatalf("AppendRow failed: %v", err)
+	}
+
+	var data HikesData
+	if err := toon.Unmarshal(doc.Bytes(), &data); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(data.Hikes) != 4 || data.Hikes[2].Name != "Wildflower Loop" || data.Hikes[3].Name != "Creek, East" {
+		t.Errorf("hikes = %+v", data.Hikes)
+	}
+	if !strings.Contains(string(doc.Bytes()), "hikes[4]{id,name,distanceKm}:\n") {
+		t.Errorf("header count not updated:\n%s", doc.Bytes())
+	}
+
+	if err := doc.AppendRow("friends", []string{"zoe"}); err == nil {
+		t.Error("expected error appending a row to an inline array")
+	}
+}
@@ -0,0 +1,45 @@
+package toon
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+)
+
+// dumpMaxDepth bounds the nesting Sdump follows, so cyclic values end in
+// an error rather than a stack overflow.
+const dumpMaxDepth = 1000
+
+// Sdump returns v written as TOON with the default options in lenient
+// mode, for quick inspection in REPLs, notebooks and debug logs: fields
+// and values that cannot be written, such as channels and functions, are
+// left out. It never panics: when v cannot be written at all, because it
+// holds a cycle or a marshaler that fails or panics, the result is a
+// comment line stating why.
+func Sdump(v any) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = dumpFailure(fmt.Errorf("toon: panic writing %T: %v", v, r))
+		}
+	}()
+
+	opts := DefaultMarshalOptions()
+	opts.Lenient = true
+	e := newEncoder(opts)
+	e.maxDepth = dumpMaxDepth
+	data, err := e.encodeDocument(reflect.ValueOf(v), "")
+	if err != nil {
+		return dumpFailure(err)
+	}
+	return string(data)
+}
+
+// Print writes v to standard output as Sdump does.
+func Print(v any) {
+	os.Stdout.WriteString(Sdump(v))
+}
+
+// dumpFailure is the comment Sdump returns in place of a document.
+func dumpFailure(err error) string {
+	return "# " + err.Error() + "\n"
+}
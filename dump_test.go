@@ -0,0 +1,81 @@
+package toon_test
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+type panickyMarshaler struct{}
+
+func (panickyMarshaler) MarshalTOON() ([]byte, error) { panic("no way") }
+
+func TestSdump(t *testing.T) {
+	type Hike struct {
+		Name string  `toon:"name"`
+		Km   float64 `toon:"km"`
+	}
+	got := toon.Sdump(map[string]any{"owner": "ana", "hikes": []Hike{{"Blue Lake", 7.5}, {"Saddle", 12}}})
+	want := `hikes[2]{name,km}:
+  Blue Lake,7.5
+  Saddle,12
+owner: ana
+`
+	if got != want {
+		t.Errorf("Sdump = %q, want %q", got, want)
+	}
+	if got := toon.Sdump(nil); got != "" {
+		t.Errorf("Sdump(nil) = %q, want empty", got)
+	}
+}
+
+func TestSdumpFailures(t *testing.T) {
+	type Node struct {
+		Name string
+		Next *Node
+	}
+	node := &Node{Name: "loop"}
+	node.Next = node
+	cyclic := map[string]any{}
+	cyclic["self"] = cyclic
+
+	for _, tt := range []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"pointer cycle", node, "# toon: value nested more than 1000 levels deep\n"},
+		{"map cycle", cyclic, "# toon: value nested more than 1000 levels deep\n"},
+		{"channel", struct {
+			Name    string
+			Updates chan int
+		}{"ana", nil}, "name: ana\n"},
+		{"panicking marshaler", panickyMarshaler{}, "# toon: panic writing toon_test.panickyMarshaler: no way\n"},
+	} {
+		if got := toon.Sdump(tt.v); got != tt.want {
+			t.Errorf("%s: Sdump = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	toon.Print(struct{ Tags []string }{[]string{"lake", "ridge"}})
+	os.Stdout = stdout
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "tags[2]: lake,ridge\n"; string(out) != want {
+		t.Errorf("Print wrote %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,287 @@
+package toon
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// decodeDynamic decodes the value at the current line without a Go type
+// to guide it, as decoding into any does: blocks become map[string]any,
+// or OrderedMaps when d.ordered is set, arrays []any and scalars the
+// values setValue gives them. A block holding a single keyless array or
+// scalar decodes to that value, and an empty one to an empty map.
+func (d *decoder) decodeDynamic(expectedIndent int) (any, error) {
+	d.skipEmptyLines()
+	if !d.hasMore() || expectedIndent > 0 && d.getIndent(d.currentLine()) < expectedIndent {
+		return d.object(OrderedMap{}), nil
+	}
+
+	line := d.currentLine()
+	if header, ok := d.keylessArray(line); ok {
+		d.advance()
+		return d.decodeDynamicArray(header.length, header.fields, header.delim, header.value, d.getIndent(line))
+	}
+	trimmed := strings.TrimSpace(line)
+	if _, _, ok := cutKeyValue(trimmed); ok && !isQuoted(trimmed) {
+		return d.decodeObject(expectedIndent)
+	}
+
+	d.advance()
+	var v any
+	err := d.setValue(reflect.ValueOf(&v).Elem(), trimmed)
+	return v, err
+}
+
+// keylessHeader is the header of an array written without a key, as root
+// arrays and arrays nested in lists are.
+type keylessHeader struct {
+	length int
+	fields []string
+	delim  Delimiter
+	value  string
+}
+
+// keylessArray reports whether the content of line is the header of an
+// array without a key, such as "[2]: a,b" or "[3]{id,name}:".
+func (d *decoder) keylessArray(line string) (keylessHeader, bool) {
+	key, value, ok := cutKeyValue(strings.TrimSpace(line))
+	if !ok || !strings.HasPrefix(key, "[") {
+		return keylessHeader{}, false
+	}
+	length, fields, delim := d.parseArrayDeclaration(strings.TrimSpace(key))
+	if length == notArray {
+		return keylessHeader{}, false
+	}
+	return keylessHeader{length, fields, delim, strings.TrimSpace(value)}, true
+}
+
+// decodeObject decodes the block at expectedIndent into a map[string]any,
+// or an OrderedMap when d.ordered is set.
+func (d *decoder) decodeObject(expectedIndent int) (any, error) {
+	if d.ordered {
+		var m OrderedMap
+		err := d.decodeOrderedMap(reflect.ValueOf(&m).Elem(), expectedIndent)
+		return m, err
+	}
+	m := make(map[string]any)
+	err := d.decodeMap(reflect.ValueOf(&m).Elem(), expectedIndent)
+	return m, err
+}
+
+// object returns m as decoding without a Go type delivers blocks.
+func (d *decoder) object(m OrderedMap) any {
+	if d.ordered {
+		return m
+	}
+	out := make(map[string]any, len(m))
+	for _, kv := range m {
+		out[kv.Key] = kv.Value
+	}
+	return out
+}
+
+// decodeDynamicArray decodes the array whose header, indented by indent,
+// was just consumed: inline values, table rows as objects, or list items.
+// A table keyed by a _key column decodes to an object of its rows, as the
+// map it was written from.
+func (d *decoder) decodeDynamicArray(length int, fields []string, delim Delimiter, value string, indent int) (any, error) {
+	// The header line has already been consumed, so d.pos is its 1-based number
+	line := d.pos
+	defer d.declareDelimiter(delim)()
+
+	var (
+		v   any
+		n   int
+		err error
+	)
+	switch {
+	case len(fields) > 0:
+		v, n, err = d.decodeDynamicTable(fields, indent)
+	case value != "":
+		var a []any
+		a, err = d.decodeDynamicInline(length, value)
+		v, n = a, len(a)
+	default:
+		var a []any
+		a, err = d.decodeDynamicList(indent)
+		v, n = a, len(a)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if length != openLength && n != length {
+		d.tolerate(line, fmt.Sprintf("array declares %d items, found %d", length, n), "")
+	}
+	return v, nil
+}
+
+func (d *decoder) decodeDynamicInline(length int, value string) ([]any, error) {
+	line := d.pos
+	parts := d.trimTrailingDelimiter(d.splitValues(value), length, line)
+
+	a := make([]any, len(parts))
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			d.tolerate(line, "blank value in inline array", "left as null")
+			continue
+		}
+		d.pushIndex(i)
+		if err := d.setValue(reflect.ValueOf(&a[i]).Elem(), part); err != nil {
+			return nil, err
+		}
+		d.popPath()
+	}
+	return a, nil
+}
+
+// decodeDynamicTable decodes the rows of a table below a header indented
+// by indent, along with the columns folded out of it, and returns them
+// with their count.
+func (d *decoder) decodeDynamicTable(fields []string, indent int) (any, int, error) {
+	folded := d.folded
+	d.folded = nil
+	sparse := len(fields) == 1 && fields[0] == sparseField
+	keyed := !sparse && fields[0] == tabularKeyField
+
+	rows := []any{}
+	var byKey OrderedMap
+	for d.hasMore() {
+		d.skipEmptyLines()
+		if !d.hasMore() || d.getIndent(d.currentLine()) <= indent {
+			break
+		}
+
+		rowData := strings.TrimSpace(d.currentLine())
+		d.advance()
+		lineNum := d.pos
+
+		names := fields
+		var values []string
+		if sparse {
+			names, values = d.splitSparseRow(rowData, lineNum)
+		} else {
+			values = d.trimTrailingDelimiter(d.splitValues(rowData), len(fields), lineNum)
+			if extra := len(values) - len(fields); extra > 0 {
+				d.warn(lineNum, "%d extra cells ignored", extra)
+			}
+		}
+
+		if !keyed {
+			d.pushIndex(len(rows))
+		}
+
+		// Cells missing from short rows are null
+		row := OrderedMap{}
+		var key string
+		for j, name := range names {
+			value := ""
+			if j < len(values) {
+				value = strings.TrimSpace(values[j])
+			}
+
+			var cell any
+			switch {
+			case keyed && j == 0:
+				key = unquote(value)
+				continue
+			case value == "" && j < len(values):
+				d.tolerate(lineNum, fmt.Sprintf("blank cell for field %q", name), "left as null")
+			case value != "":
+				d.pushPath(name)
+				if err := d.setValue(reflect.ValueOf(&cell).Elem(), value); err != nil {
+					return nil, 0, err
+				}
+				d.popPath()
+			}
+			row = append(row, KeyValue{Key: name, Value: cell})
+		}
+		for _, fold := range folded {
+			var cell any
+			d.pushPath(fold.column)
+			if err := d.setValue(reflect.ValueOf(&cell).Elem(), fold.value); err != nil {
+				return nil, 0, err
+			}
+			d.popPath()
+			row = append(row, KeyValue{Key: fold.column, Value: cell})
+		}
+
+		if keyed {
+			byKey = append(byKey, KeyValue{Key: key, Value: d.object(row)})
+		} else {
+			d.popPath()
+			rows = append(rows, d.object(row))
+		}
+	}
+
+	if keyed {
+		return d.object(byKey), len(byKey), nil
+	}
+	return rows, len(rows), nil
+}
+
+// decodeDynamicList decodes the "- " items below a header indented by
+// indent. Items holding keys become objects, items that are array headers
+// nested arrays, and other items scalars.
+func (d *decoder) decodeDynamicList(indent int) ([]any, error) {
+	a := []any{}
+	for d.hasMore() {
+		d.skipEmptyLines()
+		if !d.hasMore() {
+			break
+		}
+
+		line := d.currentLine()
+		itemIndent := d.getIndent(line)
+		trimmed := strings.TrimSpace(line)
+		if itemIndent <= indent || trimmed != "-" && !strings.HasPrefix(trimmed, "- ") {
+			break
+		}
+		content := strings.TrimSpace(trimmed[1:])
+		d.advance()
+
+		d.pushIndex(len(a))
+		var elem any
+		var err error
+		if header, ok := d.keylessArray(content); ok {
+			elem, err = d.decodeDynamicArray(header.length, header.fields, header.delim, header.value, itemIndent)
+		} else if _, _, ok := cutKeyValue(content); ok && !isQuoted(content) {
+			d.unreadListItem(content, itemIndent+2)
+			elem, err = d.decodeObject(itemIndent + 2)
+		} else if content == "" {
+			elem, err = d.decodeObject(itemIndent + 1)
+		} else {
+			err = d.setValue(reflect.ValueOf(&elem).Elem(), content)
+		}
+		if err != nil {
+			return nil, err
+		}
+		d.popPath()
+		a = append(a, elem)
+	}
+	return a, nil
+}
+
+// setDynamic stores the result of decoding without a Go type in v, which
+// it is assignable to.
+func setDynamic(v reflect.Value, value any) {
+	if value == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return
+	}
+	v.Set(reflect.ValueOf(value))
+}
+
+func isEmptyInterface(t reflect.Type) bool {
+	return t.Kind() == reflect.Interface && t.NumMethod() == 0
+}
+
+// isArrayTarget reports whether a keyless array header decodes into a
+// value of type t. Arrays count too; decodeArrayField checks their length.
+func isArrayTarget(t reflect.Type) bool {
+	t = derefType(t)
+	kind := t.Kind()
+	return (kind == reflect.Slice || kind == reflect.Array) && !isScalarType(t) || isEmptyInterface(t)
+}
@@ -0,0 +1,189 @@
+package toon_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestDecodeDynamic(t *testing.T) {
+	type Hike struct {
+		Name string  `toon:"name"`
+		Km   float64 `toon:"km"`
+	}
+	type Stop struct {
+		Name string   `toon:"name"`
+		Tags []string `toon:"tags"`
+		At   *Hike    `toon:"at"`
+	}
+	data, err := toon.Marshal(map[string]any{
+		"owner":  "ana",
+		"tags":   []string{"lake", "ridge, north"},
+		"hikes":  []Hike{{"Blue Lake", 7.5}, {"Saddle", 12}},
+		"stops":  []Stop{{"hut", []string{"water"}, &Hike{"spur", 1}}, {"peak", nil, nil}},
+		"grid":   [][]int{{1, 2}, {3}},
+		"byName": map[string]Hike{"blue": {"Blue Lake", 7.5}},
+		"meta":   map[string]any{"notes": nil, "empty": []int{}},
+	})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	want := map[string]any{
+		"owner": "ana",
+		"tags":  []any{"lake", "ridge, north"},
+		"hikes": []any{
+			map[string]any{"name": "Blue Lake", "km": 7.5},
+			map[string]any{"name": "Saddle", "km": int64(12)},
+		},
+		"stops": []any{
+			map[string]any{"name": "hut", "tags": []any{"water"}, "at": map[string]any{"name": "spur", "km": int64(1)}},
+			map[string]any{"name": "peak", "tags": []any{}, "at": nil},
+		},
+		"grid":   []any{[]any{int64(1), int64(2)}, []any{int64(3)}},
+		"byName": map[string]any{"blue": map[string]any{"name": "Blue Lake", "km": 7.5}},
+		"meta":   map[string]any{"notes": nil, "empty": []any{}},
+	}
+
+	var m map[string]any
+	if err := toon.Unmarshal(data, &m); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Unmarshal into map = %#v, want %#v", m, want)
+	}
+
+	var v any
+	if err := toon.Unmarshal(data, &v); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Unmarshal into any = %#v, want %#v", v, want)
+	}
+}
+
+func TestDecodeDynamicRoots(t *testing.T) {
+	for input, want := range map[string]any{
+		"[3]: 1,two,null\n":              []any{int64(1), "two", nil},
+		"[2|]{id|name}:\n  1|a\n  2|b\n": []any{map[string]any{"id": int64(1), "name": "a"}, map[string]any{"id": int64(2), "name": "b"}},
+		"[2]:\n  - id: 1\n  - [1]: x\n":  []any{map[string]any{"id": int64(1)}, []any{"x"}},
+		"hello\n":                        "hello",
+		"null\n":                         nil,
+		"a:\nb: 1\n":                     map[string]any{"a": map[string]any{}, "b": int64(1)},
+	} {
+		var v any
+		if err := toon.Unmarshal([]byte(input), &v); err != nil {
+			t.Errorf("Unmarshal(%q) failed: %v", input, err)
+		} else if !reflect.DeepEqual(v, want) {
+			t.Errorf("Unmarshal(%q) = %#v, want %#v", input, v, want)
+		}
+	}
+
+	type Row struct {
+		ID   int    `toon:"id"`
+		Name string `toon:"name"`
+	}
+	var rows []Row
+	if err := toon.Unmarshal([]byte("[2]{id,name}:\n  1,a\n  2,b\n"), &rows); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if want := []Row{{1, "a"}, {2, "b"}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("Unmarshal = %+v, want %+v", rows, want)
+	}
+
+	var grid struct {
+		Cells [][]int    `toon:"cells"`
+		Names [][]string `toon:"names"`
+	}
+	if err := toon.Unmarshal([]byte("cells[2]:\n  - [2]: 1,2\n  - [1]: 3\nnames[1]:\n  - [0]:\n"), &grid); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if want := [][]int{{1, 2}, {3}}; !reflect.DeepEqual(grid.Cells, want) {
+		t.Errorf("Cells = %v, want %v", grid.Cells, want)
+	}
+	if want := [][]string{{}}; !reflect.DeepEqual(grid.Names, want) {
+		t.Errorf("Names = %#v, want %#v", grid.Names, want)
+	}
+}
+
+func TestDecodeDynamicFields(t *testing.T) {
+	var out struct {
+		Any    any              `toon:"any"`
+		List   []any            `toon:"list"`
+		Counts map[string][]int `toon:"counts"`
+	}
+	input := "any[2]: 1,x\nlist[1]{a,b}:\n  1,\ncounts:\n  odd[2]: 1,3\n"
+	if err := toon.Unmarshal([]byte(input), &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if want := []any{int64(1), "x"}; !reflect.DeepEqual(out.Any, want) {
+		t.Errorf("Any = %#v, want %#v", out.Any, want)
+	}
+	if want := []any{map[string]any{"a": int64(1), "b": nil}}; !reflect.DeepEqual(out.List, want) {
+		t.Errorf("List = %#v, want %#v", out.List, want)
+	}
+	if want := map[string][]int{"odd": {1, 3}}; !reflect.DeepEqual(out.Counts, want) {
+		t.Errorf("Counts = %#v, want %#v", out.Counts, want)
+	}
+
+	var list []any
+	if err := toon.Unmarshal([]byte("[1]{_key,a}:\n  k,1\n"), &list); err == nil || !strings.Contains(err.Error(), "cannot be decoded into []interface {}") {
+		t.Errorf("err = %v, want one for the keyed table", err)
+	}
+}
+
+func TestDecodeFixedArrayItems(t *testing.T) {
+	type Route struct {
+		Points [][2]int `toon:"points"`
+	}
+	in := Route{Points: [][2]int{{1, 2}, {3, 4}}}
+	data, err := toon.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), "- [2]: 1,2") {
+		t.Fatalf("Marshal = %q, want list items with array headers", data)
+	}
+
+	var out Route
+	if err := toon.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("Unmarshal = %+v, want %+v", out, in)
+	}
+
+	if err := toon.Unmarshal([]byte("points[1]:\n  - [3]: 1,2,3\n"), &out); err == nil || !strings.Contains(err.Error(), "[2]int") {
+		t.Errorf("err = %v, want a type error for the wrong length", err)
+	}
+}
+
+func TestDynamicDecodeTypeFidelity(t *testing.T) {
+	original := map[string]any{
+		"id":     "007",
+		"count":  "30",
+		"flag":   "true",
+		"none":   "null",
+		"age":    int64(30),
+		"ratio":  1.5,
+		"active": true,
+	}
+
+	data, err := toon.Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := toon.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	for key, want := range original {
+		if got := decoded[key]; got != want {
+			t.Errorf("Key %s: expected %#v, got %#v", key, want, got)
+		}
+	}
+}
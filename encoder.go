@@ -2,47 +2,164 @@ package toon
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
+	"hash/crc32"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 )
 
+// tabularKeyField is the header column that carries map keys when a map of
+// structs is encoded as a table.
+const tabularKeyField = "_key"
+
 type encoder struct {
 	buf  bytes.Buffer
 	opts MarshalOptions
+	path []pathSegment
+
+	// floatPrecision is the number of decimals registered through
+	// TypeOptions for the value being written, or 0 for the shortest form
+	floatPrecision int
+
+	// scratch is reused to format numbers without allocating
+	scratch []byte
+
+	// ids numbers the identifiers replaced when ShortenIDs is set
+	ids *idTable
+
+	// listItem is set while writing a list item's value after its dash,
+	// where a bare separator would read back as a key
+	listItem bool
+
+	// maxDepth, when positive, fails values nested deeper than it instead
+	// of following cycles until the stack runs out
+	maxDepth int
+
+	// keptFields memoizes fields by type and schema path, so the rows of a
+	// table or list are filtered once rather than one by one
+	keptFields map[keptFieldsKey][]structField
+}
+
+type keptFieldsKey struct {
+	t    reflect.Type
+	path string
 }
 
 func newEncoder(opts MarshalOptions) *encoder {
+	if opts.KeyValueSeparator == "" {
+		opts.KeyValueSeparator = ": "
+	}
+	if opts.CompactSeparator {
+		opts.KeyValueSeparator = strings.TrimSpace(opts.KeyValueSeparator)
+	}
 	return &encoder{
 		opts: opts,
 	}
 }
 
+// reset prepares e for encoding another value, keeping its buffers.
+func (e *encoder) reset() {
+	e.buf.Reset()
+	e.path = e.path[:0]
+	e.floatPrecision = 0
+	e.ids = nil
+}
+
 func (e *encoder) encode(v any) ([]byte, error) {
-	rv := reflect.ValueOf(v)
-	if err := e.encodeValue(rv, 0, ""); err != nil {
+	return e.encodeDocument(reflect.ValueOf(v), "")
+}
+
+// encodeDocument writes rv as a whole document, under key unless it is
+// empty.
+func (e *encoder) encodeDocument(rv reflect.Value, key string) ([]byte, error) {
+	return e.writeDocument(func() error {
+		if !rv.IsValid() {
+			return nil
+		}
+		e.growFor(rv.Type())
+		if err := e.encodeValue(rv, 0, key); err != nil {
+			return err
+		}
+		e.recordSize(rv.Type())
+		return nil
+	})
+}
+
+// writeDocument wraps the content written by body in the version header,
+// identifier legend and checksum footer the options ask for.
+func (e *encoder) writeDocument(body func() error) ([]byte, error) {
+	if e.opts.VersionHeader {
+		e.buf.WriteString(versionDirective + FormatVersion + "\n")
+	}
+	start := e.buf.Len()
+	if e.opts.ShortenIDs {
+		e.ids = &idTable{refs: make(map[string]string)}
+	}
+
+	if err := body(); err != nil {
 		return nil, err
 	}
+	if e.ids != nil {
+		e.writeIDLegend(start)
+	}
+
+	if e.opts.Checksum {
+		e.scratch = appendChecksumFooter(e.scratch[:0], checksum(e.buf.Bytes()))
+		e.buf.Write(e.scratch)
+	}
 	return e.buf.Bytes(), nil
 }
 
+// checksum is the CRC-32 of body with CRLF line endings normalized, so
+// documents survive channels that rewrite line endings.
+func checksum(body []byte) uint32 {
+	return crc32.ChecksumIEEE(bytes.ReplaceAll(body, []byte("\r\n"), []byte("\n")))
+}
+
 func (e *encoder) encodeValue(v reflect.Value, depth int, key string) error {
 	if !v.IsValid() {
 		return nil
 	}
+	if e.maxDepth > 0 && depth > e.maxDepth {
+		return fmt.Errorf("toon: value nested more than %d levels deep", e.maxDepth)
+	}
+
+	v, err := e.transform(v)
+	if err != nil {
+		return err
+	}
 
 	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
 		if v.IsNil() {
 			if key != "" {
 				e.writeIndent(depth)
 				e.buf.WriteString(key)
-				e.buf.WriteString(": null\n")
+				e.buf.WriteString(e.opts.KeyValueSeparator + "null\n")
 			}
 			return nil
 		}
 		v = v.Elem()
 	}
 
+	if isUnsupportedKind(v.Kind()) {
+		return e.unsupported(v.Type())
+	}
+
+	if v.Type() == rawMessageType {
+		return e.encodeRawMessage(v, depth, key)
+	}
+	if v.Type() == orderedMapType {
+		return e.encodeOrderedMap(v, depth, key)
+	}
+	if isScalarType(v.Type()) || e.isBase64(v.Type()) {
+		return e.encodePrimitive(v, depth, key)
+	}
+
+	defer e.enterType(v.Type())()
+
 	switch v.Kind() {
 	case reflect.Struct:
 		return e.encodeStruct(v, depth, key)
@@ -63,28 +180,59 @@ func (e *encoder) encodeStruct(v reflect.Value, depth int, key string) error {
 		depth++
 	}
 
-	t := v.Type()
-	for i := 0; i < v.NumField(); i++ {
-		field := t.Field(i)
-		fieldValue := v.Field(i)
-
-		if !field.IsExported() {
-			continue
-		}
-
-		name := e.getFieldName(field)
-		if name == "-" {
+	for _, field := range e.fields(v.Type()) {
+		fieldValue, ok := field.valueOf(v)
+		if !ok || field.omitted(fieldValue) || e.skipsNil(fieldValue) {
 			continue
 		}
 
-		if err := e.encodeValue(fieldValue, depth, name); err != nil {
+		e.pushPath(field.name)
+		start := e.buf.Len()
+		if err := e.encodeValue(e.encodedFieldValue(field, fieldValue), depth, e.prefixKey(depth, e.fieldKey(field.name))); err != nil {
 			return err
 		}
+		e.writeComment(start, field.comment())
+		e.popPath()
 	}
 	return nil
 }
 
+// skipsNil reports whether the SkipNil options drop the field value v.
+func (e *encoder) skipsNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map:
+		return e.opts.SkipNilMaps && v.IsNil()
+	case reflect.Slice:
+		return e.opts.SkipNilSlices && v.IsNil()
+	case reflect.Pointer:
+		return e.opts.SkipNilPointers && v.IsNil()
+	}
+	return false
+}
+
+// fieldKey returns the key written for a struct field or column name, as
+// given by KeyTranslator when set.
+func (e *encoder) fieldKey(name string) string {
+	if e.opts.KeyTranslator == nil {
+		return name
+	}
+	return e.opts.KeyTranslator(name)
+}
+
+// prefixKey adds KeyPrefix to key when it is written at the top level.
+func (e *encoder) prefixKey(depth int, key string) string {
+	if depth > 0 {
+		return key
+	}
+	return e.opts.KeyPrefix + key
+}
+
 func (e *encoder) encodeMap(v reflect.Value, depth int, key string) error {
+	elemType := v.Type().Elem()
+	if e.useTabular(v.Len()) && e.isTabularType(elemType) && !isListFormatType(elemType) && !e.hasNestedValue(v) {
+		return e.encodeTabularMap(v, depth, key)
+	}
+
 	if key != "" {
 		e.writeIndent(depth)
 		e.buf.WriteString(key)
@@ -92,12 +240,16 @@ func (e *encoder) encodeMap(v reflect.Value, depth int, key string) error {
 		depth++
 	}
 
-	keys := v.MapKeys()
-	for _, k := range keys {
-		keyStr := fmt.Sprintf("%v", k.Interface())
-		if err := e.encodeValue(v.MapIndex(k), depth, keyStr); err != nil {
+	for _, k := range sortedMapKeys(v) {
+		keyStr := mapKeyString(k)
+		if err := checkMapKey(keyStr); err != nil {
 			return err
 		}
+		e.pushPath(keyStr)
+		if err := e.encodeValue(v.MapIndex(k), depth, e.prefixKey(depth, keyStr)); err != nil {
+			return err
+		}
+		e.popPath()
 	}
 	return nil
 }
@@ -108,44 +260,79 @@ func (e *encoder) encodeSlice(v reflect.Value, depth int, key string) error {
 	if length == 0 {
 		if key != "" {
 			e.writeIndent(depth)
-			e.buf.WriteString(key)
-			e.buf.WriteString("[0]:\n")
+			e.writeHeader(key, 0)
+			e.buf.WriteString(":\n")
 		}
 		return nil
 	}
 
-	elemType := v.Type().Elem()
-	for elemType.Kind() == reflect.Ptr {
-		elemType = elemType.Elem()
+	if e.opts.SummarizeArrays > 0 && length > e.opts.SummarizeArrays {
+		return e.encodeSummary(v, depth, key)
+	}
+
+	elemType := derefType(v.Type().Elem())
+	if isListFormatType(v.Type()) || isListFormatType(elemType) {
+		return e.encodeListSlice(v, depth, key)
+	}
+	if elemType == orderedMapType || elemType.Kind() == reflect.Interface {
+		if keys, ok := e.orderedTableKeys(v); ok && e.useTabular(length) {
+			return e.encodeOrderedTable(v, keys, depth, key)
+		}
 	}
 
 	switch elemType.Kind() {
 	case reflect.Struct:
-		if e.opts.UseTabular && e.isUniformStructSlice(v) {
+		if isScalarType(elemType) {
+			return e.encodeScalarSlice(v, depth, key)
+		}
+		if e.useTabular(length) && e.isUniformStructSlice(v) {
 			return e.encodeTabularSlice(v, depth, key)
 		}
 		return e.encodeListSlice(v, depth, key)
 	case reflect.Map:
 		return e.encodeListSlice(v, depth, key)
+	case reflect.Slice, reflect.Array:
+		// Nested arrays are list items of their own, except byte slices
+		// written as base64 strings
+		if e.isBase64(elemType) {
+			return e.encodeScalarSlice(v, depth, key)
+		}
+		return e.encodeListSlice(v, depth, key)
+	case reflect.Interface:
+		if hasCompositeElem(v) {
+			return e.encodeListSlice(v, depth, key)
+		}
+		return e.encodeScalarSlice(v, depth, key)
 	default:
-		return e.encodePrimitiveSlice(v, depth, key)
+		return e.encodeScalarSlice(v, depth, key)
+	}
+}
+
+// encodeScalarSlice writes a slice of scalars inline, or as a list once it
+// exceeds MaxInlineItems.
+func (e *encoder) encodeScalarSlice(v reflect.Value, depth int, key string) error {
+	if e.opts.MaxInlineItems > 0 && v.Len() > e.opts.MaxInlineItems {
+		return e.encodeListSlice(v, depth, key)
 	}
+	return e.encodePrimitiveSlice(v, depth, key)
 }
 
 func (e *encoder) encodePrimitiveSlice(v reflect.Value, depth int, key string) error {
 	length := v.Len()
 
 	e.writeIndent(depth)
-	if key != "" {
-		e.buf.WriteString(key)
-	}
-	e.buf.WriteString(fmt.Sprintf("[%d]: ", length))
+	e.writeHeader(key, length)
+	e.buf.WriteString(e.opts.KeyValueSeparator)
 
 	for i := 0; i < length; i++ {
 		if i > 0 {
 			e.buf.WriteString(string(e.opts.Delimiter))
 		}
-		e.writePrimitiveValue(v.Index(i))
+		e.pushIndex(i)
+		if err := e.writeLeafValue(v.Index(i)); err != nil {
+			return err
+		}
+		e.popPath()
 	}
 	e.buf.WriteString("\n")
 	return nil
@@ -166,22 +353,69 @@ func (e *encoder) encodeTabularSlice(v reflect.Value, depth int, key string) err
 		firstElem = firstElem.Elem()
 	}
 
-	fields := e.getStructFieldNames(firstElem)
+	rows := make([]reflect.Value, length)
+	for i := range rows {
+		rows[i] = derefValue(v.Index(i))
+	}
+	fields := e.tableFields(firstElem.Type(), rows)
+	if e.opts.SparseTables && isSparseTable(rows, fields) {
+		return e.encodeSparseTable(rows, fields, depth, key)
+	}
+	if e.opts.FoldConstantColumns && key != "" && length > 1 {
+		return e.encodeFoldedTable(rows, fields, depth, key)
+	}
 
 	e.writeIndent(depth)
-	if key != "" {
-		e.buf.WriteString(key)
-	}
-	e.buf.WriteString(fmt.Sprintf("[%d]{%s}:\n", length, strings.Join(fields, ",")))
+	e.writeHeader(key, length)
+	e.writeHeaderFields(e.columnNames(fields))
 
-	for i := 0; i < length; i++ {
-		elem := v.Index(i)
-		for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
-			elem = elem.Elem()
+	start := e.buf.Len()
+	for i, elem := range rows {
+		e.writeIndent(depth + 1)
+		e.pushIndex(i)
+		if err := e.writeStructAsRow(elem, fields); err != nil {
+			return err
 		}
+		e.popPath()
+		e.buf.WriteString("\n")
+		e.growForRows(start, i+1, length)
+	}
+	return nil
+}
+
+func (e *encoder) encodeTabularMap(v reflect.Value, depth int, key string) error {
+	keys := sortedMapKeys(v)
+
+	rows := make([]reflect.Value, len(keys))
+	for i, k := range keys {
+		rows[i] = derefValue(v.MapIndex(k))
+	}
+	fields := e.tableFields(derefType(v.Type().Elem()), rows)
+	header := append([]string{tabularKeyField}, e.columnNames(fields)...)
+
+	e.writeIndent(depth)
+	e.writeHeader(key, len(keys))
+	e.writeHeaderFields(header)
 
+	for i, k := range keys {
 		e.writeIndent(depth + 1)
-		e.writeStructAsRow(elem)
+		if err := e.writePrimitiveValue(k); err != nil {
+			return err
+		}
+		e.pushPath(mapKeyString(k))
+
+		if elem := rows[i]; len(fields) > 0 {
+			if elem.IsValid() {
+				e.buf.WriteString(string(e.opts.Delimiter))
+				if err := e.writeStructAsRow(elem, fields); err != nil {
+					return err
+				}
+			} else {
+				// Nil elements are written as blank cells
+				e.buf.WriteString(strings.Repeat(string(e.opts.Delimiter), len(fields)))
+			}
+		}
+		e.popPath()
 		e.buf.WriteString("\n")
 	}
 	return nil
@@ -191,10 +425,8 @@ func (e *encoder) encodeListSlice(v reflect.Value, depth int, key string) error
 	length := v.Len()
 
 	e.writeIndent(depth)
-	if key != "" {
-		e.buf.WriteString(key)
-	}
-	e.buf.WriteString(fmt.Sprintf("[%d]:\n", length))
+	e.writeHeader(key, length)
+	e.buf.WriteString(":\n")
 
 	for i := 0; i < length; i++ {
 		elem := v.Index(i)
@@ -205,82 +437,123 @@ func (e *encoder) encodeListSlice(v reflect.Value, depth int, key string) error
 		// Handle the element inline or as nested
 		for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
 			if elem.IsNil() {
-				e.buf.WriteString("null\n")
-				continue
+				break
 			}
 			elem = elem.Elem()
 		}
 
-		switch elem.Kind() {
-		case reflect.Struct:
-			e.encodeListItem(elem, depth+2)
-		case reflect.Map:
-			e.encodeListItemMap(elem, depth+2)
-		default:
-			e.writePrimitiveValue(elem)
+		e.pushIndex(i)
+		var err error
+		switch {
+		case !e.isNested(elem):
+			e.listItem = true
+			err = e.writeLeafValue(elem)
+			e.listItem = false
 			e.buf.WriteString("\n")
+		case elem.Kind() == reflect.Struct:
+			err = e.encodeListItem(elem, depth+2)
+		case elem.Kind() == reflect.Map:
+			err = e.encodeListItemMap(elem, depth+2)
+		default:
+			err = e.encodeInline(elem, depth+2, "")
+		}
+		if err != nil {
+			return err
 		}
+		e.popPath()
 	}
 	return nil
 }
 
+// encodeInline encodes v as encodeValue does at depth, but without indenting
+// the first line, which continues the line of a list item's dash.
+func (e *encoder) encodeInline(v reflect.Value, depth int, key string) error {
+	saved := e.buf
+	e.buf = bytes.Buffer{}
+	err := e.encodeValue(v, depth, key)
+	out := e.buf.String()
+	e.buf = saved
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		// Only empty arrays without a key write nothing
+		out = "[0]:\n"
+	}
+	e.buf.WriteString(strings.TrimLeft(out, " "))
+	return nil
+}
+
+// isNested reports whether v is written as a block of its own rather than
+// as a single value.
+func (e *encoder) isNested(v reflect.Value) bool {
+	if !isCompositeValue(v) {
+		return false
+	}
+	t := derefValue(v).Type()
+	return !e.isBase64(t) && t != rawMessageType
+}
+
 func (e *encoder) encodeListItem(v reflect.Value, depth int) error {
-	t := v.Type()
+	defer e.enterType(v.Type())()
 	first := true
 
-	for i := 0; i < v.NumField(); i++ {
-		field := t.Field(i)
-		if !field.IsExported() {
+	for _, field := range e.fields(v.Type()) {
+		fieldValue, ok := field.valueOf(v)
+		if !ok || field.omitted(fieldValue) || e.skipsNil(fieldValue) {
 			continue
 		}
 
-		name := e.getFieldName(field)
-		if name == "-" {
-			continue
+		e.pushPath(field.name)
+		start := e.buf.Len()
+		if err := e.encodeListItemEntry(e.encodedFieldValue(field, fieldValue), depth, e.fieldKey(field.name), first); err != nil {
+			return err
 		}
+		e.writeComment(start, field.comment())
+		e.popPath()
+		first = false
+	}
+	return nil
+}
 
-		fieldValue := v.Field(i)
-
+// encodeListItemEntry writes one key of a list item. The first key goes on
+// the same line as the dash, later ones on new lines indented to depth.
+func (e *encoder) encodeListItemEntry(v reflect.Value, depth int, key string, first bool) error {
+	if e.isNested(v) {
 		if first {
-			// First field on same line as -
-			e.buf.WriteString(name)
-			e.buf.WriteString(": ")
-			e.writePrimitiveValue(fieldValue)
-			e.buf.WriteString("\n")
-			first = false
-		} else {
-			// Subsequent fields on new lines
-			e.writeIndent(depth)
-			e.buf.WriteString(name)
-			e.buf.WriteString(": ")
-			e.writePrimitiveValue(fieldValue)
-			e.buf.WriteString("\n")
+			return e.encodeInline(v, depth, key)
 		}
+		return e.encodeValue(v, depth, key)
+	}
+
+	if !first {
+		e.writeIndent(depth)
+	}
+	e.buf.WriteString(key)
+	e.buf.WriteString(e.opts.KeyValueSeparator)
+	if err := e.writeLeafValue(v); err != nil {
+		return err
 	}
+	e.buf.WriteString("\n")
 	return nil
 }
 
 func (e *encoder) encodeListItemMap(v reflect.Value, depth int) error {
-	keys := v.MapKeys()
 	first := true
 
-	for _, k := range keys {
-		keyStr := fmt.Sprintf("%v", k.Interface())
-		val := v.MapIndex(k)
+	for _, k := range sortedMapKeys(v) {
+		keyStr := mapKeyString(k)
+		if err := checkMapKey(keyStr); err != nil {
+			return err
+		}
 
-		if first {
-			e.buf.WriteString(keyStr)
-			e.buf.WriteString(": ")
-			e.writePrimitiveValue(val)
-			e.buf.WriteString("\n")
-			first = false
-		} else {
-			e.writeIndent(depth)
-			e.buf.WriteString(keyStr)
-			e.buf.WriteString(": ")
-			e.writePrimitiveValue(val)
-			e.buf.WriteString("\n")
+		e.pushPath(keyStr)
+		if err := e.encodeListItemEntry(v.MapIndex(k), depth, keyStr, first); err != nil {
+			return err
 		}
+		e.popPath()
+		first = false
 	}
 	return nil
 }
@@ -289,107 +562,354 @@ func (e *encoder) encodePrimitive(v reflect.Value, depth int, key string) error
 	e.writeIndent(depth)
 	if key != "" {
 		e.buf.WriteString(key)
-		e.buf.WriteString(": ")
+		e.buf.WriteString(e.opts.KeyValueSeparator)
+	}
+	if err := e.writePrimitiveValue(v); err != nil {
+		return err
 	}
-	e.writePrimitiveValue(v)
 	e.buf.WriteString("\n")
 	return nil
 }
 
-func (e *encoder) writePrimitiveValue(v reflect.Value) {
+// writeLeafValue writes a value that does not go through encodeValue, such
+// as a table cell, applying TransformValue first.
+func (e *encoder) writeLeafValue(v reflect.Value) error {
+	v, err := e.transform(v)
+	if err != nil {
+		return err
+	}
+	return e.writePrimitiveValue(v)
+}
+
+// transform applies TransformValue to v at the current path.
+func (e *encoder) transform(v reflect.Value) (reflect.Value, error) {
+	if e.opts.TransformValue == nil || !v.IsValid() || !v.CanInterface() {
+		return v, nil
+	}
+
+	result, err := e.opts.TransformValue(e.pathString(), v.Interface())
+	if err != nil {
+		return v, fmt.Errorf("toon: transform %s: %w", e.pathString(), err)
+	}
+	// Keep a nil result as a nil interface so it is written as null
+	return reflect.ValueOf(&result).Elem(), nil
+}
+
+func (e *encoder) writePrimitiveValue(v reflect.Value) error {
 	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
 		if v.IsNil() {
 			e.buf.WriteString("null")
-			return
+			return nil
 		}
 		v = v.Elem()
 	}
 
-	switch v.Kind() {
-	case reflect.String:
-		s := v.String()
-		if strings.ContainsAny(s, ",|\t\n") {
-			e.buf.WriteString("\"")
-			e.buf.WriteString(strings.ReplaceAll(s, "\"", "\\\""))
-			e.buf.WriteString("\"")
+	if isUnsupportedKind(v.Kind()) {
+		return e.unsupported(v.Type())
+	}
+
+	if v.Type() == jsonNumberType {
+		e.buf.WriteString(v.String())
+		return nil
+	}
+	if isMarshalerType(v.Type()) {
+		return e.writeMarshaler(v)
+	}
+	if isScalarType(v.Type()) {
+		e.writeScalarValue(v)
+		return nil
+	}
+	if e.isBase64(v.Type()) {
+		if v.IsNil() {
+			e.buf.WriteString("null")
 		} else {
-			e.buf.WriteString(s)
+			e.writeString(base64.StdEncoding.EncodeToString(v.Bytes()))
 		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		e.writeString(v.String())
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		e.buf.WriteString(fmt.Sprintf("%d", v.Int()))
+		e.scratch = strconv.AppendInt(e.scratch[:0], v.Int(), 10)
+		e.buf.Write(e.scratch)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		e.buf.WriteString(fmt.Sprintf("%d", v.Uint()))
-	case reflect.Float32:
-		e.buf.WriteString(fmt.Sprintf("%g", v.Float()))
-	case reflect.Float64:
-		e.buf.WriteString(fmt.Sprintf("%g", v.Float()))
+		e.scratch = strconv.AppendUint(e.scratch[:0], v.Uint(), 10)
+		e.buf.Write(e.scratch)
+	case reflect.Float32, reflect.Float64:
+		defer e.enterType(v.Type())()
+		e.scratch = appendFloat(e.scratch[:0], v.Float(), v.Type().Bits(), e.floatPrecision)
+		e.buf.Write(e.scratch)
 	case reflect.Bool:
-		e.buf.WriteString(fmt.Sprintf("%t", v.Bool()))
+		e.buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Complex64, reflect.Complex128:
+		e.buf.WriteString(formatComplex(v.Complex(), v.Type().Bits()))
 	default:
-		e.buf.WriteString(fmt.Sprintf("%v", v.Interface()))
+		fmt.Fprint(&e.buf, v.Interface())
 	}
+	return nil
 }
 
-func (e *encoder) writeStructAsRow(v reflect.Value) {
-	t := v.Type()
-	first := true
+// writeHeader writes the start of an array header, key[length], or key[]
+// when OmitArrayCounts is set.
+func (e *encoder) writeHeader(key string, length int) {
+	e.buf.WriteString(key)
+	e.buf.WriteByte('[')
+	if !e.opts.OmitArrayCounts {
+		e.scratch = strconv.AppendInt(e.scratch[:0], int64(length), 10)
+		e.buf.Write(e.scratch)
+	}
+	// Comma is implied; other delimiters are declared so decoders need not
+	// guess them
+	if e.opts.Delimiter != DelimiterComma {
+		e.buf.WriteString(string(e.opts.Delimiter))
+	}
+	e.buf.WriteByte(']')
+}
 
-	for i := 0; i < v.NumField(); i++ {
-		field := t.Field(i)
-		if !field.IsExported() {
-			continue
+// writeHeaderFields writes the field list and colon ending a tabular
+// array header.
+func (e *encoder) writeHeaderFields(fields []string) {
+	e.buf.WriteByte('{')
+	e.buf.WriteString(e.formatHeaderFields(fields))
+	e.buf.WriteString("}:\n")
+}
+
+func (e *encoder) writeString(s string) {
+	if e.ids != nil && longIDPattern.MatchString(s) {
+		e.buf.WriteString(e.ids.ref(s))
+		return
+	}
+	// Strings that read like references or, as list items, like key-value
+	// pairs are quoted so they stay strings
+	if e.shouldQuote(s) || e.listItem && strings.ContainsAny(s, ":=") || e.ids != nil && idRefPattern.MatchString(s) {
+		e.buf.WriteString(quoteString(s))
+	} else {
+		e.buf.WriteString(s)
+	}
+}
+
+func (e *encoder) writeStructAsRow(v reflect.Value, fields []structField) error {
+	defer e.enterType(v.Type())()
+	for i, field := range fields {
+		if i > 0 {
+			e.buf.WriteString(string(e.opts.Delimiter))
 		}
 
-		name := e.getFieldName(field)
-		if name == "-" {
-			continue
+		// Fields behind a nil embedded pointer are left as blank cells
+		if fieldValue, ok := field.valueOf(v); ok {
+			e.pushPath(field.name)
+			if err := e.writeLeafValue(e.encodedFieldValue(field, fieldValue)); err != nil {
+				return err
+			}
+			e.popPath()
 		}
+	}
+	return nil
+}
 
-		if !first {
-			e.buf.WriteString(string(e.opts.Delimiter))
+// tableFields returns the columns of a table of t values, ordered by
+// OptimizeColumns when enabled. rows holds the table's elements.
+func (e *encoder) tableFields(t reflect.Type, rows []reflect.Value) []structField {
+	fields := e.fields(t)
+	if e.opts.OptimizeColumns {
+		fields = e.orderColumns(fields, rows)
+	}
+	return fields
+}
+
+func (e *encoder) columnNames(fields []structField) []string {
+	var names []string
+	for _, field := range fields {
+		names = append(names, e.columnName(field))
+	}
+	return names
+}
+
+// columnName returns the name of field's column in a table header.
+func (e *encoder) columnName(field structField) string {
+	return field.withUnit(e.fieldKey(field.column))
+}
+
+// cellText renders v as it would be written in a table cell.
+func (e *encoder) cellText(v reflect.Value) string {
+	sub := newEncoder(e.opts)
+	sub.ids = e.ids
+	if err := sub.writePrimitiveValue(v); err != nil {
+		return ""
+	}
+	return sub.buf.String()
+}
+
+// formatHeaderFields joins tabular field names with the active delimiter,
+// quoting names that would otherwise be split or end the field list early.
+func (e *encoder) formatHeaderFields(fields []string) string {
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		if needsQuoting(field) || strings.ContainsAny(field, "{}") {
+			quoted[i] = quoteString(field)
+		} else {
+			quoted[i] = field
 		}
-		first = false
+	}
+	return strings.Join(quoted, string(e.opts.Delimiter))
+}
 
-		e.writePrimitiveValue(v.Field(i))
+// shouldQuote applies the configured StringQuoting policy to s.
+func (e *encoder) shouldQuote(s string) bool {
+	switch e.opts.StringQuoting {
+	case QuoteAlways:
+		return true
+	case QuoteMinimal:
+		return s == "" ||
+			strings.ContainsAny(s, string(e.opts.Delimiter)+"\n\r") ||
+			strings.HasPrefix(s, "\"") ||
+			strings.TrimSpace(s) != s ||
+			holdsComment(s) ||
+			looksLikeLiteral(s)
+	default:
+		return needsQuoting(s)
 	}
 }
 
-func (e *encoder) getStructFieldNames(v reflect.Value) []string {
-	t := v.Type()
-	var fields []string
+// fields returns the struct fields of t to encode at the current path,
+// derived fields included. In lenient mode fields whose type can never be
+// encoded are dropped, as are those rejected by FieldFilter, so headers
+// and rows agree.
+func (e *encoder) fields(t reflect.Type) []structField {
+	fields := encodedFields(t)
+	if !e.opts.Lenient && e.opts.FieldFilter == nil && !hasAudiences(fields) {
+		return fields
+	}
 
-	for i := 0; i < v.NumField(); i++ {
-		field := t.Field(i)
-		if !field.IsExported() {
+	prefix := schemaPath(e.path)
+	key := keptFieldsKey{t, prefix}
+	if kept, ok := e.keptFields[key]; ok {
+		return kept
+	}
+	kept := fields[:0:0]
+	for _, field := range fields {
+		if e.opts.Lenient && isUnsupportedType(field.typ) {
+			continue
+		}
+		if !field.visibleTo(e.opts.Audience) {
+			continue
+		}
+		if e.opts.FieldFilter != nil && !e.opts.FieldFilter(joinPath(prefix, field.name), field.field) {
 			continue
 		}
+		kept = append(kept, field)
+	}
+	if e.keptFields == nil {
+		e.keptFields = make(map[keptFieldsKey][]structField)
+	}
+	e.keptFields[key] = kept
+	return kept
+}
+
+func hasAudiences(fields []structField) bool {
+	for _, field := range fields {
+		if len(field.audiences()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// unsupported reports a value of type t that cannot be encoded. In lenient
+// mode the value is skipped and nothing is written.
+func (e *encoder) unsupported(t reflect.Type) error {
+	if e.opts.Lenient {
+		return nil
+	}
+	return &UnsupportedTypeError{Path: e.pathString(), Type: t}
+}
+
+// pathSegment is a key on the path to the value being written, or an
+// array index when index is not negative. Indexes are only formatted when
+// the path is read, so rows cost nothing to track.
+type pathSegment struct {
+	key   string
+	index int
+}
+
+func (e *encoder) pushPath(segment string) {
+	e.path = append(e.path, pathSegment{key: segment, index: -1})
+}
+
+func (e *encoder) pushIndex(i int) {
+	e.path = append(e.path, pathSegment{index: i})
+}
+
+func (e *encoder) popPath() {
+	e.path = e.path[:len(e.path)-1]
+}
 
-		name := e.getFieldName(field)
-		if name == "-" {
+// pathString renders the path like hikes[2].name.
+func (e *encoder) pathString() string {
+	var b strings.Builder
+	for _, segment := range e.path {
+		if segment.index >= 0 {
+			b.WriteByte('[')
+			b.WriteString(strconv.Itoa(segment.index))
+			b.WriteByte(']')
 			continue
 		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(segment.key)
+	}
+	return b.String()
+}
 
-		fields = append(fields, name)
+// schemaPath renders path segments without array indexes, like hikes.name.
+func schemaPath(path []pathSegment) string {
+	var b strings.Builder
+	for _, segment := range path {
+		if segment.index >= 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(segment.key)
 	}
-	return fields
+	return b.String()
 }
 
-func (e *encoder) getFieldName(field reflect.StructField) string {
-	if tag := field.Tag.Get("toon"); tag != "" {
-		parts := strings.Split(tag, ",")
-		return parts[0]
+// formatPath renders path segments like hikes[2].name.
+func formatPath(path []string) string {
+	var b strings.Builder
+	for _, segment := range path {
+		if b.Len() > 0 && !strings.HasPrefix(segment, "[") {
+			b.WriteByte('.')
+		}
+		b.WriteString(segment)
 	}
+	return b.String()
+}
 
-	if tag := field.Tag.Get("json"); tag != "" {
-		parts := strings.Split(tag, ",")
-		return parts[0]
+func isUnsupportedKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return true
 	}
+	return false
+}
 
-	name := field.Name
-	if len(name) > 0 {
-		return strings.ToLower(name[:1]) + name[1:]
+// isUnsupportedType reports whether t, or the element type it ultimately
+// holds, is of an unsupported kind.
+func isUnsupportedType(t reflect.Type) bool {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return isUnsupportedKind(t.Kind())
+		}
 	}
-	return name
 }
 
 func (e *encoder) writeIndent(depth int) {
@@ -398,6 +918,99 @@ func (e *encoder) writeIndent(depth int) {
 	}
 }
 
+// sortedMapKeys returns the keys of v in a stable order so map output is
+// deterministic: integers by value, other keys by their text.
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+
+	// Integer keys go in numeric order, so that 10 follows 9
+	switch v.Type().Key().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Int() < keys[j].Int() })
+		return keys
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Uint() < keys[j].Uint() })
+		return keys
+	}
+
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = mapKeyString(k)
+	}
+	sort.Sort(keysByName{keys, names})
+	return keys
+}
+
+type keysByName struct {
+	keys  []reflect.Value
+	names []string
+}
+
+func (s keysByName) Len() int           { return len(s.keys) }
+func (s keysByName) Less(i, j int) bool { return s.names[i] < s.names[j] }
+func (s keysByName) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+}
+
+var stringType = reflect.TypeOf("")
+
+// mapKeyString is fmt.Sprint(k.Interface()) without its overhead for plain
+// string keys.
+func mapKeyString(k reflect.Value) string {
+	if k.Type() == stringType {
+		return k.String()
+	}
+	return fmt.Sprint(k.Interface())
+}
+
+// checkMapKey fails for map keys that would not read back unchanged, since
+// keys are written bare.
+func checkMapKey(key string) error {
+	if !isFlatKey(key) {
+		return fmt.Errorf("toon: cannot write %q as a key", key)
+	}
+	return nil
+}
+
+// hasCompositeElem reports whether any element of the slice v holds a
+// struct, map, slice or array once pointers and interfaces are followed.
+func hasCompositeElem(v reflect.Value) bool {
+	for i := 0; i < v.Len(); i++ {
+		if isCompositeValue(v.Index(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCompositeValue reports whether v holds a struct, map, slice or array
+// once pointers and interfaces are followed, other than a scalar type.
+func isCompositeValue(v reflect.Value) bool {
+	v = derefValue(v)
+	if !v.IsValid() || isScalarType(v.Type()) {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// isBase64 reports whether values of type t are written as base64 strings.
+func (e *encoder) isBase64(t reflect.Type) bool {
+	return !e.opts.BytesAsArray && isByteSlice(t)
+}
+
+func isByteSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+func (e *encoder) useTabular(length int) bool {
+	return e.opts.UseTabular && length > 0 && length >= e.opts.MinTabularRows
+}
+
 func (e *encoder) isUniformStructSlice(v reflect.Value) bool {
 	if v.Len() == 0 {
 		return false
@@ -411,18 +1024,82 @@ func (e *encoder) isUniformStructSlice(v reflect.Value) bool {
 		firstElem = firstElem.Elem()
 	}
 
-	if firstElem.Kind() != reflect.Struct {
+	t := firstElem.Type()
+	if !e.isTabularType(t) {
+		return false
+	}
+	if fields := e.interfaceFields(t); len(fields) > 0 {
+		for i := 0; i < v.Len(); i++ {
+			if e.hasNestedField(v.Index(i), t, fields) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hasNestedValue reports whether a struct held in the map v has an
+// interface field that does not fit in a table cell.
+func (e *encoder) hasNestedValue(v reflect.Value) bool {
+	t := derefType(v.Type().Elem())
+	fields := e.interfaceFields(t)
+	if len(fields) == 0 {
 		return false
 	}
 
-	t := firstElem.Type()
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if !field.IsExported() {
-			continue
+	iter := v.MapRange()
+	for iter.Next() {
+		if e.hasNestedField(iter.Value(), t, fields) {
+			return true
 		}
+	}
+	return false
+}
+
+// interfaceFields returns the fields of the struct type t that are
+// declared with an interface type.
+func (e *encoder) interfaceFields(t reflect.Type) []structField {
+	var fields []structField
+	for _, field := range e.fields(t) {
+		if field.typ.Kind() == reflect.Interface {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
 
-		kind := field.Type.Kind()
+// hasNestedField reports whether one of fields, interface fields of the
+// struct type t, holds a value in v that does not fit in a table cell.
+func (e *encoder) hasNestedField(v reflect.Value, t reflect.Type, fields []structField) bool {
+	v = derefValue(v)
+	if !v.IsValid() || v.Type() != t {
+		return false
+	}
+	for _, field := range fields {
+		if fv, ok := field.valueOf(v); ok && e.isNested(fv) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTabularType reports whether values of type t can be written as a single
+// table row, i.e. t is a struct (or pointer to one) with only scalar fields.
+func (e *encoder) isTabularType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || isScalarType(t) {
+		return false
+	}
+
+	for _, field := range encodedFields(t) {
+		ft := derefType(field.typ)
+		kind := ft.Kind()
+		if isScalarType(ft) || e.isBase64(ft) {
+			continue
+		}
 		if kind == reflect.Struct || kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map {
 			return false
 		}
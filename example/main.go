@@ -118,7 +118,7 @@ func main() {
 	fmt.Println("\nFormat Validation:")
 	fmt.Println("=====================")
 
-	fmt.Printf("Valid TOON data: %t\n", toon.Valid(toonData))
-	fmt.Printf("Valid JSON as TOON: %t\n", toon.Valid(jsonData))
-	fmt.Printf("Valid random text: %t\n", toon.Valid([]byte("random invalid text")))
+	fmt.Printf("Valid TOON data: %t\n", toon.Valid(toonData, toon.LevelSyntax))
+	fmt.Printf("Valid JSON as TOON: %t\n", toon.Valid(jsonData, toon.LevelSyntax))
+	fmt.Printf("Valid random text: %t\n", toon.Valid([]byte("random invalid text"), toon.LevelSyntax))
 }
@@ -0,0 +1,118 @@
+package toon
+
+import (
+	"fmt"
+	"strings"
+)
+
+type extractFrame struct {
+	indent int
+	path   string
+	fields []string  // column names when the frame is a table
+	delim  Delimiter // delimiter declared in the table's header, if any
+	list   bool
+	items  int // rows or list items seen so far
+}
+
+// Extract scans data once and returns the values found at the requested
+// paths, written like context.task, friends[0] or hikes[2].name, without
+// decoding the rest of the document. Values are returned as text with
+// quotes and escapes resolved; paths that are not present, or that lead to
+// a block rather than a value, are left out of the result.
+func Extract(data []byte, paths []string) (map[string]string, error) {
+	d := newDecoder(data, DefaultUnmarshalOptions())
+	result := make(map[string]string, len(paths))
+	wanted := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		wanted[path] = true
+	}
+
+	record := func(path, raw string) {
+		if wanted[path] {
+			result[path] = unquote(strings.TrimSpace(raw))
+			delete(wanted, path)
+		}
+	}
+
+	var stack []*extractFrame
+	for len(wanted) > 0 {
+		d.skipEmptyLines()
+		if !d.hasMore() {
+			break
+		}
+
+		line := d.currentLine()
+		indent := d.getIndent(line)
+		trimmed := strings.TrimSpace(line)
+		d.advance()
+
+		for len(stack) > 0 && indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := &extractFrame{indent: -1}
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+
+		if parent.fields != nil {
+			rowPath := fmt.Sprintf("%s[%d]", parent.path, parent.items)
+			parent.items++
+			restore := d.declareDelimiter(parent.delim)
+			for j, cell := range d.splitValues(trimmed) {
+				if j < len(parent.fields) {
+					record(rowPath+"."+parent.fields[j], cell)
+				}
+			}
+			restore()
+			continue
+		}
+
+		if parent.list && (trimmed == "-" || strings.HasPrefix(trimmed, "- ")) {
+			item := &extractFrame{indent: indent, path: fmt.Sprintf("%s[%d]", parent.path, parent.items)}
+			parent.items++
+			stack = append(stack, item)
+
+			content := strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
+			if _, _, ok := cutKeyValue(content); !ok || isQuoted(content) {
+				record(item.path, content)
+				continue
+			}
+			// Fields on the dash line sit where the item's other fields do
+			parent, indent, trimmed = item, indent+2, content
+		}
+
+		key, value, ok := cutKeyValue(trimmed)
+		if !ok {
+			return result, d.syntaxError(d.pos, "expected key: value")
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		length, fields, delim := d.parseArrayDeclaration(key)
+		if length == notArray {
+			path := joinPath(parent.path, key)
+			if value == "" {
+				stack = append(stack, &extractFrame{indent: indent, path: path})
+			} else {
+				record(path, value)
+			}
+			continue
+		}
+
+		path := joinPath(parent.path, d.extractKeyFromArray(key))
+		switch {
+		case len(fields) > 0:
+			stack = append(stack, &extractFrame{indent: indent, path: path, fields: fields, delim: delim})
+		case value != "":
+			restore := d.declareDelimiter(delim)
+			for i, cell := range d.splitValues(value) {
+				record(fmt.Sprintf("%s[%d]", path, i), cell)
+			}
+			restore()
+		default:
+			stack = append(stack, &extractFrame{indent: indent, path: path, list: true})
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package toon_test
+
+import (
+	"errors"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestExtract(t *testing.T) {
+	input := `#toon 1.0
+context:
+  task: Our favorite hikes
+  location: "Boulder, CO"
+friends[3]: ana,luis,sam
+hikes[2]{id,name,distanceKm}:
+  1,Blue Lake Trail,7.5
+  2,Ridge Overlook,9.2
+stops[2]:
+  - name: Trailhead
+    meta:
+      elevation: 2400
+    kind: parking
+  - name: Summit
+    kind: view
+tags[2]:
+  - alpine
+  - "a: b"
+`
+
+	got, err := toon.Extract([]byte(input), []string{
+		"context.location",
+		"friends[2]",
+		"hikes[1].name",
+		"stops[0].meta.elevation",
+		"stops[0].kind",
+		"stops[1].name",
+		"tags[1]",
+		"context",
+		"missing.key",
+	})
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	want := map[string]string{
+		"context.location":        "Boulder, CO",
+		"friends[2]":              "sam",
+		"hikes[1].name":           "Ridge Overlook",
+		"stops[0].meta.elevation": "2400",
+		"stops[0].kind":           "parking",
+		"stops[1].name":           "Summit",
+		"tags[1]":                 "a: b",
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d values, want %d: %v", len(got), len(want), got)
+	}
+	for path, value := range want {
+		if got[path] != value {
+			t.Errorf("%s = %q, want %q", path, got[path], value)
+		}
+	}
+}
+
+func TestExtractSyntaxError(t *testing.T) {
+	_, err := toon.Extract([]byte("name: x\ngarbage\nage: 3\n"), []string{"age"})
+	var syntaxErr *toon.SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Line != 2 {
+		t.Errorf("expected SyntaxError on line 2, got %v", err)
+	}
+}
@@ -0,0 +1,376 @@
+package toon
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type structField struct {
+	name    string
+	index   []int
+	typ     reflect.Type
+	options []string
+	field   reflect.StructField
+
+	// column is the header name used in tabular form, from the toonCol
+	// tag, defaulting to name.
+	column string
+
+	// method names the method computing a derived field, which has no
+	// index.
+	method string
+}
+
+var fieldCache sync.Map // reflect.Type -> []structField
+
+// structFields returns the encodable fields of t in declaration order,
+// computed once per type. The result is shared and must not be modified.
+func structFields(t reflect.Type) []structField {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]structField)
+	}
+	fields := typeFields(t)
+	cached, _ := fieldCache.LoadOrStore(t, fields[:len(fields):len(fields)])
+	return cached.([]structField)
+}
+
+// typeFields works out the fields of t for structFields. Fields of
+// embedded structs without an explicit name are promoted into the parent
+// at the embedding position. Colliding names are resolved as in
+// encoding/json: the field closest to the root wins, then a tagged field
+// over untagged ones, and otherwise the colliding fields are all dropped.
+func typeFields(t reflect.Type) []structField {
+	var candidates []structField
+	var depths []int
+
+	// walking guards against types that embed themselves through a pointer
+	walking := make(map[reflect.Type]bool)
+
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		if walking[t] {
+			return
+		}
+		walking[t] = true
+		defer delete(walking, t)
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldIndex := append(append([]int(nil), index...), i)
+
+			if field.Anonymous && !hasTagName(field) {
+				ft := field.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					if field.IsExported() || field.Type.Kind() != reflect.Ptr {
+						walk(ft, fieldIndex)
+					}
+					continue
+				}
+			}
+
+			if !field.IsExported() {
+				continue
+			}
+
+			// A lone "-" drops the field; "-," names it "-"
+			if fieldTag(field) == "-" {
+				continue
+			}
+			name := getFieldName(field)
+
+			candidates = append(candidates, structField{
+				name:    name,
+				index:   fieldIndex,
+				typ:     field.Type,
+				options: tagOptions(field),
+				field:   field,
+				column:  columnName(field, name),
+			})
+			depths = append(depths, len(index))
+		}
+	}
+	walk(t, nil)
+
+	byName := make(map[string][]int)
+	for i, f := range candidates {
+		byName[f.name] = append(byName[f.name], i)
+	}
+
+	var fields []structField
+	for i, f := range candidates {
+		if dominantField(byName[f.name], candidates, depths) == i {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// encodedFields returns the fields written for the struct type t: its
+// declared fields followed by any derived ones.
+func encodedFields(t reflect.Type) []structField {
+	derived := derivedFields(t)
+	if len(derived) == 0 {
+		return structFields(t)
+	}
+	return append(structFields(t), derived...)
+}
+
+// dominantField returns which of the candidates sharing a name is kept, or
+// -1 when they cancel each other out.
+func dominantField(indexes []int, candidates []structField, depths []int) int {
+	var shallowest []int
+	for _, i := range indexes {
+		switch {
+		case len(shallowest) == 0 || depths[i] < depths[shallowest[0]]:
+			shallowest = []int{i}
+		case depths[i] == depths[shallowest[0]]:
+			shallowest = append(shallowest, i)
+		}
+	}
+	if len(shallowest) == 1 {
+		return shallowest[0]
+	}
+
+	dominant := -1
+	for _, i := range shallowest {
+		if hasTagName(candidates[i].field) {
+			if dominant >= 0 {
+				return -1
+			}
+			dominant = i
+		}
+	}
+	return dominant
+}
+
+// fieldsByName maps each field name of t to its field.
+func fieldsByName(t reflect.Type) map[string]structField {
+	fields := make(map[string]structField)
+	for _, f := range structFields(t) {
+		fields[f.name] = f
+	}
+	return fields
+}
+
+// fieldsByColumn maps each tabular column name of t to its field, with and
+// without the unit its header carries. Plain field names are accepted too
+// where they don't clash with a column.
+func fieldsByColumn(t reflect.Type) map[string]structField {
+	fields := make(map[string]structField)
+	all := structFields(t)
+	for _, f := range all {
+		fields[f.column] = f
+		fields[f.withUnit(f.column)] = f
+	}
+	for _, f := range all {
+		if _, ok := fields[f.name]; !ok {
+			fields[f.name] = f
+		}
+	}
+	return fields
+}
+
+func (f structField) hasOption(name string) bool {
+	for _, option := range f.options {
+		if option == name {
+			return true
+		}
+	}
+	return false
+}
+
+// audiences returns the audiences named by the field's only= options, as
+// in only=internal or only=log|api. Fields without any are written for
+// every audience.
+func (f structField) audiences() []string {
+	var audiences []string
+	for _, option := range f.options {
+		if list, ok := strings.CutPrefix(option, "only="); ok {
+			audiences = append(audiences, strings.Split(list, "|")...)
+		}
+	}
+	return audiences
+}
+
+// unit returns the unit named by the field's unit= option, as in unit=km,
+// or "".
+func (f structField) unit() string {
+	for _, option := range f.options {
+		if unit, ok := strings.CutPrefix(option, "unit="); ok {
+			return unit
+		}
+	}
+	return ""
+}
+
+// timeFormat returns the layout the field's format= option gives its
+// times, as in format=2006-01-02, or "" when it has none. Layouts cannot
+// hold commas, which separate tag options.
+func (f structField) timeFormat() string {
+	for _, option := range f.options {
+		if layout, ok := strings.CutPrefix(option, "format="); ok {
+			return layout
+		}
+	}
+	return ""
+}
+
+// withUnit returns column as named in a table header, with the field's
+// unit appended as in distanceKm(km).
+func (f structField) withUnit(column string) string {
+	if unit := f.unit(); unit != "" {
+		return column + "(" + unit + ")"
+	}
+	return column
+}
+
+// visibleTo reports whether the field is written for audience.
+func (f structField) visibleTo(audience string) bool {
+	audiences := f.audiences()
+	if len(audiences) == 0 {
+		return true
+	}
+	for _, a := range audiences {
+		if a == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// omitted reports whether the field's omitempty option drops v, which it
+// does for false, 0, nil pointers and interfaces and empty strings, slices,
+// arrays and maps, as in encoding/json.
+func (f structField) omitted(v reflect.Value) bool {
+	if !f.hasOption("omitempty") {
+		return false
+	}
+
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// encodedFieldValue returns the value written for a field: times in the
+// form chosen by fieldTimeValue, and numbers and booleans as strings when
+// the field has the string option, so that they are written quoted.
+func (e *encoder) encodedFieldValue(field structField, v reflect.Value) reflect.Value {
+	v = e.fieldTimeValue(field, v)
+	if !field.hasOption("string") {
+		return v
+	}
+
+	sv := derefValue(v)
+	if !sv.IsValid() || isScalarType(sv.Type()) {
+		return v
+	}
+	switch sv.Kind() {
+	case reflect.Bool:
+		return reflect.ValueOf(strconv.FormatBool(sv.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(strconv.FormatInt(sv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return reflect.ValueOf(strconv.FormatUint(sv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return reflect.ValueOf(strconv.FormatFloat(sv.Float(), 'g', -1, sv.Type().Bits()))
+	}
+	return v
+}
+
+// valueOf returns the value of the field in the struct v, calling the
+// method of derived fields, and false when a nil embedded pointer hides it.
+func (f structField) valueOf(v reflect.Value) (reflect.Value, bool) {
+	if f.method == "" {
+		return fieldByIndex(v, f.index)
+	}
+	if !v.CanAddr() {
+		p := reflect.New(v.Type()).Elem()
+		p.Set(v)
+		v = p
+	}
+	return v.Addr().MethodByName(f.method).Call(nil)[0], true
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but reports false instead
+// of panicking when an embedded pointer along the way is nil.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// fieldByIndexAlloc is like reflect.Value.FieldByIndex but allocates nil
+// embedded pointers, for use when decoding into v.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldTag returns the toon tag of field, falling back to its json tag so
+// JSON-annotated types need no extra tags.
+func fieldTag(field reflect.StructField) string {
+	if tag := field.Tag.Get("toon"); tag != "" {
+		return tag
+	}
+	return field.Tag.Get("json")
+}
+
+func hasTagName(field reflect.StructField) bool {
+	name, _, _ := strings.Cut(fieldTag(field), ",")
+	return name != ""
+}
+
+// tagOptions returns the options following the name in the field's tag,
+// such as omitempty or string.
+func tagOptions(field reflect.StructField) []string {
+	if tag := fieldTag(field); tag != "" {
+		return strings.Split(tag, ",")[1:]
+	}
+	return nil
+}
+
+func columnName(field reflect.StructField, name string) string {
+	if column := field.Tag.Get("toonCol"); column != "" {
+		return column
+	}
+	return name
+}
+
+func getFieldName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(fieldTag(field), ","); name != "" {
+		return name
+	}
+	name := field.Name
+	if len(name) > 0 {
+		return strings.ToLower(name[:1]) + name[1:]
+	}
+	return name
+}
@@ -0,0 +1,174 @@
+package toon_test
+
+import (
+	"reflect"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestEmbeddedStructFields(t *testing.T) {
+	type Base struct {
+		ID        int    `json:"id"`
+		CreatedBy string `json:"created_by"`
+	}
+	type Audit struct {
+		Source string `toon:"source"`
+	}
+	type Item struct {
+		Base
+		*Audit
+		Name string `json:"name"`
+	}
+
+	input := `items[2]{id,created_by,source,name}:
+  1,ana,api,Tent
+  2,luis,,Stove
+`
+
+	var result struct {
+		Items []Item `toon:"items"`
+	}
+	if err := toon.Unmarshal([]byte(input), &result); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if len(result.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(result.Items))
+	}
+	first := result.Items[0]
+	if first.ID != 1 || first.CreatedBy != "ana" || first.Audit == nil || first.Source != "api" || first.Name != "Tent" {
+		t.Errorf("First item incorrect: %+v", first)
+	}
+
+	data, err := toon.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := `items[2]{id,created_by,source,name}:
+  1,ana,api,Tent
+  2,luis,,Stove
+`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+}
+
+func TestEmbeddedFieldPrecedence(t *testing.T) {
+	type Left struct {
+		ID    int    `json:"id"`
+		Label string `json:"label"`
+		Color string
+	}
+	type Right struct {
+		ID    int `toon:"id"`
+		Label string
+		Color string
+	}
+	type Node struct {
+		Left
+		Right
+		*Node
+		Name string `json:"name"`
+	}
+
+	in := Node{
+		Left:  Left{ID: 1, Label: "left", Color: "red"},
+		Right: Right{ID: 2, Label: "right", Color: "blue"},
+		Name:  "n",
+	}
+
+	// id and color collide at the same depth and cancel out; only the
+	// tagged label survives
+	data, err := toon.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := "label: left\nname: n\n"
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+
+	var out Node
+	if err := toon.Unmarshal([]byte("id: 5\nlabel: x\nname: y\n"), &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Left.ID != 0 || out.Right.ID != 0 || out.Left.Label != "x" || out.Right.Label != "" || out.Name != "y" {
+		t.Errorf("Unmarshal = %+v, want only label and name set", out)
+	}
+}
+
+func TestFieldTags(t *testing.T) {
+	type Tagged struct {
+		Name    string   `json:"name"`
+		Km      int      `json:"km,string"`
+		Done    bool     `json:"done,string"`
+		Note    string   `json:"note,omitempty"`
+		Tags    []string `json:"tags,omitempty"`
+		Secret  string   `json:"-"`
+		Dash    string   `json:"-,"`
+		Elapsed *int     `json:"elapsed,omitempty"`
+	}
+	type Measured struct {
+		Name     string  `toon:"name"`
+		Distance float64 `toon:"distanceKm,unit=km"`
+		Gain     int     `toon:"elevationGain,omitempty,unit=m"`
+	}
+	type Measures struct {
+		Longest Measured   `toon:"longest"`
+		Hikes   []Measured `toon:"hikes"`
+	}
+	type Column struct {
+		Name       string  `toon:"name"`
+		DistanceKm float64 `toon:"distanceKm" toonCol:"dist_km"`
+	}
+	type Columns struct {
+		Best  Column   `toon:"best"`
+		Hikes []Column `toon:"hikes"`
+	}
+
+	testMarshalCases(t, []marshalCase{
+		{
+			name: "json tag options",
+			in:   Tagged{Name: "Lake", Km: 7, Done: true, Dash: "d"},
+			want: "name: Lake\nkm: \"7\"\ndone: \"true\"\n-: d\n",
+		},
+		{
+			// Units annotate table columns only; block keys keep their names
+			name: "units",
+			in: Measures{
+				Longest: Measured{Name: "Ridge", Distance: 9.2, Gain: 540},
+				Hikes:   []Measured{{Name: "Lake", Distance: 7.5, Gain: 320}, {Name: "Ridge", Distance: 9.2, Gain: 540}},
+			},
+			want: "longest:\n  name: Ridge\n  distanceKm: 9.2\n  elevationGain: 540\n" +
+				"hikes[2]{name,distanceKm(km),elevationGain(m)}:\n  Lake,7.5,320\n  Ridge,9.2,540\n",
+		},
+		{
+			name: "column names",
+			in: Columns{
+				Best:  Column{Name: "Ridge", DistanceKm: 9.2},
+				Hikes: []Column{{Name: "Lake", DistanceKm: 7.5}, {Name: "Loop", DistanceKm: 5.1}},
+			},
+			want: "best:\n  name: Ridge\n  distanceKm: 9.2\nhikes[2]{name,dist_km}:\n  Lake,7.5\n  Loop,5.1\n",
+		},
+	})
+
+	// Headers without the units or with the plain field name decode too
+	opts := toon.DefaultUnmarshalOptions()
+	opts.Strict = true
+	for _, tt := range []struct {
+		input string
+		want  any
+	}{
+		{"hikes[1]{name,distanceKm}:\n  Lake,7.5\n", Measures{Hikes: []Measured{{Name: "Lake", Distance: 7.5}}}},
+		{"hikes[1]{name,distanceKm}:\n  Lake,7.5\n", Columns{Hikes: []Column{{Name: "Lake", DistanceKm: 7.5}}}},
+	} {
+		out := reflect.New(reflect.TypeOf(tt.want))
+		if err := toon.UnmarshalWithOptions([]byte(tt.input), out.Interface(), opts); err != nil {
+			t.Fatalf("Unmarshal(%q) failed: %v", tt.input, err)
+		}
+		if !reflect.DeepEqual(out.Elem().Interface(), tt.want) {
+			t.Errorf("Unmarshal(%q) = %+v, want %+v", tt.input, out.Elem(), tt.want)
+		}
+	}
+}
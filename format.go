@@ -0,0 +1,173 @@
+package toon
+
+import (
+	"strings"
+)
+
+// Format rewrites a document in the canonical layout Marshal produces:
+// two spaces per indentation level, one space after each key's colon, no
+// spaces around delimiters, no trailing whitespace, single blank lines
+// and a final line break. Comments are kept, indented like the line after
+// them, and a "#crc32" footer is recomputed. Values, order and counts are
+// left as they are.
+func Format(data []byte) ([]byte, error) {
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &formatter{
+		doc:    doc,
+		unit:   DefaultMarshalOptions().Indent,
+		out:    make([]string, len(doc.lines)),
+		dashed: make(map[int]bool),
+		set:    make([]bool, len(doc.lines)),
+	}
+	f.nodes(doc.nodes, 0)
+	return f.bytes(), nil
+}
+
+type formatter struct {
+	doc  *Document
+	unit int
+
+	// out holds the formatted content lines, set marking which they are
+	out []string
+	set []bool
+
+	// dashed marks the lines of list items whose first field shares the
+	// dash line
+	dashed map[int]bool
+}
+
+func (f *formatter) write(line, indent int, text string) {
+	f.out[line] = strings.Repeat(" ", indent) + text
+	f.set[line] = true
+}
+
+// content returns the text of line without its indentation and, for list
+// items, without their dash.
+func (f *formatter) content(line int) string {
+	text := strings.TrimSpace(f.doc.lines[line])
+	if f.dashed[line] {
+		text = strings.TrimSpace(strings.TrimPrefix(text, "-"))
+	}
+	return text
+}
+
+func (f *formatter) nodes(nodes []*node, indent int) {
+	for _, n := range nodes {
+		f.node(n, indent)
+	}
+}
+
+func (f *formatter) node(n *node, indent int) {
+	key, value, _ := cutKeyValue(f.content(n.line))
+	key = strings.TrimSpace(key)
+
+	switch n.kind {
+	case scalarNode:
+		f.write(n.line, indent, key+": "+strings.TrimSpace(value))
+	case inlineNode:
+		f.write(n.line, indent, key+": "+f.cells(n))
+	default:
+		f.write(n.line, indent, key+":")
+	}
+
+	switch n.kind {
+	case blockNode:
+		f.nodes(n.children, indent+f.unit)
+	case tableNode:
+		sparse := n.fields[0] == sparseField
+		for _, row := range n.children {
+			text := strings.TrimSpace(f.doc.lines[row.line])
+			if !sparse {
+				text = f.cells(row)
+			}
+			f.write(row.line, indent+f.unit, text)
+		}
+	case listNode:
+		for _, item := range n.children {
+			f.item(item, indent+f.unit)
+		}
+	}
+}
+
+func (f *formatter) item(item *node, indent int) {
+	if len(item.children) == 0 {
+		f.dashed[item.line] = true
+		text := "-"
+		if content := f.content(item.line); content != "" {
+			text += " " + content
+		}
+		f.write(item.line, indent, text)
+		return
+	}
+
+	// The fields line up with the first one, which follows the dash
+	f.dashed[item.line] = true
+	f.nodes(item.children, indent+2)
+	f.out[item.line] = strings.Repeat(" ", indent) + "- " + strings.TrimLeft(f.out[item.line], " ")
+}
+
+// cells returns the cells of an inline array or table row joined by its
+// delimiter without surrounding spaces.
+func (f *formatter) cells(n *node) string {
+	cells := f.doc.cells(n)
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return strings.Join(cells, string(f.doc.delimiter(n)))
+}
+
+// bytes assembles the formatted lines with the comments between them.
+func (f *formatter) bytes() []byte {
+	var lines []string
+	var pending []string
+	blank, footer := false, false
+
+	// Comments take the indentation of the content line after them, so
+	// they are collected until it is known
+	flush := func(indent string) {
+		for _, comment := range pending {
+			if comment != "" {
+				comment = indent + comment
+			}
+			lines = append(lines, comment)
+		}
+		pending = pending[:0]
+	}
+
+	for i, line := range f.doc.lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case f.set[i]:
+			if blank && len(lines)+len(pending) > 0 {
+				pending = append(pending, "")
+			}
+			flush(leadingSpace(f.out[i]))
+			lines = append(lines, f.out[i])
+			blank = false
+		case isChecksumFooter(trimmed):
+			footer = true
+		case trimmed == "":
+			blank = true
+		default:
+			if blank && len(lines)+len(pending) > 0 {
+				pending = append(pending, "")
+			}
+			pending = append(pending, trimmed)
+			blank = false
+		}
+	}
+	flush("")
+
+	body := ""
+	if len(lines) > 0 {
+		body = strings.Join(lines, "\n") + "\n"
+	}
+	if footer {
+		body += string(appendChecksumFooter(nil, checksum([]byte(body))))
+	}
+	return []byte(body)
+}
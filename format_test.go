@@ -0,0 +1,64 @@
+package toon_test
+
+import (
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestFormat(t *testing.T) {
+	input := "#toon 1.0\n\n\ncontext:\n    task:Our hikes   \n    # where\n    location:  Boulder\r\n" +
+		"friends[3]: ana , luis,sam\nhikes[2]{id,name}:\n      1, Blue Lake\n      2,Ridge\n\n\n" +
+		"# items\nitems[2]:\n    - id: 1\n      name: One\n      tags[1]: x\n    - 5"
+	want := "#toon 1.0\n\ncontext:\n  task: Our hikes\n  # where\n  location: Boulder\n" +
+		"friends[3]: ana,luis,sam\nhikes[2]{id,name}:\n  1,Blue Lake\n  2,Ridge\n\n" +
+		"# items\nitems[2]:\n  - id: 1\n    name: One\n    tags[1]: x\n  - 5\n"
+
+	got, err := toon.Format([]byte(input))
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Format =\n%s\nwant\n%s", got, want)
+	}
+
+	again, err := toon.Format(got)
+	if err != nil || string(again) != string(got) {
+		t.Errorf("Format is not idempotent: %q, %v", again, err)
+	}
+}
+
+func TestFormatMarshalOutput(t *testing.T) {
+	hikes := HikesData{
+		Context: Context{Task: "Our favorite hikes together", Location: "Boulder", Season: "spring_2025"},
+		Friends: []string{"ana", "luis", "sam"},
+		Hikes: []Hike{
+			{ID: 1, Name: "Blue Lake Trail", DistanceKm: 7.5, ElevationGain: 320, Companion: "ana", WasSunny: true},
+			{ID: 2, Name: "Ridge Overlook", DistanceKm: 9.2, ElevationGain: 540, Companion: "luis", WasSunny: false},
+		},
+	}
+
+	data, err := toon.Marshal(hikes, toon.WithIndent(4), toon.WithChecksum(true))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	canonical, err := toon.Marshal(hikes, toon.WithChecksum(true))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	got, err := toon.Format(data)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if string(got) != string(canonical) {
+		t.Errorf("Format =\n%s\nwant\n%s", got, canonical)
+	}
+
+	opts := toon.DefaultUnmarshalOptions()
+	opts.VerifyChecksum = true
+	var decoded HikesData
+	if err := toon.UnmarshalWithOptions(got, &decoded, opts); err != nil {
+		t.Errorf("formatted checksum does not verify: %v", err)
+	}
+}
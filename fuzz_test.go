@@ -0,0 +1,115 @@
+package toon_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+	"github.com/l00pss/gotoon/corpus"
+)
+
+var fuzzSeeds = []string{
+	"name: Alice\nage: 30\n",
+	"context:\n  task: Our favorite hikes together\n  location: Boulder\n",
+	"friends[3]: ana,luis,sam\n",
+	"hikes[2]{id,name,distanceKm,elevationGain,companion,wasSunny}:\n  1,Blue Lake Trail,7.5,320,ana,true\n  2,Ridge Overlook,9.2,540,luis,false\n",
+	"items[2]:\n  - id: 1\n    name: One\n  - id: 2\n    name: Two\n",
+	"hikes[99999999999999999999]{id}:\n  1\n",
+	"hikes[2]{\"id\nname}:\n  1\n",
+	"\u3000\u3000name: wide\n\tage:\t30\n",
+	"friends[-1]: a\n",
+	"- \n- -\n:\n[]:\n",
+	"context[2]: a,b\nhikes[1]:\n  - name[2]: a,b\n    id[1]{x}:\n      1\n",
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	for _, sample := range corpus.Samples() {
+		f.Add(sample.Input)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var data1 HikesData
+		_ = toon.Unmarshal(data, &data1)
+
+		var data2 map[string]any
+		_ = toon.Unmarshal(data, &data2)
+
+		var data3 any
+		_ = toon.Unmarshal(data, &data3)
+
+		var data4 []Hike
+		_ = toon.Unmarshal(data, &data4)
+
+		strict := toon.DefaultUnmarshalOptions()
+		strict.Strict = true
+		var data5 HikesData
+		_ = toon.UnmarshalWithOptions(data, &data5, strict)
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add("Blue Lake Trail", 7.5, int64(320), true)
+	f.Add("a,\"b\"|c;\n", -0.0, int64(-1), false)
+
+	f.Fuzz(func(t *testing.T, name string, distance float64, gain int64, sunny bool) {
+		original := Hike{Name: name, DistanceKm: distance, ElevationGain: int(gain), WasSunny: sunny}
+
+		data, err := toon.Marshal(original)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+
+		var decoded Hike
+		if err := toon.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal failed: %v\n%s", err, data)
+		}
+		if decoded.Name != original.Name || decoded.ElevationGain != original.ElevationGain || decoded.WasSunny != original.WasSunny {
+			t.Errorf("Round trip mismatch: %+v != %+v\n%s", decoded, original, data)
+		}
+		if distance == distance && decoded.DistanceKm != distance {
+			t.Errorf("Distance mismatch: %v != %v", decoded.DistanceKm, distance)
+		}
+	})
+}
+
+func TestUnmarshalPanicBecomesSyntaxError(t *testing.T) {
+	input := "name: Alice\nvalue: 42\n"
+
+	var result struct {
+		Name  string       `toon:"name"`
+		Value fmt.Stringer `toon:"value"`
+	}
+
+	err := toon.Unmarshal([]byte(input), &result)
+	var syntaxErr *toon.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Expected SyntaxError, got %v", err)
+	}
+	if syntaxErr.Line != 2 {
+		t.Errorf("Expected error on line 2, got %d", syntaxErr.Line)
+	}
+	if !strings.HasSuffix(syntaxErr.Message, "fmt.Stringer at value") {
+		t.Errorf("Message = %q, want the type and path", syntaxErr.Message)
+	}
+
+	// Panics outside reflect are bugs and are not turned into errors
+	defer func() {
+		if recover() == nil {
+			t.Error("Unmarshal recovered a panic from UnmarshalTOON")
+		}
+	}()
+	var trip struct {
+		Mood panicky `toon:"mood"`
+	}
+	_ = toon.Unmarshal([]byte("mood: calm\n"), &trip)
+}
+
+// panicky panics when it is decoded.
+type panicky struct{}
+
+func (*panicky) UnmarshalTOON([]byte) error { panic("panicky") }
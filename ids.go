@@ -0,0 +1,98 @@
+package toon
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// idLegendKey is the key of the table ShortenIDs adds to map references
+// back to identifiers.
+const idLegendKey = "_ids"
+
+var (
+	// longIDPattern matches the identifiers ShortenIDs replaces: UUIDs and
+	// hex strings of at least 16 digits, such as hashes.
+	longIDPattern = regexp.MustCompile(`^(?:[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+	// idRefPattern matches the references written in their place.
+	idRefPattern = regexp.MustCompile(`^@[1-9][0-9]*$`)
+)
+
+// idTable numbers the identifiers replaced while encoding a document.
+type idTable struct {
+	refs map[string]string
+	ids  []string
+}
+
+// ref returns the reference for id, numbering it if it is new.
+func (t *idTable) ref(id string) string {
+	if ref, ok := t.refs[id]; ok {
+		return ref
+	}
+	t.ids = append(t.ids, id)
+	ref := "@" + strconv.Itoa(len(t.ids))
+	t.refs[id] = ref
+	return ref
+}
+
+// writeIDLegend inserts the table of shortened identifiers at offset start
+// of the output, ahead of the values that refer to them.
+func (e *encoder) writeIDLegend(start int) {
+	if len(e.ids.ids) == 0 {
+		return
+	}
+	body := append([]byte(nil), e.buf.Bytes()[start:]...)
+	e.buf.Truncate(start)
+
+	// The identifiers themselves must not be shortened
+	ids := e.ids
+	e.ids = nil
+	e.writeHeader(e.prefixKey(0, idLegendKey), len(ids.ids))
+	e.writeHeaderFields([]string{"ref", "id"})
+	for _, id := range ids.ids {
+		e.writeIndent(1)
+		e.buf.WriteString(ids.refs[id] + string(e.opts.Delimiter))
+		e.writeString(id)
+		e.buf.WriteByte('\n')
+	}
+	e.ids = ids
+	e.buf.Write(body)
+}
+
+// readIDLegend reads the table of shortened identifiers into d.ids and
+// blanks its lines, so that it is not decoded as a key.
+func (d *decoder) readIDLegend() {
+	for i, line := range d.lines {
+		if !strings.HasPrefix(line, idLegendKey+"[") {
+			continue
+		}
+		key, _, _ := cutKeyValue(line)
+		length, fields, delim := d.parseArrayDeclaration(key)
+		if length == notArray || !reflect.DeepEqual(fields, []string{"ref", "id"}) {
+			continue
+		}
+
+		d.ids = make(map[string]string)
+		d.lines[i] = ""
+		restore := d.declareDelimiter(delim)
+		for j := i + 1; j < len(d.lines) && d.getIndent(d.lines[j]) > 0; j++ {
+			if cells := d.splitValues(strings.TrimSpace(d.lines[j])); len(cells) == 2 {
+				d.ids[strings.TrimSpace(cells[0])] = unquote(strings.TrimSpace(cells[1]))
+			}
+			d.lines[j] = ""
+		}
+		restore()
+		return
+	}
+}
+
+// resolveID returns the identifier raw refers to as a quoted string, or
+// raw when it is not a reference.
+func (d *decoder) resolveID(raw string) string {
+	if id, ok := d.ids[strings.TrimSpace(raw)]; ok {
+		return quoteString(id)
+	}
+	return raw
+}
@@ -0,0 +1,66 @@
+package toon_test
+
+import (
+	"reflect"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestShortenIDs(t *testing.T) {
+	type Event struct {
+		ID     string `toon:"id"`
+		Parent string `toon:"parent"`
+		Note   string `toon:"note"`
+	}
+	type Log struct {
+		Owner  string  `toon:"owner"`
+		Events []Event `toon:"events"`
+	}
+
+	const user = "3f2a6c1e-9b7d-4e2a-8c1f-0d9e8b7a6c5d"
+	const commit = "a94a8fe5ccb19ba61c4c0873d391e987982fbbd3"
+	in := Log{
+		Owner: user,
+		Events: []Event{
+			{ID: commit, Parent: user, Note: "@1"},
+			{ID: user, Parent: commit, Note: "short"},
+		},
+	}
+
+	data, err := toon.Marshal(in, toon.WithShortenIDs(true), toon.WithVersionHeader(true))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := "#toon 1.0\n_ids[2]{ref,id}:\n  @1," + user + "\n  @2," + commit + "\n" +
+		"owner: @1\nevents[2]{id,parent,note}:\n  @2,@1,\"@1\"\n  @1,@2,short\n"
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+
+	opts := toon.DefaultUnmarshalOptions()
+	opts.ResolveIDs = true
+	opts.Strict = true
+	var out Log
+	if err := toon.UnmarshalWithOptions(data, &out, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+
+	// Without ResolveIDs the references are read as they are
+	out = Log{}
+	if err := toon.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Owner != "@1" || out.Events[0].Note != "@1" {
+		t.Errorf("Unmarshal without ResolveIDs = %+v", out)
+	}
+
+	// Documents without identifiers get no table
+	data, err = toon.Marshal(Log{Owner: "ana"}, toon.WithShortenIDs(true))
+	if err != nil || string(data) != "owner: ana\nevents[0]:\n" {
+		t.Errorf("Marshal without identifiers = %q, %v", data, err)
+	}
+}
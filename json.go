@@ -0,0 +1,157 @@
+package toon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+)
+
+// FromJSON converts a JSON document, whether an object, an array or a
+// scalar, into TOON without a Go type to decode it into. Object keys keep
+// their order and numbers their spelling, and arrays of objects that hold
+// the same keys in the same order, with scalar values only, become
+// tables. Keys that cannot be written bare, such as those holding a colon
+// or brackets, are rejected.
+func FromJSON(jsonData []byte, opts MarshalOptions) ([]byte, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.UseNumber()
+	v, err := readJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("toon: invalid JSON: data after the top-level value")
+	}
+	return newEncoder(opts).encode(v)
+}
+
+// ToJSON converts a TOON document into JSON without a Go type to decode it
+// into, for systems downstream of model output that only read JSON. Keys
+// keep their order, tables and lists become arrays of objects, tables
+// keyed by a _key column objects, and scalars take the JSON type decoding
+// into any gives them. A document holding only a root array or scalar
+// converts to that value, and an empty document to {}. Numbers keep their
+// spelling, so integers beyond the precision of float64 survive.
+func ToJSON(toonData []byte) ([]byte, error) {
+	d := newDecoder(toonData, DefaultUnmarshalOptions())
+	d.ordered = true
+	d.numbers = true
+	var v any
+	err := d.run(func() error {
+		var err error
+		v, err = d.decodeDynamic(0)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// readJSON reads the next JSON value from dec, objects as OrderedMaps and
+// arrays as []any.
+func readJSON(dec *json.Decoder) (any, error) {
+	tok, err := readJSONToken(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		m := OrderedMap{}
+		for dec.More() {
+			tok, err := readJSONToken(dec)
+			if err != nil {
+				return nil, err
+			}
+			key := tok.(string)
+			if !isFlatKey(key) {
+				return nil, fmt.Errorf("toon: cannot write %q as a key", key)
+			}
+			value, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, KeyValue{Key: key, Value: value})
+		}
+		_, err := readJSONToken(dec)
+		return m, err
+	case json.Delim('['):
+		a := []any{}
+		for dec.More() {
+			value, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, value)
+		}
+		_, err := readJSONToken(dec)
+		return a, err
+	}
+	return tok, nil
+}
+
+// readJSONToken reads the next token from dec, where the input ending is
+// an error too.
+func readJSONToken(dec *json.Decoder) (json.Token, error) {
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("toon: invalid JSON: %w", err)
+	}
+	return tok, nil
+}
+
+// encodeRawMessage writes the JSON held in v as native TOON structure
+// rather than as bytes.
+func (e *encoder) encodeRawMessage(v reflect.Value, depth int, key string) error {
+	raw := v.Bytes()
+	if len(raw) == 0 {
+		raw = []byte("null")
+	}
+
+	var parsed any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&parsed); err != nil {
+		return fmt.Errorf("toon: invalid json.RawMessage at %s: %w", e.pathString(), err)
+	}
+
+	return e.encodeValue(reflect.ValueOf(&parsed).Elem(), depth, key)
+}
+
+// decodeRawMessage decodes the section for v dynamically and stores it
+// back as JSON. value is the text after the key's colon.
+func (d *decoder) decodeRawMessage(v reflect.Value, value string, indent int) error {
+	var parsed any
+	pv := reflect.ValueOf(&parsed).Elem()
+
+	if value == "" {
+		if err := d.decodeValue(pv, indent+2); err != nil {
+			return err
+		}
+	} else if err := d.setValue(pv, value); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(parsed)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(json.RawMessage(raw)))
+	return nil
+}
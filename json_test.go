@@ -0,0 +1,191 @@
+package toon_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestRawMessageFields(t *testing.T) {
+	type Event struct {
+		Kind    string          `toon:"kind"`
+		Payload json.RawMessage `toon:"payload"`
+		Extra   json.RawMessage `toon:"extra"`
+	}
+
+	in := Event{
+		Kind:    "click",
+		Payload: json.RawMessage(`{"x":10,"label":"ok","nested":{"flag":true}}`),
+		Extra:   json.RawMessage(`"007"`),
+	}
+
+	data, err := toon.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := "kind: click\npayload:\n  label: ok\n  nested:\n    flag: true\n  x: 10\nextra: \"007\"\n"
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+
+	var out Event
+	if err := toon.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Kind != "click" {
+		t.Errorf("Kind = %q, want click", out.Kind)
+	}
+	if string(out.Payload) != `{"label":"ok","nested":{"flag":true},"x":10}` {
+		t.Errorf("Payload = %s", out.Payload)
+	}
+	if string(out.Extra) != `"007"` {
+		t.Errorf("Extra = %s", out.Extra)
+	}
+
+	if _, err := toon.Marshal(Event{Payload: json.RawMessage(`{bad`)}); err == nil {
+		t.Error("expected error for invalid json.RawMessage")
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	input := `{
+		"owner": "ana",
+		"hikes": [
+			{"name": "Blue Lake", "km": 7.5, "sunny": true},
+			{"name": "Ridge, north", "km": 9.0, "sunny": false}
+		],
+		"tags": ["lake", "ridge"],
+		"stops": [{"name": "hut"}, {"name": "peak", "height": 2100}],
+		"meta": {"version": 2, "notes": null}
+	}`
+	data, err := toon.FromJSON([]byte(input), toon.DefaultMarshalOptions())
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+
+	// Keys keep their order, uniform objects become a table, others a list
+	want := `owner: ana
+hikes[2]{name,km,sunny}:
+  Blue Lake,7.5,true
+  "Ridge, north",9.0,false
+tags[2]: lake,ridge
+stops[2]:
+  - name: hut
+  - name: peak
+    height: 2100
+meta:
+  version: 2
+  notes: null
+`
+	if string(data) != want {
+		t.Fatalf("FromJSON = %q, want %q", data, want)
+	}
+
+	for input, want := range map[string]string{
+		`[{"a":1,"b":"x"},{"a":2,"b":"y"}]`: "[2]{a,b}:\n  1,x\n  2,y\n",
+		`[1,"two",null]`:                    "[3]: 1,two,null\n",
+		`"hello"`:                           "hello\n",
+		`42`:                                "42\n",
+	} {
+		data, err := toon.FromJSON([]byte(input), toon.DefaultMarshalOptions())
+		if err != nil || string(data) != want {
+			t.Errorf("FromJSON(%s) = %q, %v, want %q", input, data, err, want)
+		}
+	}
+
+	opts := toon.DefaultMarshalOptions()
+	opts.Delimiter = toon.DelimiterTab
+	opts.MinTabularRows = 3
+	data, err = toon.FromJSON([]byte(`{"a":[{"x":1},{"x":2}],"b":[{"x":1},{"x":2},{"x":3}]}`), opts)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+	if want := "a[2\t]:\n  - x: 1\n  - x: 2\nb[3\t]{x}:\n  1\n  2\n  3\n"; string(data) != want {
+		t.Errorf("FromJSON = %q, want %q", data, want)
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	for _, input := range []string{``, `{"a":`, `{"a":1} {}`, `[1,]`, `{"a:b":1}`, `{"a=b":1}`} {
+		if _, err := toon.FromJSON([]byte(input), toon.DefaultMarshalOptions()); err == nil {
+			t.Errorf("FromJSON(%q) succeeded", input)
+		}
+	}
+
+	opts := toon.DefaultMarshalOptions()
+	opts.Indent = 0
+	if _, err := toon.FromJSON([]byte(`{}`), opts); !errors.Is(err, toon.ErrInvalidOptions) {
+		t.Errorf("err = %v, want ErrInvalidOptions", err)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	input := `#toon 1.0
+owner: ana
+hikes[2]{name,km,sunny}:
+  Blue Lake,7.5,true
+  "Ridge, north",9,false
+tags[2|]: lake|"007"
+stops[2]:
+  - name: hut
+    at:
+      km: 3
+  - [2]: 1,2
+byName[1]{_key,km}:
+  blue,7.5
+meta:
+  notes: null
+  empty[0]:
+`
+	data, err := toon.ToJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	want := `{"owner":"ana","hikes":[{"name":"Blue Lake","km":7.5,"sunny":true},{"name":"Ridge, north","km":9,"sunny":false}],` +
+		`"tags":["lake","007"],"stops":[{"name":"hut","at":{"km":3}},[1,2]],"byName":{"blue":{"km":7.5}},"meta":{"notes":null,"empty":[]}}`
+	if string(data) != want {
+		t.Fatalf("ToJSON = %s, want %s", data, want)
+	}
+
+	for input, want := range map[string]string{
+		"":                             `{}`,
+		"[2]{a,b}:\n  1,x\n  2,y\n":    `[{"a":1,"b":"x"},{"a":2,"b":"y"}]`,
+		"[3]: 1,two,null\n":            `[1,"two",null]`,
+		"hello\n":                      `"hello"`,
+		"host = example.com\n":         `{"host":"example.com"}`,
+		"name: Infinity\n":             `{"name":"Infinity"}`,
+		"n: 12345678901234567890123\n": `{"n":12345678901234567890123}`,
+		"[2]: 1.50,-0\n":               `[1.50,-0]`,
+	} {
+		data, err := toon.ToJSON([]byte(input))
+		if err != nil || string(data) != want {
+			t.Errorf("ToJSON(%q) = %s, %v, want %s", input, data, err, want)
+		}
+	}
+
+	// JSON survives a round trip through TOON
+	for _, doc := range []string{
+		`{"z":1,"a":[{"x":1.5,"y":"s"},{"x":2,"y":null}],"l":[[1],{"k":true}],"e":{}}`,
+		`["x:y","u: v","p=q"]`,
+		`{"l":["x:y","u: v","p=q",{"k":1}]}`,
+	} {
+		encoded, err := toon.FromJSON([]byte(doc), toon.DefaultMarshalOptions())
+		if err != nil {
+			t.Fatalf("FromJSON failed: %v", err)
+		}
+		if data, err := toon.ToJSON(encoded); err != nil || string(data) != doc {
+			t.Errorf("ToJSON(FromJSON(%s)) = %s, %v", doc, data, err)
+		}
+	}
+}
+
+func TestToJSONErrors(t *testing.T) {
+	for _, input := range []string{"t[1]{=}:\n  a\n", "#toon 9.0\na: 1\n"} {
+		var syntaxErr *toon.SyntaxError
+		if _, err := toon.ToJSON([]byte(input)); !errors.As(err, &syntaxErr) {
+			t.Errorf("ToJSON(%q): err = %v, want a *SyntaxError", input, err)
+		}
+	}
+}
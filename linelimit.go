@@ -0,0 +1,55 @@
+package toon
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// lineTooLong returns the error for line, numbered from 1 and starting
+// at byte offset, exceeding MaxLineBytes. text holds at least its first
+// limit bytes.
+func lineTooLong(line, offset, limit int, text string) error {
+	return &SyntaxError{
+		Line:    line,
+		Column:  limit + 1,
+		Offset:  offset + limit,
+		Message: fmt.Sprintf("line is longer than MaxLineBytes (%d bytes)", limit),
+		Content: strings.TrimLeft(text[:limit], " \t"),
+		Err:     ErrLineTooLong,
+	}
+}
+
+// checkLineLengths rejects the input when a line is longer than
+// MaxLineBytes.
+func (d *decoder) checkLineLengths() error {
+	for i, line := range d.lines {
+		if len(strings.TrimSuffix(line, "\r")) > d.opts.MaxLineBytes {
+			return lineTooLong(d.lineBase+i+1, d.offsetBase+d.offsets[i], d.opts.MaxLineBytes, line)
+		}
+	}
+	return nil
+}
+
+// readLine reads the next line of r, including its line break, growing
+// its buffer for lines longer than that of r. When limit is positive, a
+// line holding more than limit bytes besides its line break fails with
+// ErrLineTooLong as soon as that many have been read, so the rest of it
+// is never buffered; n is the number of the line in the input and offset
+// the byte at which it starts. The error is io.EOF when the input ends,
+// possibly after an unterminated line.
+func readLine(r *bufio.Reader, limit, n, offset int) (string, error) {
+	var buf []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		buf = append(buf, chunk...)
+		if limit > 0 && len(bytes.TrimSuffix(bytes.TrimSuffix(buf, []byte("\n")), []byte("\r"))) > limit {
+			return "", lineTooLong(n, offset, limit, string(buf))
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return string(buf), err
+		}
+	}
+}
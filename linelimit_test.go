@@ -0,0 +1,106 @@
+package toon_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestLongLines(t *testing.T) {
+	type Series struct {
+		Name   string `toon:"name"`
+		Values []int  `toon:"values"`
+	}
+	in := Series{Name: "samples", Values: make([]int, 200000)}
+	for i := range in.Values {
+		in.Values[i] = i * 31
+	}
+	data, err := toon.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if lines := strings.Split(string(data), "\n"); len(lines[1]) < 1<<20 {
+		t.Fatalf("values line has %d bytes, want over 1MB", len(lines[1]))
+	}
+
+	var out Series
+	if err := toon.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("Unmarshal lost values: got %d", len(out.Values))
+	}
+
+	out = Series{}
+	if err := toon.NewDecoder(bytes.NewReader(data)).Decode(&out); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("Decode lost values: got %d", len(out.Values))
+	}
+
+	var buf bytes.Buffer
+	tr, err := toon.NewTranscoder(toon.DefaultUnmarshalOptions(), toon.DefaultMarshalOptions())
+	if err != nil {
+		t.Fatalf("NewTranscoder failed: %v", err)
+	}
+	if err := tr.Transcode(&buf, bytes.NewReader(data)); err != nil {
+		t.Fatalf("Transcode failed: %v", err)
+	}
+	if buf.String() != string(data) {
+		t.Fatal("Transcode changed the document")
+	}
+}
+
+func TestMaxLineBytes(t *testing.T) {
+	data := []byte("name: samples\r\nvalues[5]: 1,2,3,4,5\r\nlast: x\r\n")
+	opts := toon.DefaultUnmarshalOptions()
+	opts.MaxLineBytes = len("values[5]: 1,2,3,4,5")
+
+	// Line breaks, \r\n included, do not count
+	var out map[string]any
+	if err := toon.UnmarshalWithOptions(data, &out, opts); err != nil {
+		t.Fatalf("Unmarshal at the limit failed: %v", err)
+	}
+	dec := toon.NewDecoder(bytes.NewReader(data))
+	dec.SetOptions(opts)
+	if err := dec.Decode(&out); err != nil {
+		t.Fatalf("Decode at the limit failed: %v", err)
+	}
+
+	opts.MaxLineBytes--
+	const want = "toon: syntax error at line 2, column 20: line is longer than MaxLineBytes (19 bytes)"
+	check := func(name string, err error) {
+		t.Helper()
+		var syntaxErr *toon.SyntaxError
+		if !errors.Is(err, toon.ErrLineTooLong) || !errors.As(err, &syntaxErr) {
+			t.Fatalf("%s: err = %v, want a SyntaxError wrapping ErrLineTooLong", name, err)
+		}
+		if err.Error() != want {
+			t.Errorf("%s: err = %q, want %q", name, err, want)
+		}
+		if syntaxErr.Offset != 34 || syntaxErr.Content != "values[5]: 1,2,3,4," {
+			t.Errorf("%s: Offset = %d, Content = %q", name, syntaxErr.Offset, syntaxErr.Content)
+		}
+	}
+
+	check("Unmarshal", toon.UnmarshalWithOptions(data, &out, opts))
+
+	// Both the streaming path and the one reading the input whole
+	var values []string
+	for _, v := range []any{&out, &values} {
+		dec := toon.NewDecoder(bytes.NewReader(data))
+		dec.SetOptions(opts)
+		check("Decode", dec.Decode(v))
+	}
+
+	tr, err := toon.NewTranscoder(opts, toon.DefaultMarshalOptions())
+	if err != nil {
+		t.Fatalf("NewTranscoder failed: %v", err)
+	}
+	check("Transcode", tr.Transcode(&bytes.Buffer{}, bytes.NewReader(data)))
+}
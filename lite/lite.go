@@ -0,0 +1,312 @@
+// Package lite writes TOON documents without reflection, for TinyGo and
+// other constrained targets where the encoder of the main package does not
+// fit or does not build. Callers write each key and value explicitly; the
+// output matches what the main package's Marshal produces for the same
+// data with default options.
+//
+//	var w lite.Writer
+//	w.String("device", "probe-7")
+//	w.Table("readings", len(readings), "t", "temp")
+//	for _, r := range readings {
+//		w.Row()
+//		w.IntCell(r.T)
+//		w.FloatCell(r.Temp)
+//	}
+//	w.End()
+//	send(w.Bytes())
+//
+// The package imports only strconv and strings.
+package lite
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Writer appends a TOON document to an internal buffer. The zero value
+// writes with two-space indentation and comma delimiters; set Indent and
+// Delimiter before the first write to change them.
+type Writer struct {
+	Indent    int
+	Delimiter byte
+
+	buf   []byte
+	depth int
+
+	// cells is the number of cells written on the open table row, if any
+	cells int
+	open  bool
+}
+
+// NewWriter returns a Writer appending to buf, so callers can reuse
+// memory across documents.
+func NewWriter(buf []byte) *Writer {
+	return &Writer{buf: buf[:0]}
+}
+
+// Bytes returns the document written so far.
+func (w *Writer) Bytes() []byte {
+	w.endRow()
+	return w.buf
+}
+
+// Reset discards the document, keeping the buffer and settings.
+func (w *Writer) Reset() {
+	w.buf = w.buf[:0]
+	w.depth = 0
+	w.open = false
+}
+
+func (w *Writer) String(key, v string) {
+	w.key(key)
+	w.buf = append(w.buf, ' ')
+	w.appendString(v)
+	w.buf = append(w.buf, '\n')
+}
+
+func (w *Writer) Int(key string, v int64) {
+	w.key(key)
+	w.buf = append(w.buf, ' ')
+	w.buf = strconv.AppendInt(w.buf, v, 10)
+	w.buf = append(w.buf, '\n')
+}
+
+func (w *Writer) Uint(key string, v uint64) {
+	w.key(key)
+	w.buf = append(w.buf, ' ')
+	w.buf = strconv.AppendUint(w.buf, v, 10)
+	w.buf = append(w.buf, '\n')
+}
+
+func (w *Writer) Float(key string, v float64) {
+	w.key(key)
+	w.buf = append(w.buf, ' ')
+	w.buf = strconv.AppendFloat(w.buf, v, 'g', -1, 64)
+	w.buf = append(w.buf, '\n')
+}
+
+func (w *Writer) Bool(key string, v bool) {
+	w.key(key)
+	w.buf = append(w.buf, ' ')
+	w.buf = strconv.AppendBool(w.buf, v)
+	w.buf = append(w.buf, '\n')
+}
+
+func (w *Writer) Null(key string) {
+	w.key(key)
+	w.buf = append(w.buf, " null\n"...)
+}
+
+// Begin starts a nested block under key. Keys written until the matching
+// End belong to it.
+func (w *Writer) Begin(key string) {
+	w.key(key)
+	w.buf = append(w.buf, '\n')
+	w.depth++
+}
+
+// End closes the innermost block or table.
+func (w *Writer) End() {
+	w.endRow()
+	if w.depth > 0 {
+		w.depth--
+	}
+}
+
+// Strings writes v as an inline array.
+func (w *Writer) Strings(key string, v []string) {
+	w.header(key, len(v))
+	for i, s := range v {
+		w.separate(i)
+		w.appendString(s)
+	}
+	w.buf = append(w.buf, '\n')
+}
+
+// Ints writes v as an inline array.
+func (w *Writer) Ints(key string, v []int64) {
+	w.header(key, len(v))
+	for i, n := range v {
+		w.separate(i)
+		w.buf = strconv.AppendInt(w.buf, n, 10)
+	}
+	w.buf = append(w.buf, '\n')
+}
+
+// Floats writes v as an inline array.
+func (w *Writer) Floats(key string, v []float64) {
+	w.header(key, len(v))
+	for i, f := range v {
+		w.separate(i)
+		w.buf = strconv.AppendFloat(w.buf, f, 'g', -1, 64)
+	}
+	w.buf = append(w.buf, '\n')
+}
+
+// Table starts a table of rows rows with the given columns. Write each
+// row with Row followed by one cell per column, then close the table with
+// End.
+func (w *Writer) Table(key string, rows int, columns ...string) {
+	w.line()
+	w.buf = append(w.buf, key...)
+	w.buf = append(w.buf, '[')
+	w.buf = strconv.AppendInt(w.buf, int64(rows), 10)
+	w.hint()
+	w.buf = append(w.buf, "]{"...)
+	for i, column := range columns {
+		w.separate(i)
+		if needsQuoting(column) || strings.ContainsAny(column, "{}") {
+			w.buf = appendQuoted(w.buf, column)
+		} else {
+			w.buf = append(w.buf, column...)
+		}
+	}
+	w.buf = append(w.buf, "}:\n"...)
+	w.depth++
+}
+
+// Row starts the next row of the current table.
+func (w *Writer) Row() {
+	w.line()
+	w.open = true
+	w.cells = 0
+}
+
+func (w *Writer) StringCell(v string) {
+	w.cell()
+	w.appendString(v)
+}
+
+func (w *Writer) IntCell(v int64) {
+	w.cell()
+	w.buf = strconv.AppendInt(w.buf, v, 10)
+}
+
+func (w *Writer) UintCell(v uint64) {
+	w.cell()
+	w.buf = strconv.AppendUint(w.buf, v, 10)
+}
+
+func (w *Writer) FloatCell(v float64) {
+	w.cell()
+	w.buf = strconv.AppendFloat(w.buf, v, 'g', -1, 64)
+}
+
+func (w *Writer) BoolCell(v bool) {
+	w.cell()
+	w.buf = strconv.AppendBool(w.buf, v)
+}
+
+// NullCell writes a blank cell, which decodes as the zero value.
+func (w *Writer) NullCell() {
+	w.cell()
+}
+
+func (w *Writer) cell() {
+	if w.cells > 0 {
+		w.buf = append(w.buf, w.delimiter())
+	}
+	w.cells++
+}
+
+func (w *Writer) separate(i int) {
+	if i > 0 {
+		w.buf = append(w.buf, w.delimiter())
+	}
+}
+
+// hint declares a delimiter other than the implied comma in a header.
+func (w *Writer) hint() {
+	if delim := w.delimiter(); delim != ',' {
+		w.buf = append(w.buf, delim)
+	}
+}
+
+func (w *Writer) key(key string) {
+	w.line()
+	w.buf = append(w.buf, key...)
+	w.buf = append(w.buf, ':')
+}
+
+func (w *Writer) header(key string, length int) {
+	w.line()
+	w.buf = append(w.buf, key...)
+	w.buf = append(w.buf, '[')
+	w.buf = strconv.AppendInt(w.buf, int64(length), 10)
+	w.hint()
+	w.buf = append(w.buf, "]:"...)
+	if length > 0 {
+		w.buf = append(w.buf, ' ')
+	}
+}
+
+// line ends an open table row and indents the next line.
+func (w *Writer) line() {
+	w.endRow()
+	indent := w.Indent
+	if indent <= 0 {
+		indent = 2
+	}
+	for i := 0; i < w.depth*indent; i++ {
+		w.buf = append(w.buf, ' ')
+	}
+}
+
+func (w *Writer) endRow() {
+	if w.open {
+		w.buf = append(w.buf, '\n')
+		w.open = false
+	}
+}
+
+func (w *Writer) delimiter() byte {
+	if w.Delimiter == 0 {
+		return ','
+	}
+	return w.Delimiter
+}
+
+func (w *Writer) appendString(s string) {
+	if needsQuoting(s) {
+		w.buf = appendQuoted(w.buf, s)
+	} else {
+		w.buf = append(w.buf, s...)
+	}
+}
+
+// needsQuoting mirrors the default quoting rules of the main package.
+func needsQuoting(s string) bool {
+	return s == "" ||
+		strings.ContainsAny(s, ",|\t;\"\n\r") ||
+		strings.TrimSpace(s) != s ||
+		strings.HasPrefix(s, "#") || strings.Contains(s, " #") ||
+		looksLikeLiteral(s)
+}
+
+func looksLikeLiteral(s string) bool {
+	switch s {
+	case "null", "true", "false":
+		return true
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+func appendQuoted(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '"':
+			buf = append(buf, '\\', c)
+		case '\n':
+			buf = append(buf, `\n`...)
+		case '\r':
+			buf = append(buf, `\r`...)
+		case '\t':
+			buf = append(buf, `\t`...)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,140 @@
+package lite_test
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+	"github.com/l00pss/gotoon/lite"
+)
+
+type reading struct {
+	T    int64   `toon:"t"`
+	Temp float64 `toon:"temp"`
+	Note string  `toon:"note"`
+	OK   bool    `toon:"ok"`
+}
+
+type telemetry struct {
+	Device   string  `toon:"device"`
+	Uptime   uint64  `toon:"uptime"`
+	Battery  float64 `toon:"battery"`
+	Charging bool    `toon:"charging"`
+	Location struct {
+		Lat float64 `toon:"lat"`
+		Lon float64 `toon:"lon"`
+	} `toon:"location"`
+	Tags     []string  `toon:"tags"`
+	Codes    []int64   `toon:"codes"`
+	Samples  []float64 `toon:"samples"`
+	Readings []reading `toon:"readings"`
+	Errors   []string  `toon:"errors"`
+}
+
+func TestWriterMatchesMarshal(t *testing.T) {
+	v := telemetry{
+		Device:   "probe-7",
+		Uptime:   86400,
+		Battery:  0.82,
+		Charging: true,
+		Tags:     []string{"north", "a,b", "42", "", "#7", "c#"},
+		Codes:    []int64{3, -1},
+		Samples:  []float64{1.5, 2e-7},
+		Readings: []reading{
+			{T: 1, Temp: 21.5, Note: "ok # fine", OK: true},
+			{T: 2, Temp: -3, Note: "say \"hi\"\n", OK: false},
+		},
+		Errors: []string{},
+	}
+	v.Location.Lat = 40.015
+	v.Location.Lon = -105.27
+
+	want, err := toon.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var w lite.Writer
+	w.String("device", v.Device)
+	w.Uint("uptime", v.Uptime)
+	w.Float("battery", v.Battery)
+	w.Bool("charging", v.Charging)
+	w.Begin("location")
+	w.Float("lat", v.Location.Lat)
+	w.Float("lon", v.Location.Lon)
+	w.End()
+	w.Strings("tags", v.Tags)
+	w.Ints("codes", v.Codes)
+	w.Floats("samples", v.Samples)
+	w.Table("readings", len(v.Readings), "t", "temp", "note", "ok")
+	for _, r := range v.Readings {
+		w.Row()
+		w.IntCell(r.T)
+		w.FloatCell(r.Temp)
+		w.StringCell(r.Note)
+		w.BoolCell(r.OK)
+	}
+	w.End()
+	w.Strings("errors", v.Errors)
+
+	if got := string(w.Bytes()); got != string(want) {
+		t.Errorf("Writer =\n%s\nMarshal =\n%s", got, want)
+	}
+
+	var decoded telemetry
+	if err := toon.Unmarshal(w.Bytes(), &decoded); err != nil {
+		t.Errorf("Unmarshal failed: %v", err)
+	}
+}
+
+// TestImports keeps the package free of reflection, directly or through
+// packages such as fmt.
+func TestImports(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := map[string]bool{"strconv": true, "strings": true}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, spec := range f.Imports {
+			if path, _ := strconv.Unquote(spec.Path.Value); !allowed[path] {
+				t.Errorf("%s imports %s", file, path)
+			}
+		}
+	}
+}
+
+func TestWriterOptions(t *testing.T) {
+	w := lite.NewWriter(make([]byte, 0, 64))
+	w.Indent = 4
+	w.Delimiter = '\t'
+	w.Begin("a")
+	w.Table("rows", 1, "x", "y")
+	w.Row()
+	w.IntCell(1)
+	w.NullCell()
+	w.End()
+	w.Null("b")
+	w.End()
+
+	want := "a:\n    rows[1\t]{x\ty}:\n        1\t\n    b: null\n"
+	if got := string(w.Bytes()); got != want {
+		t.Errorf("Writer = %q, want %q", got, want)
+	}
+
+	w.Reset()
+	if len(w.Bytes()) != 0 {
+		t.Errorf("Reset left %q", w.Bytes())
+	}
+}
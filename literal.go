@@ -0,0 +1,191 @@
+package toon
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The encoder and the decoder share the rules in this file for the text
+// of scalar values, so that whatever one writes the other reads back as
+// written: when a string must be quoted, how quotes escape it, which bare
+// values are null, booleans or numbers, and how numbers are spelled.
+// The lite package keeps its own copy of the quoting rules.
+
+// needsQuoting reports whether s must be quoted to survive decoding: it is
+// empty, contains any delimiter, a quote or a line break, has leading or
+// trailing whitespace, would read as holding a comment, or would read back
+// as a number, boolean or null.
+// Every delimiter is considered, not only the active one, because the
+// decoder may have to guess which one a row uses.
+func needsQuoting(s string) bool {
+	return s == "" ||
+		strings.ContainsAny(s, ",|\t;\"\n\r") ||
+		strings.TrimSpace(s) != s ||
+		holdsComment(s) ||
+		looksLikeLiteral(s)
+}
+
+// looksLikeLiteral reports whether s would read back as a number, boolean
+// or null if written bare. Numbers outside JSON syntax, such as 007 or NaN,
+// count as well, since numeric fields still accept them.
+func looksLikeLiteral(s string) bool {
+	if _, ok := parseLiteral(s); ok {
+		return true
+	}
+	// Skip ParseFloat, and the error it allocates, for plain words
+	if s == "" || !strings.Contains("0123456789+-.iInN", s[:1]) {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// parseLiteral types a bare value the way decoding into any does: null is
+// nil, true and false are booleans, integers are int64, or uint64 above
+// the range of int64, and other numbers are float64. Only numbers spelled
+// as in JSON count, so words such as Infinity or NaN and forms such as 0x10
+// stay strings. ok is false for values that are strings.
+func parseLiteral(s string) (v any, ok bool) {
+	switch s {
+	case "null":
+		return nil, true
+	case "true", "false":
+		return s == "true", true
+	}
+	if !isJSONNumber(s) {
+		return nil, false
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return u, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+// isJSONNumber reports whether s follows the JSON number grammar: an
+// optional minus, an integer without leading zeros, then an optional
+// fraction and exponent.
+func isJSONNumber(s string) bool {
+	i := 0
+	digits := func() int {
+		start := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	if n := digits(); n == 0 || n > 1 && s[i-n] == '0' {
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// appendFloat appends f with the given number of decimals, or with the
+// fewest digits that read back as the same float of the given bit size
+// when precision is not positive.
+func appendFloat(dst []byte, f float64, bits, precision int) []byte {
+	if precision > 0 {
+		return strconv.AppendFloat(dst, f, 'f', precision, bits)
+	}
+	return strconv.AppendFloat(dst, f, 'g', -1, bits)
+}
+
+// formatComplex writes c as a+bi, without the parentheses strconv adds;
+// strconv.ParseComplex reads both forms.
+func formatComplex(c complex128, bits int) string {
+	return strings.Trim(strconv.FormatComplex(c, 'g', -1, bits), "()")
+}
+
+// quoteString wraps s in double quotes, escaping backslashes, quotes and
+// line breaks so the result stays on one line.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// isQuoted reports whether s is a quoted value: it starts with a quote and
+// ends with one that is not escaped.
+func isQuoted(s string) bool {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return false
+	}
+	backslashes := 0
+	for i := len(s) - 2; i > 0 && s[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 0
+}
+
+// unquote strips the surrounding double quotes from s and resolves the
+// escapes written by quoteString; any other escaped byte stands for
+// itself. Unquoted values are returned verbatim.
+func unquote(s string) string {
+	if !isQuoted(s) {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			default:
+				c = s[i]
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
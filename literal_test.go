@@ -0,0 +1,253 @@
+package toon_test
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+// trickyStrings holds strings that sit on the edge of the quoting rules:
+// literal lookalikes, delimiters, quotes, escapes, comment markers and
+// padding.
+var trickyStrings = []string{
+	"", " ", "  a  ", "a ", " a", "a", "é", "日本",
+	"null", "Null", "NULL", "true", "True", "false", "FALSE",
+	"0", "-0", "1", "-1", "+1", "01", "1.5", ".5", "5.", "1e5", "1E-5", "-1e+5",
+	"9223372036854775807", "9223372036854775808", "18446744073709551616",
+	"NaN", "nan", "Inf", "+Inf", "-inf", "infinity", "1e400",
+	"0x10", "0x1p4", "0b1", "0o7", "1_000", "1,000", "1+2i",
+	"a,b", "a|b", "a;b", "a\tb", "a\nb", "a\rb", "a\r\nb", "\n", "\t",
+	`"`, `""`, `"a"`, `a"b`, `"a`, `a"`, `\`, `a\`, `\"`, `\n`, `a\nb`, `\\`, `"a\"`,
+	"#", "#a", "a #b", "a#b", "a\t#b", "c#", "#crc32 0",
+	"-", "- a", "- - x", "[", "]", "{", "}", "a[2]", "{x}", "[1]: x",
+	":", "a:", "a: b", "a:b", "@1", "@x",
+	"\x00", "\b", "\f", "\v", "\x7f",
+}
+
+type literalRow struct {
+	A string `toon:"a"`
+	B string `toon:"b"`
+}
+
+type literalDoc struct {
+	S    string         `toon:"s"`
+	List []string       `toon:"list"`
+	Rows []literalRow   `toon:"rows"`
+	Any  []any          `toon:"any"`
+	Map  map[string]any `toon:"map"`
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	strs := append([]string(nil), trickyStrings...)
+	for c := 0; c < 0x80; c++ {
+		strs = append(strs, string(rune(c)), "a"+string(rune(c))+"b")
+	}
+
+	policies := []toon.StringQuoting{toon.QuoteAuto, toon.QuoteAlways, toon.QuoteMinimal}
+	delims := []toon.Delimiter{toon.DelimiterComma, toon.DelimiterTab, toon.DelimiterPipe, toon.DelimiterSemicolon}
+	for _, policy := range policies {
+		for _, delim := range delims {
+			opts := toon.DefaultMarshalOptions()
+			opts.StringQuoting = policy
+			opts.Delimiter = delim
+			uopts := toon.DefaultUnmarshalOptions()
+			if policy == toon.QuoteMinimal {
+				// Other delimiters are left bare and would be guessed
+				uopts.Delimiter = delim
+			}
+
+			for _, s := range strs {
+				in := literalDoc{
+					S:    s,
+					List: []string{s, "x"},
+					Rows: []literalRow{{A: s, B: s}, {A: "x", B: "y"}},
+					Any:  []any{s, "x"},
+					Map:  map[string]any{"k": s},
+				}
+				data, err := toon.MarshalWithOptions(in, opts)
+				if err != nil {
+					t.Fatalf("policy %d, delimiter %q: Marshal(%q) failed: %v", policy, delim, s, err)
+				}
+				var out literalDoc
+				if err := toon.UnmarshalWithOptions(data, &out, uopts); err != nil {
+					t.Fatalf("policy %d, delimiter %q: Unmarshal of %q failed: %v\n%s", policy, delim, s, err, data)
+				}
+				if !reflect.DeepEqual(out, in) {
+					t.Fatalf("policy %d, delimiter %q: %q read back as %#v\n%s", policy, delim, s, out, data)
+				}
+			}
+		}
+	}
+}
+
+func TestLiteralRoundTrip(t *testing.T) {
+	tests := []struct {
+		in   any
+		text string
+		want any
+	}{
+		{nil, "null", nil},
+		{true, "true", true},
+		{false, "false", false},
+		{int64(math.MinInt64), "-9223372036854775808", int64(math.MinInt64)},
+		{int64(math.MaxInt64), "9223372036854775807", int64(math.MaxInt64)},
+		{uint64(math.MaxUint64), "18446744073709551615", uint64(math.MaxUint64)},
+		{1.5, "1.5", 1.5},
+		{-2.25e-9, "-2.25e-09", -2.25e-9},
+		{1e21, "1e+21", 1e21},
+		{math.Inf(1), "+Inf", "+Inf"},
+		{math.Inf(-1), "-Inf", "-Inf"},
+		{float32(0.1), "0.1", 0.1},
+		{"12", `"12"`, "12"},
+		{"1e5", `"1e5"`, "1e5"},
+		{"NaN", `"NaN"`, "NaN"},
+		{"0x10", "0x10", "0x10"},
+		{"Infinity", `"Infinity"`, "Infinity"},
+		{"0x1p4", `"0x1p4"`, "0x1p4"},
+		{"1+2i", "1+2i", "1+2i"},
+	}
+
+	for _, tt := range tests {
+		data, err := toon.Marshal(map[string]any{"v": tt.in})
+		if err != nil {
+			t.Fatalf("Marshal(%#v) failed: %v", tt.in, err)
+		}
+		if want := "v: " + tt.text + "\n"; string(data) != want {
+			t.Errorf("Marshal(%#v) = %q, want %q", tt.in, data, want)
+		}
+		var out map[string]any
+		if err := toon.Unmarshal(data, &out); err != nil {
+			t.Fatalf("Unmarshal(%q) failed: %v", data, err)
+		}
+		if !reflect.DeepEqual(out["v"], tt.want) {
+			t.Errorf("Unmarshal(%q) = %#v, want %#v", data, out["v"], tt.want)
+		}
+	}
+
+	// Bare numbers outside JSON syntax read back as strings
+	for _, text := range []string{"NaN", "inf", "Infinity", "0x1p4", "007", "+5", "1.", ".5"} {
+		var out map[string]any
+		if err := toon.Unmarshal([]byte("v: "+text+"\n"), &out); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if out["v"] != text {
+			t.Errorf("Unmarshal(%q) = %#v, want the string", text, out["v"])
+		}
+	}
+}
+
+func TestNumberRoundTrip(t *testing.T) {
+	type Numbers struct {
+		F32 float32    `toon:"f32"`
+		F64 float64    `toon:"f64"`
+		I8  int8       `toon:"i8"`
+		U64 uint64     `toon:"u64"`
+		C64 complex64  `toon:"c64"`
+		C   complex128 `toon:"c"`
+	}
+
+	values := []Numbers{
+		{},
+		{F32: 0.1, F64: 0.1, I8: -128, U64: math.MaxUint64, C64: complex(0.1, -0.2), C: complex(1e300, 1)},
+		{F32: math.MaxFloat32, F64: math.MaxFloat64, I8: 127, U64: 1, C64: complex(1, 0), C: complex(0, -1)},
+		{F32: math.SmallestNonzeroFloat32, F64: math.SmallestNonzeroFloat64, C: complex(math.Inf(1), math.Inf(-1))},
+		{F32: float32(math.Inf(-1)), F64: -1e-300},
+	}
+	strict := toon.DefaultUnmarshalOptions()
+	strict.StrictTypes = true
+	for _, in := range values {
+		data, err := toon.Marshal(in)
+		if err != nil {
+			t.Fatalf("Marshal(%+v) failed: %v", in, err)
+		}
+		var out Numbers
+		if err := toon.UnmarshalWithOptions(data, &out, strict); err != nil {
+			t.Fatalf("Unmarshal(%q) failed: %v", data, err)
+		}
+		if out != in {
+			t.Errorf("%+v read back as %+v from %q", in, out, data)
+		}
+	}
+
+	data, err := toon.Marshal(Numbers{F32: 0.1})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "f32: 0.1\n") {
+		t.Errorf("float32 written with float64 digits: %q", data)
+	}
+}
+
+func TestMalformedQuotes(t *testing.T) {
+	// A value whose closing quote is escaped is not quoted, so it is read
+	// verbatim
+	for _, raw := range []string{`"a\"`, `"\\\"`, `"`, `"a`} {
+		var out struct {
+			S string `toon:"s"`
+		}
+		if err := toon.Unmarshal([]byte(fmt.Sprintf("s: %s\n", raw)), &out); err != nil {
+			t.Fatalf("Unmarshal(%q) failed: %v", raw, err)
+		}
+		if out.S != raw {
+			t.Errorf("Unmarshal(%q) = %q, want it verbatim", raw, out.S)
+		}
+	}
+}
+
+func TestEscapingRoundTrip(t *testing.T) {
+	type Note struct {
+		ID   int    `toon:"id"`
+		Text string `toon:"text"`
+	}
+	type Doc struct {
+		Title string   `toon:"title"`
+		Empty string   `toon:"empty"`
+		Tags  []string `toon:"tags"`
+		Notes []Note   `toon:"notes"`
+	}
+
+	values := []string{
+		"a,b", "a|b", "a;b", "tab\there", "line\nbreak", `say "hi"`,
+		`"quoted"`, `C:\path\n`, " padded ", "plain",
+	}
+
+	original := Doc{Title: `multi "line"\ntitle; with|all,delims`, Tags: values}
+	for i, v := range values {
+		original.Notes = append(original.Notes, Note{ID: i, Text: v})
+	}
+
+	delimiters := []toon.Delimiter{toon.DelimiterComma, toon.DelimiterTab, toon.DelimiterPipe, toon.DelimiterSemicolon}
+	for _, delim := range delimiters {
+		opts := toon.DefaultMarshalOptions()
+		opts.Delimiter = delim
+
+		data, err := toon.MarshalWithOptions(original, opts)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+
+		var decoded Doc
+		if err := toon.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal failed with %q: %v", delim, err)
+		}
+
+		if decoded.Title != original.Title || decoded.Empty != "" {
+			t.Errorf("Scalar mismatch with %q: %q, %q", delim, decoded.Title, decoded.Empty)
+		}
+		if len(decoded.Tags) != len(values) || len(decoded.Notes) != len(values) {
+			t.Fatalf("Length mismatch with %q:\n%s", delim, data)
+		}
+		for i, v := range values {
+			if decoded.Tags[i] != v {
+				t.Errorf("Tag %d with %q: expected %q, got %q", i, delim, v, decoded.Tags[i])
+			}
+			if decoded.Notes[i].Text != v {
+				t.Errorf("Note %d with %q: expected %q, got %q", i, delim, v, decoded.Notes[i].Text)
+			}
+		}
+	}
+}
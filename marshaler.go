@@ -0,0 +1,62 @@
+package toon
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+)
+
+// Marshaler is implemented by types that write themselves as a single
+// value, such as identifiers, enums and money amounts. MarshalTOON returns
+// the value as it appears after a key or in a table cell, on one line and
+// quoted where it would otherwise read as a number, boolean, null or more
+// than one cell. Values of such types are never written as blocks or
+// arrays.
+type Marshaler interface {
+	MarshalTOON() ([]byte, error)
+}
+
+// Unmarshaler is implemented by types that read themselves from a single
+// value. UnmarshalTOON receives the value as written, quotes and escapes
+// included, and must copy the data if it keeps it.
+type Unmarshaler interface {
+	UnmarshalTOON(data []byte) error
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+// isMarshalerType reports whether t or a pointer to it implements
+// Marshaler.
+func isMarshalerType(t reflect.Type) bool {
+	return t.Kind() != reflect.Interface && t.Kind() != reflect.Ptr &&
+		(t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType))
+}
+
+func (e *encoder) writeMarshaler(v reflect.Value) error {
+	m, ok := v.Interface().(Marshaler)
+	if !ok {
+		m = addressable(v).Interface().(Marshaler)
+	}
+	data, err := m.MarshalTOON()
+	if err != nil {
+		return fmt.Errorf("toon: marshal %s: %w", e.pathString(), err)
+	}
+	if bytes.ContainsAny(data, "\r\n") {
+		return fmt.Errorf("toon: MarshalTOON of %s returned more than one line", v.Type())
+	}
+	e.buf.Write(data)
+	return nil
+}
+
+// unmarshalerFor returns the Unmarshaler of the addressable value v, if its
+// type implements it through a pointer receiver or otherwise.
+func unmarshalerFor(v reflect.Value) (Unmarshaler, bool) {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface || !v.CanAddr() {
+		return nil, false
+	}
+	u, ok := v.Addr().Interface().(Unmarshaler)
+	return u, ok
+}
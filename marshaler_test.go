@@ -0,0 +1,132 @@
+package toon_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+// money is written as "12.50 EUR", quoted since it holds a space.
+type money struct {
+	Cents    int64
+	Currency string
+}
+
+func (m money) MarshalTOON() ([]byte, error) {
+	if m.Currency == "" {
+		return nil, errors.New("no currency")
+	}
+	return []byte(fmt.Sprintf(`"%d.%02d %s"`, m.Cents/100, m.Cents%100, m.Currency)), nil
+}
+
+func (m *money) UnmarshalTOON(data []byte) error {
+	var whole, cents int64
+	if _, err := fmt.Sscanf(strings.Trim(string(data), `"`), "%d.%d %s", &whole, &cents, &m.Currency); err != nil {
+		return fmt.Errorf("bad amount %s: %w", data, err)
+	}
+	m.Cents = whole*100 + cents
+	return nil
+}
+
+// grade is an enum written by name.
+type grade int
+
+var gradeNames = []string{"easy", "moderate", "hard"}
+
+func (g grade) MarshalTOON() ([]byte, error) { return []byte(gradeNames[g]), nil }
+
+func (g *grade) UnmarshalTOON(data []byte) error {
+	for i, name := range gradeNames {
+		if name == string(data) {
+			*g = grade(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown grade %s", data)
+}
+
+// price implements Marshaler only, so it is written but cannot be read.
+type price struct{ Cents int64 }
+
+func (p price) MarshalTOON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%02d", p.Cents/100, p.Cents%100)), nil
+}
+
+// multiline breaks the single-line rule.
+type multiline struct{}
+
+func (multiline) MarshalTOON() ([]byte, error) { return []byte("a\nb"), nil }
+
+func TestMarshalerInterfaces(t *testing.T) {
+	type Hike struct {
+		Name  string `toon:"name"`
+		Grade grade  `toon:"grade"`
+		Fee   money  `toon:"fee"`
+	}
+	type Trip struct {
+		Budget  money   `toon:"budget"`
+		Deposit *money  `toon:"deposit"`
+		Refund  *money  `toon:"refund"`
+		Grades  []grade `toon:"grades"`
+		Hikes   []Hike  `toon:"hikes"`
+	}
+
+	in := Trip{
+		Budget:  money{125050, "EUR"},
+		Deposit: &money{2000, "USD"},
+		Grades:  []grade{0, 2},
+		Hikes:   []Hike{{"Lake", 0, money{0, "EUR"}}, {"Ridge", 2, money{1550, "EUR"}}},
+	}
+	data, err := toon.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `budget: "1250.50 EUR"
+deposit: "20.00 USD"
+refund: null
+grades[2]: easy,hard
+hikes[2]{name,grade,fee}:
+  Lake,easy,"0.00 EUR"
+  Ridge,hard,"15.50 EUR"
+`
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+
+	var out Trip
+	if err := toon.UnmarshalWithOptions(data, &out, toon.UnmarshalOptions{Strict: true}); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+
+	if err := toon.Unmarshal([]byte("grades[1]: extreme\n"), &out); err == nil || !strings.Contains(err.Error(), "unknown grade extreme") {
+		t.Errorf("Unmarshal of unknown grade = %v", err)
+	}
+	if _, err := toon.Marshal(Trip{Budget: money{1, ""}}); err == nil || !strings.Contains(err.Error(), "toon: marshal budget: no currency") {
+		t.Errorf("Marshal with failing MarshalTOON = %v", err)
+	}
+	if _, err := toon.Marshal(map[string]multiline{"x": {}}); err == nil || !strings.Contains(err.Error(), "more than one line") {
+		t.Errorf("Marshal of multi-line value = %v", err)
+	}
+
+	// Without UnmarshalTOON or UnmarshalText the value is a type error
+	type Item struct {
+		Name  string `toon:"name"`
+		Price price  `toon:"price"`
+	}
+	data, err = toon.Marshal(Item{Name: "map", Price: price{1234}})
+	if err != nil || string(data) != "name: map\nprice: 12.34\n" {
+		t.Fatalf("Marshal = %q, %v", data, err)
+	}
+	var item Item
+	var typeErr *toon.UnmarshalTypeError
+	if err := toon.Unmarshal(data, &item); !errors.As(err, &typeErr) || typeErr.Path != "price" {
+		t.Errorf("Unmarshal into Marshaler-only type = %v, want *UnmarshalTypeError at price", err)
+	}
+}
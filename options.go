@@ -0,0 +1,229 @@
+package toon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type MarshalOption func(*MarshalOptions) error
+
+func WithIndent(indent int) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.Indent = indent
+		return nil
+	}
+}
+
+func WithDelimiter(delim Delimiter) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.Delimiter = delim
+		return nil
+	}
+}
+
+func WithKeyValueSeparator(sep string) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.KeyValueSeparator = sep
+		return nil
+	}
+}
+
+func WithCompactSeparator(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.CompactSeparator = enabled
+		return nil
+	}
+}
+
+func WithTabular(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.UseTabular = enabled
+		return nil
+	}
+}
+
+func WithLenient(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.Lenient = enabled
+		return nil
+	}
+}
+
+func WithStringQuoting(quoting StringQuoting) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.StringQuoting = quoting
+		return nil
+	}
+}
+
+func WithVersionHeader(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.VersionHeader = enabled
+		return nil
+	}
+}
+
+func WithChecksum(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.Checksum = enabled
+		return nil
+	}
+}
+
+func WithMaxInlineItems(n int) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.MaxInlineItems = n
+		return nil
+	}
+}
+
+func WithMinTabularRows(n int) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.MinTabularRows = n
+		return nil
+	}
+}
+
+func WithTimeLocation(loc *time.Location) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.TimeLocation = loc
+		return nil
+	}
+}
+
+func WithTimeFormat(layout string) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.TimeFormat = layout
+		return nil
+	}
+}
+
+func WithBytesAsArray(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.BytesAsArray = enabled
+		return nil
+	}
+}
+
+func WithSummarizeArrays(threshold int) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.SummarizeArrays = threshold
+		return nil
+	}
+}
+
+func WithOptimizeColumns(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.OptimizeColumns = enabled
+		return nil
+	}
+}
+
+func WithFoldConstantColumns(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.FoldConstantColumns = enabled
+		return nil
+	}
+}
+
+func WithSparseTables(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.SparseTables = enabled
+		return nil
+	}
+}
+
+func WithOmitArrayCounts(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.OmitArrayCounts = enabled
+		return nil
+	}
+}
+
+func WithSkipNilMaps(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.SkipNilMaps = enabled
+		return nil
+	}
+}
+
+func WithSkipNilSlices(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.SkipNilSlices = enabled
+		return nil
+	}
+}
+
+func WithSkipNilPointers(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.SkipNilPointers = enabled
+		return nil
+	}
+}
+
+func WithKeyPrefix(prefix string) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.KeyPrefix = prefix
+		return nil
+	}
+}
+
+func WithAudience(audience string) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.Audience = audience
+		return nil
+	}
+}
+
+func WithShortenIDs(enabled bool) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.ShortenIDs = enabled
+		return nil
+	}
+}
+
+func WithKeyTranslator(translate func(name string) string) MarshalOption {
+	return func(o *MarshalOptions) error {
+		o.KeyTranslator = translate
+		return nil
+	}
+}
+
+func (o MarshalOptions) validate() error {
+	if o.Indent <= 0 {
+		return fmt.Errorf("%w: indent must be greater than 0, got %d", ErrInvalidOptions, o.Indent)
+	}
+
+	switch o.Delimiter {
+	case DelimiterComma, DelimiterTab, DelimiterPipe, DelimiterSemicolon:
+	default:
+		return fmt.Errorf("%w: unknown delimiter %q", ErrInvalidOptions, o.Delimiter)
+	}
+
+	if sep := strings.Trim(o.KeyValueSeparator, " "); o.KeyValueSeparator != "" && sep != ":" && sep != "=" {
+		return fmt.Errorf("%w: key/value separator must be ':' or '=' with optional spaces, got %q", ErrInvalidOptions, o.KeyValueSeparator)
+	}
+
+	if o.MaxInlineItems < 0 {
+		return fmt.Errorf("%w: max inline items must not be negative, got %d", ErrInvalidOptions, o.MaxInlineItems)
+	}
+
+	if o.MinTabularRows < 0 {
+		return fmt.Errorf("%w: min tabular rows must not be negative, got %d", ErrInvalidOptions, o.MinTabularRows)
+	}
+
+	if o.SummarizeArrays < 0 {
+		return fmt.Errorf("%w: summarize threshold must not be negative, got %d", ErrInvalidOptions, o.SummarizeArrays)
+	}
+
+	if o.StringQuoting < QuoteAuto || o.StringQuoting > QuoteMinimal {
+		return fmt.Errorf("%w: unknown string quoting policy %d", ErrInvalidOptions, o.StringQuoting)
+	}
+
+	if strings.ContainsAny(o.KeyPrefix, ":=[]{}# \t\r\n") {
+		return fmt.Errorf("%w: key prefix %q holds characters not allowed in keys", ErrInvalidOptions, o.KeyPrefix)
+	}
+
+	return nil
+}
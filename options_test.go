@@ -0,0 +1,606 @@
+package toon_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestMarshalFunctionalOptions(t *testing.T) {
+	data := struct {
+		Context Context `toon:"context"`
+		Numbers []int   `toon:"numbers"`
+	}{
+		Context: Context{Task: "hike"},
+		Numbers: []int{1, 2, 3},
+	}
+
+	result, err := toon.Marshal(data, toon.WithDelimiter(toon.DelimiterTab), toon.WithIndent(4))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := "context:\n    task: hike\n    location: \"\"\n    season: \"\"\nnumbers[3\t]: 1\t2\t3\n"
+	if string(result) != expected {
+		t.Errorf("Expected:\n%q\nGot:\n%q", expected, string(result))
+	}
+
+	invalid := []toon.MarshalOption{
+		toon.WithIndent(0),
+		toon.WithIndent(-2),
+		toon.WithDelimiter(":"),
+	}
+	for _, option := range invalid {
+		if _, err := toon.Marshal(data, option); !errors.Is(err, toon.ErrInvalidOptions) {
+			t.Errorf("Expected ErrInvalidOptions, got %v", err)
+		}
+	}
+
+	if _, err := toon.MarshalWithOptions(data, toon.MarshalOptions{}); !errors.Is(err, toon.ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions for zero options, got %v", err)
+	}
+}
+
+func TestMarshalStringQuoting(t *testing.T) {
+	data := struct {
+		Name string   `toon:"name"`
+		Code string   `toon:"code"`
+		Flag string   `toon:"flag"`
+		Tags []string `toon:"tags"`
+	}{
+		Name: "Blue Lake",
+		Code: "007",
+		Flag: "true",
+		Tags: []string{"a|b", "c,d"},
+	}
+
+	tests := []struct {
+		quoting  toon.StringQuoting
+		expected string
+	}{
+		{toon.QuoteAuto, "name: Blue Lake\ncode: \"007\"\nflag: \"true\"\ntags[2]: \"a|b\",\"c,d\"\n"},
+		{toon.QuoteAlways, "name: \"Blue Lake\"\ncode: \"007\"\nflag: \"true\"\ntags[2]: \"a|b\",\"c,d\"\n"},
+		{toon.QuoteMinimal, "name: Blue Lake\ncode: \"007\"\nflag: \"true\"\ntags[2]: a|b,\"c,d\"\n"},
+	}
+
+	for _, tt := range tests {
+		result, err := toon.Marshal(data, toon.WithStringQuoting(tt.quoting))
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if string(result) != tt.expected {
+			t.Errorf("Quoting %d: expected:\n%q\nGot:\n%q", tt.quoting, tt.expected, string(result))
+		}
+	}
+}
+
+func TestMarshalOptionLayouts(t *testing.T) {
+	type Lists struct {
+		Short []int    `toon:"short"`
+		Long  []string `toon:"long"`
+	}
+	type Trail struct {
+		Hikes []Hike `toon:"hikes"`
+	}
+	type Noted struct {
+		Note   string `toon:"note"`
+		Season string `toon:"season"`
+		Name   string `toon:"name"`
+		Km     int    `toon:"km"`
+	}
+	type Seasonal struct {
+		Name   string `toon:"name"`
+		Season string `toon:"season"`
+		Km     int    `toon:"km"`
+	}
+	type Sparse struct {
+		Name string  `toon:"name"`
+		Km   int     `toon:"km"`
+		Note string  `toon:"note"`
+		Tag  string  `toon:"a=b"`
+		Rate float64 `toon:"rate"`
+	}
+	type NotedHikes struct {
+		Hikes []Noted `toon:"hikes"`
+	}
+	type SeasonalHikes struct {
+		Hikes []Seasonal `toon:"hikes"`
+	}
+	type SparseHikes struct {
+		Hikes []Sparse `toon:"hikes"`
+	}
+	type Row struct {
+		ID   int    `toon:"id"`
+		Name string `toon:"name"`
+	}
+	type Feed struct {
+		Rows  []Row    `toon:"rows"`
+		Tags  []string `toon:"tags"`
+		Empty []int    `toon:"empty"`
+	}
+	type Stop struct {
+		Name string   `toon:"name"`
+		Tags []string `toon:"tags"`
+	}
+	type Config struct {
+		Host  string            `toon:"host"`
+		URL   string            `toon:"url"`
+		Ports []int             `toon:"ports"`
+		Stops []Stop            `toon:"stops"`
+		Env   map[string]string `toon:"env"`
+	}
+	type Leg struct {
+		Name string  `toon:"name"`
+		Km   float64 `toon:"km"`
+	}
+	type Trip struct {
+		Owner string   `toon:"owner"`
+		Tags  []string `toon:"tags"`
+		Stops []Leg    `toon:"stops"`
+		Meta  struct {
+			Note string `toon:"note"`
+		} `toon:"meta"`
+	}
+
+	blue := Hike{ID: 1, Name: "Blue Lake Trail", DistanceKm: 7.5, ElevationGain: 320, Companion: "ana", WasSunny: true}
+	lake := Seasonal{Name: "Lake", Season: "spring_2025", Km: 7}
+	trip := Trip{Owner: "ana", Tags: []string{"lake", "ridge"}, Stops: []Leg{{"hut", 3}}}
+	trip.Meta.Note = " padded"
+	compact := func(sep string) []toon.MarshalOption {
+		return []toon.MarshalOption{toon.WithKeyValueSeparator(sep), toon.WithMinTabularRows(2), toon.WithCompactSeparator(true)}
+	}
+
+	testMarshalCases(t, []marshalCase{
+		{
+			name: "max inline items",
+			in:   Lists{Short: []int{1, 2}, Long: []string{"ana", "luis", "sam"}},
+			opts: []toon.MarshalOption{toon.WithMaxInlineItems(2)},
+			want: "short[2]: 1,2\nlong[3]:\n  - ana\n  - luis\n  - sam\n",
+		},
+		{
+			name: "below min tabular rows",
+			in:   Trail{Hikes: []Hike{blue}},
+			opts: []toon.MarshalOption{toon.WithMinTabularRows(2)},
+			want: "hikes[1]:\n  - id: 1\n    name: Blue Lake Trail\n    distanceKm: 7.5\n    elevationGain: 320\n    companion: ana\n    wasSunny: true\n",
+		},
+		{
+			name: "at min tabular rows",
+			in:   Trail{Hikes: []Hike{blue, blue}},
+			opts: []toon.MarshalOption{toon.WithMinTabularRows(2)},
+			want: "hikes[2]{id,name,distanceKm,elevationGain,companion,wasSunny}:\n  1,Blue Lake Trail,7.5,320,ana,true\n  1,Blue Lake Trail,7.5,320,ana,true\n",
+		},
+		{
+			name: "version header",
+			in: struct {
+				Name string `toon:"name"`
+			}{Name: "Alice"},
+			opts: []toon.MarshalOption{toon.WithVersionHeader(true)},
+			want: "#toon 1.0\nname: Alice\n",
+		},
+		{
+			name: "optimize columns",
+			in: NotedHikes{Hikes: []Noted{
+				{Season: "spring", Name: "Lake", Km: 7},
+				{Season: "spring", Name: "Ridge", Km: 9},
+				{Note: "wet", Season: "summer", Name: "Pass", Km: 12},
+			}},
+			opts: []toon.MarshalOption{toon.WithOptimizeColumns(true)},
+			want: "hikes[3]{name,km,note,season}:\n  Lake,7,\"\",spring\n  Ridge,9,\"\",spring\n  Pass,12,wet,summer\n",
+		},
+		{
+			name: "fold constant columns",
+			in:   SeasonalHikes{Hikes: []Seasonal{lake, {Name: "Ridge", Season: "spring_2025", Km: 9}}},
+			opts: []toon.MarshalOption{toon.WithFoldConstantColumns(true)},
+			want: "hikes.*.season: spring_2025\nhikes[2]{name,km}:\n  Lake,7\n  Ridge,9\n",
+		},
+		{
+			// A table whose columns are all constant keeps its last column
+			name: "fold every column",
+			in:   SeasonalHikes{Hikes: []Seasonal{lake, lake}},
+			opts: []toon.MarshalOption{toon.WithFoldConstantColumns(true)},
+			want: "hikes.*.name: Lake\nhikes.*.season: spring_2025\nhikes[2]{km}:\n  7\n  7\n",
+		},
+		{
+			name: "sparse tables",
+			in:   SparseHikes{Hikes: []Sparse{{Name: "Lake", Km: 7}, {Note: "wet, cold", Tag: "x"}}},
+			opts: []toon.MarshalOption{toon.WithSparseTables(true)},
+			want: "hikes[2]{=}:\n  name=Lake,km=7\n  note=\"wet, cold\",\"a=b\"=x\n",
+		},
+		{
+			// Quoted cells keep a '#' after a space rather than starting a comment
+			name: "sparse cells holding hashes",
+			in:   SparseHikes{Hikes: []Sparse{{Name: "x #y", Note: "a #b"}}},
+			opts: []toon.MarshalOption{toon.WithSparseTables(true)},
+			want: "hikes[1]{=}:\n  name=\"x #y\",note=\"a #b\"\n",
+		},
+		{
+			name: "omit array counts",
+			in:   Feed{Rows: []Row{{1, "a"}, {2, "b"}}, Tags: []string{"x", "y"}, Empty: []int{}},
+			opts: []toon.MarshalOption{toon.WithOmitArrayCounts(true)},
+			want: "rows[]{id,name}:\n  1,a\n  2,b\ntags[]: x,y\nempty[]:\n",
+		},
+		{
+			// Lines opening a block or a table keep their colon
+			name: "key value separator",
+			in: Config{
+				Host:  "example.com",
+				URL:   "http://example.com:8080/?a=b",
+				Ports: []int{80, 443},
+				Stops: []Stop{{"hut", []string{"water"}}, {"peak", []string{}}},
+				Env:   map[string]string{"MODE": "a=b"},
+			},
+			opts: []toon.MarshalOption{toon.WithKeyValueSeparator(" = ")},
+			want: "host = example.com\nurl = http://example.com:8080/?a=b\nports[2] = 80,443\nstops[2]:\n" +
+				"  - name = hut\n    tags[1] = water\n  - name = peak\n    tags[0]:\nenv:\n  MODE = a=b\n",
+		},
+		{
+			name: "compact colon",
+			in:   trip,
+			opts: compact(": "),
+			want: "owner:ana\ntags[2]:lake,ridge\nstops[1]:\n  - name:hut\n    km:3\nmeta:\n  note:\" padded\"\n",
+		},
+		{
+			name: "compact equals sign",
+			in:   trip,
+			opts: compact(" = "),
+			want: "owner=ana\ntags[2]=lake,ridge\nstops[1]:\n  - name=hut\n    km=3\nmeta:\n  note=\" padded\"\n",
+		},
+	})
+
+	// A row of zero values keeps its first field, which reads back as
+	// blank outside strict mode
+	data, err := toon.Marshal(SparseHikes{Hikes: []Sparse{{}}}, toon.WithSparseTables(true))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := "hikes[1]{=}:\n  name=\n"; string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+	var out SparseHikes
+	if err := toon.Unmarshal(data, &out); err != nil || !reflect.DeepEqual(out, SparseHikes{Hikes: []Sparse{{}}}) {
+		t.Errorf("Unmarshal = %+v, %v", out, err)
+	}
+}
+
+func TestKeyValueSeparator(t *testing.T) {
+	type Config struct {
+		Host  string `toon:"host"`
+		URL   string `toon:"url"`
+		Ports []int  `toon:"ports"`
+	}
+	want := Config{Host: "example.com", URL: "http://example.com:8080/?a=b", Ports: []int{80, 443}}
+
+	// Either separator reads in the same document, with or without spaces
+	var mixed Config
+	if err := toon.Unmarshal([]byte("host=example.com\nurl: http://example.com:8080/?a=b\nports[2]= 80,443\n"), &mixed); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(mixed, want) {
+		t.Errorf("Unmarshal = %+v, want %+v", mixed, want)
+	}
+
+	// Keys are written bare, so those that would not read back are refused
+	for _, key := range []string{"a=b", "a:b", "", "#c", "a #b", "x.*.y", " x", `"q"`, "a[2]", "- x"} {
+		if _, err := toon.Marshal(map[string]string{key: "x"}); err == nil {
+			t.Errorf("Marshal with key %q succeeded", key)
+		}
+		if _, err := toon.Marshal([]toon.OrderedMap{{{Key: key, Value: 1}}, {{Key: "c", Value: 2}}}); err == nil {
+			t.Errorf("Marshal with ordered key %q succeeded", key)
+		}
+		if _, err := toon.Marshal(map[string]any{"l": []any{map[string]int{key: 1}, 1}}); err == nil {
+			t.Errorf("Marshal with list item key %q succeeded", key)
+		}
+	}
+	if _, err := toon.Marshal(map[int]string{-1: "x"}); err != nil {
+		t.Errorf("Marshal with key -1: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		option toon.MarshalOption
+	}{
+		{"prefix holding the separator", toon.WithKeyPrefix("app=")},
+		{"arrow", toon.WithKeyValueSeparator("->")},
+		{"space", toon.WithKeyValueSeparator(" ")},
+		{"both separators", toon.WithKeyValueSeparator(": =")},
+		{"tabs", toon.WithKeyValueSeparator("\t=\t")},
+	} {
+		if _, err := toon.Marshal(want, tt.option); !errors.Is(err, toon.ErrInvalidOptions) {
+			t.Errorf("%s: err = %v, want ErrInvalidOptions", tt.name, err)
+		}
+	}
+}
+
+func TestCompactSeparator(t *testing.T) {
+	type Stop struct {
+		Name string  `toon:"name"`
+		Km   float64 `toon:"km"`
+	}
+	type Trip struct {
+		Owner string   `toon:"owner"`
+		Tags  []string `toon:"tags"`
+		Stops []Stop   `toon:"stops"`
+	}
+	trip := Trip{Owner: "ana", Tags: []string{"lake", "ridge"}, Stops: []Stop{{"hut", 3}}}
+
+	// Any spacing around the colon reads the same, checksum footer included
+	data, err := toon.Marshal(trip, toon.WithChecksum(true))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	spaced := strings.NewReplacer("owner: ", "owner :", "tags[2]: ", "tags[2]   :  ", "km: ", "km:", "#crc32: ", "#crc32:").Replace(string(data))
+	opts := toon.DefaultUnmarshalOptions()
+	opts.VerifyChecksum = true
+	var decoded Trip
+	if err := toon.UnmarshalWithOptions([]byte(spaced), &decoded, opts); !errors.Is(err, toon.ErrChecksum) || !strings.Contains(err.Error(), "footer has") {
+		t.Fatalf("Unmarshal = %v, want a checksum mismatch for the edited document", err)
+	}
+	if err := toon.Unmarshal([]byte(spaced), &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, trip) {
+		t.Errorf("Unmarshal = %+v, want %+v", decoded, trip)
+	}
+	if got, err := toon.Extract([]byte(spaced), []string{"owner", "tags[1]", "stops[0].km"}); err != nil || got["owner"] != "ana" || got["tags[1]"] != "ridge" || got["stops[0].km"] != "3" {
+		t.Errorf("Extract = %v, %v", got, err)
+	}
+	if formatted, err := toon.Format([]byte(spaced)); err != nil || !strings.HasPrefix(string(formatted), "owner: ana\ntags[2]: lake,ridge\n") {
+		t.Errorf("Format = %q, %v", formatted, err)
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	type Service struct {
+		Name  string         `toon:"name"`
+		Tags  []string       `toon:"tags"`
+		Stats map[string]int `toon:"stats"`
+	}
+
+	in := Service{Name: "search", Tags: []string{"a", "b"}, Stats: map[string]int{"hits": 3}}
+	data, err := toon.Marshal(in, toon.WithKeyPrefix("app."))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := "app.name: search\napp.tags[2]: a,b\napp.stats:\n  hits: 3\n"
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+
+	// Keys of another service in the same document are left alone
+	combined := append(data, "billing.name: invoices\n"...)
+	opts := toon.DefaultUnmarshalOptions()
+	opts.KeyPrefix = "app."
+	var out Service
+	if err := toon.UnmarshalWithOptions(combined, &out, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+
+	if _, err := toon.Marshal(in, toon.WithKeyPrefix("app: ")); !errors.Is(err, toon.ErrInvalidOptions) {
+		t.Errorf("Marshal with prefix %q = %v, want ErrInvalidOptions", "app: ", err)
+	}
+}
+
+func TestKeyTranslator(t *testing.T) {
+	type Hike struct {
+		Name     string  `toon:"name"`
+		Distance float64 `toon:"distanceKm,unit=km"`
+	}
+	type Trip struct {
+		TripName string         `toon:"tripName"`
+		Hikes    []Hike         `toon:"hikes"`
+		Tags     map[string]int `toon:"tags"`
+	}
+
+	labels := map[string]string{"tripName": "trip", "name": "title", "distanceKm": "distance"}
+	fields := map[string]string{"trip": "tripName", "title": "name", "distance": "distanceKm"}
+	translate := func(m map[string]string) func(string) string {
+		return func(s string) string {
+			if label, ok := m[s]; ok {
+				return label
+			}
+			return s
+		}
+	}
+
+	in := Trip{
+		TripName: "Alps",
+		Hikes:    []Hike{{Name: "Lake", Distance: 7.5}, {Name: "Ridge", Distance: 9.2}},
+		Tags:     map[string]int{"name": 1},
+	}
+	data, err := toon.Marshal(in, toon.WithKeyTranslator(translate(labels)))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	// Map keys are data and stay as they are
+	want := "trip: Alps\nhikes[2]{title,distance(km)}:\n  Lake,7.5\n  Ridge,9.2\ntags:\n  name: 1\n"
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+
+	opts := toon.DefaultUnmarshalOptions()
+	opts.KeyTranslator = translate(fields)
+	var out Trip
+	if err := toon.UnmarshalWithOptions(data, &out, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestMarshalSkipNil(t *testing.T) {
+	type Hike struct {
+		Name   string         `toon:"name"`
+		Tags   []string       `toon:"tags"`
+		Extra  map[string]int `toon:"extra"`
+		Parent *Hike          `toon:"parent"`
+	}
+	in := struct {
+		Longest Hike  `toon:"longest"`
+		Empty   []int `toon:"empty"`
+		Items   []any `toon:"items"`
+		Next    *Hike `toon:"next"`
+	}{
+		Longest: Hike{Name: "Ridge"},
+		Empty:   []int{},
+		Items:   []any{Hike{Name: "Lake", Tags: []string{"cold"}}},
+	}
+
+	tests := []struct {
+		name string
+		opts []toon.MarshalOption
+		want string
+	}{
+		{"none", nil, "longest:\n  name: Ridge\n  tags[0]:\n  extra:\n  parent: null\nempty[0]:\n" +
+			"items[1]:\n  - name: Lake\n    tags[1]: cold\n    extra:\n    parent: null\nnext: null\n"},
+		{"slices", []toon.MarshalOption{toon.WithSkipNilSlices(true)}, "longest:\n  name: Ridge\n  extra:\n  parent: null\nempty[0]:\n" +
+			"items[1]:\n  - name: Lake\n    tags[1]: cold\n    extra:\n    parent: null\nnext: null\n"},
+		{"all", []toon.MarshalOption{toon.WithSkipNilMaps(true), toon.WithSkipNilSlices(true), toon.WithSkipNilPointers(true)},
+			"longest:\n  name: Ridge\nempty[0]:\nitems[1]:\n  - name: Lake\n    tags[1]: cold\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := toon.Marshal(in, tt.opts...)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("Marshal = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalAudience(t *testing.T) {
+	type Hike struct {
+		Name  string `toon:"name"`
+		Cost  int    `toon:"cost,only=api"`
+		Trace string `toon:"trace,only=internal|log"`
+	}
+	type Report struct {
+		Title string `toon:"title"`
+		Debug string `toon:"debug,only=log,omitempty"`
+		Hikes []Hike `toon:"hikes"`
+	}
+	in := Report{Title: "spring", Debug: "ok", Hikes: []Hike{{"Lake", 3, "t1"}, {"Ridge", 5, "t2"}}}
+
+	tests := []struct {
+		audience string
+		want     string
+	}{
+		{"", "title: spring\nhikes[2]{name}:\n  Lake\n  Ridge\n"},
+		{"llm", "title: spring\nhikes[2]{name}:\n  Lake\n  Ridge\n"},
+		{"api", "title: spring\nhikes[2]{name,cost}:\n  Lake,3\n  Ridge,5\n"},
+		{"log", "title: spring\ndebug: ok\nhikes[2]{name,trace}:\n  Lake,t1\n  Ridge,t2\n"},
+	}
+	for _, tt := range tests {
+		data, err := toon.Marshal(in, toon.WithAudience(tt.audience))
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("Marshal for %q = %q, want %q", tt.audience, data, tt.want)
+		}
+	}
+
+	// Decoding reads every field regardless of audience
+	var out Report
+	if err := toon.Unmarshal([]byte(tests[3].want), &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Debug != "ok" || out.Hikes[1].Trace != "t2" {
+		t.Errorf("Unmarshal = %+v", out)
+	}
+}
+
+func TestMarshalFieldFilter(t *testing.T) {
+	type Debug struct {
+		Trace string `toon:"trace"`
+	}
+	type Hike struct {
+		Name     string `toon:"name"`
+		Internal string `toon:"internal"`
+	}
+	type Report struct {
+		Title string `toon:"title"`
+		Debug Debug  `toon:"debug"`
+		Hikes []Hike `toon:"hikes"`
+	}
+
+	in := Report{
+		Title: "spring",
+		Debug: Debug{Trace: "abc"},
+		Hikes: []Hike{{Name: "Lake", Internal: "x"}, {Name: "Ridge", Internal: "y"}},
+	}
+
+	var paths []string
+	opts := toon.DefaultMarshalOptions()
+	opts.FieldFilter = func(path string, field reflect.StructField) bool {
+		paths = append(paths, path)
+		return path != "debug" && field.Name != "Internal"
+	}
+
+	data, err := toon.MarshalWithOptions(in, opts)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := "title: spring\nhikes[2]{name}:\n  Lake\n  Ridge\n"
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+	if !strings.Contains(strings.Join(paths, " "), "hikes.internal") {
+		t.Errorf("filter paths = %v, want hikes.internal among them", paths)
+	}
+}
+
+func TestMarshalTransformValue(t *testing.T) {
+	type Contact struct {
+		Email string  `toon:"email"`
+		Lat   float64 `toon:"lat"`
+	}
+	type Book struct {
+		Owner    Contact   `toon:"owner"`
+		Contacts []Contact `toon:"contacts"`
+	}
+
+	in := Book{
+		Owner:    Contact{Email: "ana@example.com", Lat: 40.01499},
+		Contacts: []Contact{{Email: "luis@example.com", Lat: 39.7392}},
+	}
+
+	opts := toon.DefaultMarshalOptions()
+	opts.TransformValue = func(path string, v any) (any, error) {
+		switch {
+		case strings.HasSuffix(path, "email"):
+			return strings.Repeat("*", 3) + "@" + strings.SplitN(v.(string), "@", 2)[1], nil
+		case strings.HasSuffix(path, "lat"):
+			return float64(int(v.(float64)*10)) / 10, nil
+		}
+		return v, nil
+	}
+
+	data, err := toon.MarshalWithOptions(in, opts)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := "owner:\n  email: ***@example.com\n  lat: 40\ncontacts[1]{email,lat}:\n  ***@example.com,39.7\n"
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+
+	opts.TransformValue = func(path string, v any) (any, error) {
+		if path == "contacts[0].email" {
+			return nil, errors.New("blocked")
+		}
+		return v, nil
+	}
+	if _, err := toon.MarshalWithOptions(in, opts); err == nil || !strings.Contains(err.Error(), "contacts[0].email") {
+		t.Errorf("expected transform error with path, got %v", err)
+	}
+}
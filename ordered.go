@@ -0,0 +1,193 @@
+package toon
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+var orderedMapType = reflect.TypeOf(OrderedMap(nil))
+
+// KeyValue is one key of an OrderedMap.
+type KeyValue struct {
+	Key   string
+	Value any
+}
+
+// OrderedMap holds the keys of a block in document order, for converters
+// that reproduce the order of the source in their output. Decoding into an
+// OrderedMap types values as decoding into any does, except that nested
+// blocks are OrderedMaps too. Marshal writes the keys in order rather than
+// sorted, and encoding/json writes it as an object with its keys in order.
+type OrderedMap []KeyValue
+
+// Get returns the value of key and whether the map holds it.
+func (m OrderedMap) Get(key string) (any, bool) {
+	for _, kv := range m {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// MarshalJSON writes m as a JSON object with its keys in order.
+func (m OrderedMap) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, kv := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeOrderedMap decodes a block into the OrderedMap v, with arrays as
+// []any. A repeated key keeps its first position and takes the later
+// value.
+func (d *decoder) decodeOrderedMap(v reflect.Value, expectedIndent int) error {
+	saved := d.ordered
+	d.ordered = true
+	defer func() { d.ordered = saved }()
+
+	m := OrderedMap{}
+	index := make(map[string]int)
+	folds := make(map[string][]foldedColumn)
+
+	err := d.decodeEntries(expectedIndent, func(key, value string, indent int) error {
+		d.advance()
+		length, fields, delim := d.parseArrayDeclaration(key)
+		if length != notArray {
+			key = d.extractKeyFromArray(key)
+		} else if table, column, ok := strings.Cut(key, foldedColumnSep); ok {
+			folds[table] = append(folds[table], foldedColumn{column: column, value: value, line: d.pos})
+			return nil
+		}
+		d.pushPath(key)
+		defer d.popPath()
+
+		var elem any
+		if length != notArray {
+			d.folded = folds[key]
+			var err error
+			if elem, err = d.decodeDynamicArray(length, fields, delim, value, indent); err != nil {
+				return err
+			}
+			d.folded = nil
+		} else if value == "" {
+			nested := OrderedMap{}
+			if err := d.decodeValue(reflect.ValueOf(&nested).Elem(), indent+2); err != nil {
+				return err
+			}
+			elem = nested
+		} else if err := d.setValue(reflect.ValueOf(&elem).Elem(), value); err != nil {
+			return err
+		}
+
+		if i, ok := index[key]; ok {
+			d.warn(d.pos, "duplicate key %q overrides earlier value", key)
+			m[i].Value = elem
+			return nil
+		}
+		index[key] = len(m)
+		m = append(m, KeyValue{Key: key, Value: elem})
+		return nil
+	})
+	v.Set(reflect.ValueOf(m))
+	return err
+}
+
+// orderedTableKeys returns the keys of the OrderedMaps held by the slice v
+// when all of them have the same keys in the same order and no nested
+// values, so that v can be written as a table.
+func (e *encoder) orderedTableKeys(v reflect.Value) ([]string, bool) {
+	var keys []string
+	for i := 0; i < v.Len(); i++ {
+		elem := derefValue(v.Index(i))
+		if !elem.IsValid() || elem.Type() != orderedMapType {
+			return nil, false
+		}
+		m := elem.Interface().(OrderedMap)
+		if len(m) == 0 || i > 0 && len(m) != len(keys) {
+			return nil, false
+		}
+		for j, kv := range m {
+			if i == 0 {
+				keys = append(keys, kv.Key)
+			} else if kv.Key != keys[j] {
+				return nil, false
+			}
+			if e.isNested(reflect.ValueOf(kv.Value)) {
+				return nil, false
+			}
+		}
+	}
+	return keys, true
+}
+
+// encodeOrderedTable writes the OrderedMaps held by v as the rows of a
+// table with the columns keys.
+func (e *encoder) encodeOrderedTable(v reflect.Value, keys []string, depth int, key string) error {
+	e.writeIndent(depth)
+	e.writeHeader(key, v.Len())
+	e.writeHeaderFields(keys)
+
+	for i := 0; i < v.Len(); i++ {
+		e.writeIndent(depth + 1)
+		e.pushIndex(i)
+		for j, kv := range derefValue(v.Index(i)).Interface().(OrderedMap) {
+			if j > 0 {
+				e.buf.WriteString(string(e.opts.Delimiter))
+			}
+			e.pushPath(kv.Key)
+			if err := e.writeLeafValue(reflect.ValueOf(&kv.Value).Elem()); err != nil {
+				return err
+			}
+			e.popPath()
+		}
+		e.popPath()
+		e.buf.WriteString("\n")
+	}
+	return nil
+}
+
+// encodeOrderedMap writes the OrderedMap v as a block with its keys in
+// order.
+func (e *encoder) encodeOrderedMap(v reflect.Value, depth int, key string) error {
+	if key != "" {
+		e.writeIndent(depth)
+		e.buf.WriteString(key)
+		e.buf.WriteString(":\n")
+		depth++
+	}
+
+	for _, kv := range v.Interface().(OrderedMap) {
+		if err := checkMapKey(kv.Key); err != nil {
+			return err
+		}
+		e.pushPath(kv.Key)
+		if err := e.encodeValue(reflect.ValueOf(&kv.Value).Elem(), depth, e.prefixKey(depth, kv.Key)); err != nil {
+			return err
+		}
+		e.popPath()
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package toon_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestOrderedMap(t *testing.T) {
+	input := "zeta: 1\nalpha:\n  sunny: true\n  name: \"Blue Lake\"\n  empty:\nmid: 2.5\nzeta: 3\n"
+
+	var warnings []toon.Warning
+	opts := toon.DefaultUnmarshalOptions()
+	opts.Warnings = &warnings
+	var m toon.OrderedMap
+	if err := toon.UnmarshalWithOptions([]byte(input), &m, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	want := toon.OrderedMap{
+		{Key: "zeta", Value: int64(3)},
+		{Key: "alpha", Value: toon.OrderedMap{
+			{Key: "sunny", Value: true},
+			{Key: "name", Value: "Blue Lake"},
+			{Key: "empty", Value: toon.OrderedMap{}},
+		}},
+		{Key: "mid", Value: 2.5},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("Unmarshal = %#v, want %#v", m, want)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("warnings = %v, want one for the duplicate key", warnings)
+	}
+	if v, ok := m.Get("mid"); !ok || v != 2.5 {
+		t.Errorf("Get(mid) = %v, %v", v, ok)
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	if got := `{"zeta":3,"alpha":{"sunny":true,"name":"Blue Lake","empty":{}},"mid":2.5}`; string(data) != got {
+		t.Errorf("json.Marshal = %s, want %s", data, got)
+	}
+
+	data, err = toon.Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if got := "zeta: 3\nalpha:\n  sunny: true\n  name: Blue Lake\n  empty:\nmid: 2.5\n"; string(data) != got {
+		t.Errorf("Marshal = %q, want %q", data, got)
+	}
+}
+
+func TestOrderedMapArrays(t *testing.T) {
+	input := `tags[2]: lake,"ridge, north"
+hikes[2]{name,km}:
+  Blue Lake,7.5
+  Saddle,
+stops[2]:
+  - name: hut
+    heights[1]: 2100
+  - [2]: 1,2
+byName[1]{_key,km}:
+  blue,7.5
+`
+	var m toon.OrderedMap
+	if err := toon.Unmarshal([]byte(input), &m); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	want := toon.OrderedMap{
+		{Key: "tags", Value: []any{"lake", "ridge, north"}},
+		{Key: "hikes", Value: []any{
+			toon.OrderedMap{{Key: "name", Value: "Blue Lake"}, {Key: "km", Value: 7.5}},
+			toon.OrderedMap{{Key: "name", Value: "Saddle"}, {Key: "km", Value: nil}},
+		}},
+		{Key: "stops", Value: []any{
+			toon.OrderedMap{{Key: "name", Value: "hut"}, {Key: "heights", Value: []any{int64(2100)}}},
+			[]any{int64(1), int64(2)},
+		}},
+		{Key: "byName", Value: toon.OrderedMap{
+			{Key: "blue", Value: toon.OrderedMap{{Key: "km", Value: 7.5}}},
+		}},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("Unmarshal = %#v, want %#v", m, want)
+	}
+
+	// Uniform rows are written back as a table
+	data, err := toon.Marshal(toon.OrderedMap{want[1]})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if got := "hikes[2]{name,km}:\n  Blue Lake,7.5\n  Saddle,null\n"; string(data) != got {
+		t.Errorf("Marshal = %q, want %q", data, got)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/l00pss/gotoon/protoadapt
+
+go 1.25
+
+require (
+	github.com/l00pss/gotoon v0.0.0-20261016020315-a13ad6b5c93e
+	google.golang.org/protobuf v1.36.6
+)
+
+replace github.com/l00pss/gotoon => ../
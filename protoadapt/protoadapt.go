@@ -0,0 +1,332 @@
+// Package protoadapt writes protocol buffer messages as TOON and reads them
+// back, for services whose payloads are defined in .proto files rather than
+// Go structs. Messages are converted through protoreflect into structs
+// built from their descriptors, so repeated messages become tables like
+// slices of structs do:
+//
+//	data, err := protoadapt.Marshal(resp)
+//	...
+//	var req pb.SearchRequest
+//	err = protoadapt.Unmarshal(data, &req)
+//
+// Fields are keyed by their JSON names, as protojson writes them, and
+// fields that are not set are left out. Enums are written by value name,
+// bytes as base64 and 64-bit integers as numbers. Well-known types such as
+// google.protobuf.Timestamp are written as the messages they are. Messages
+// that contain themselves, directly or through other messages, are not
+// supported.
+//
+// The package is a module of its own, so that the toon package does not
+// depend on the protobuf runtime.
+package protoadapt
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
+	toon "github.com/l00pss/gotoon"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Marshal writes m as a TOON document with default options, optionally
+// overridden.
+func Marshal(m proto.Message, options ...toon.MarshalOption) ([]byte, error) {
+	opts := toon.DefaultMarshalOptions()
+	for _, option := range options {
+		if err := option(&opts); err != nil {
+			return nil, err
+		}
+	}
+	return MarshalWithOptions(m, opts)
+}
+
+// MarshalWithOptions writes m as a TOON document.
+func MarshalWithOptions(m proto.Message, opts toon.MarshalOptions) ([]byte, error) {
+	msg := m.ProtoReflect()
+	t, err := structType(msg.Descriptor())
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.New(t).Elem()
+	if err := toStruct(msg, v); err != nil {
+		return nil, err
+	}
+	return toon.MarshalWithOptions(v.Interface(), opts)
+}
+
+// Unmarshal reads a document into m with default options. m is reset
+// first, as proto.Unmarshal does.
+func Unmarshal(data []byte, m proto.Message) error {
+	return UnmarshalWithOptions(data, m, toon.DefaultUnmarshalOptions())
+}
+
+// UnmarshalWithOptions reads a document into m, which is reset first.
+func UnmarshalWithOptions(data []byte, m proto.Message, opts toon.UnmarshalOptions) error {
+	t, err := structType(m.ProtoReflect().Descriptor())
+	if err != nil {
+		return err
+	}
+	v := reflect.New(t)
+	if err := toon.UnmarshalWithOptions(data, v.Interface(), opts); err != nil {
+		return err
+	}
+	proto.Reset(m)
+	return fromStruct(v.Elem(), m.ProtoReflect())
+}
+
+var structTypes sync.Map // protoreflect.MessageDescriptor -> reflect.Type
+
+// structType returns the struct type standing for messages of md: one
+// field per message field, in declaration order.
+func structType(md protoreflect.MessageDescriptor) (reflect.Type, error) {
+	if t, ok := structTypes.Load(md); ok {
+		return t.(reflect.Type), nil
+	}
+	t, err := buildStructType(md, make(map[protoreflect.FullName]bool))
+	if err != nil {
+		return nil, err
+	}
+	structTypes.Store(md, t)
+	return t, nil
+}
+
+// buildStructType builds the struct type of md. visiting holds the
+// messages being built, to reject recursive ones that a struct type
+// cannot hold.
+func buildStructType(md protoreflect.MessageDescriptor, visiting map[protoreflect.FullName]bool) (reflect.Type, error) {
+	if visiting[md.FullName()] {
+		return nil, fmt.Errorf("protoadapt: recursive message %s is not supported", md.FullName())
+	}
+	visiting[md.FullName()] = true
+	defer delete(visiting, md.FullName())
+
+	fields := md.Fields()
+	structFields := make([]reflect.StructField, fields.Len())
+	for i := range structFields {
+		fd := fields.Get(i)
+		t, err := fieldType(fd, visiting)
+		if err != nil {
+			return nil, err
+		}
+		structFields[i] = reflect.StructField{
+			Name: "F" + strconv.Itoa(int(fd.Number())),
+			Type: t,
+			Tag:  reflect.StructTag(fmt.Sprintf(`toon:"%s,omitempty"`, fd.JSONName())),
+		}
+	}
+	return reflect.StructOf(structFields), nil
+}
+
+// fieldType returns the Go type of the field fd. Singular fields that
+// track presence, messages among them, are pointers, nil when not set.
+func fieldType(fd protoreflect.FieldDescriptor, visiting map[protoreflect.FullName]bool) (reflect.Type, error) {
+	switch {
+	case fd.IsMap():
+		key, err := valueType(fd.MapKey(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := valueType(fd.MapValue(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(key, elem), nil
+	case fd.IsList():
+		elem, err := valueType(fd, visiting)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+	}
+
+	t, err := valueType(fd, visiting)
+	if err != nil {
+		return nil, err
+	}
+	if fd.HasPresence() {
+		return reflect.PointerTo(t), nil
+	}
+	return t, nil
+}
+
+// valueType returns the Go type of a single value of fd.
+func valueType(fd protoreflect.FieldDescriptor, visiting map[protoreflect.FullName]bool) (reflect.Type, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return reflect.TypeOf(false), nil
+	case protoreflect.EnumKind, protoreflect.StringKind:
+		return reflect.TypeOf(""), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return reflect.TypeOf(int32(0)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return reflect.TypeOf(int64(0)), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return reflect.TypeOf(uint32(0)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return reflect.TypeOf(uint64(0)), nil
+	case protoreflect.FloatKind:
+		return reflect.TypeOf(float32(0)), nil
+	case protoreflect.DoubleKind:
+		return reflect.TypeOf(float64(0)), nil
+	case protoreflect.BytesKind:
+		return reflect.TypeOf([]byte(nil)), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return buildStructType(fd.Message(), visiting)
+	}
+	return nil, fmt.Errorf("protoadapt: field %s has unsupported kind %v", fd.FullName(), fd.Kind())
+}
+
+// toStruct copies the fields set in msg into the struct v.
+func toStruct(msg protoreflect.Message, v reflect.Value) error {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !msg.Has(fd) {
+			continue
+		}
+		fv := v.Field(i)
+		pv := msg.Get(fd)
+
+		switch {
+		case fd.IsMap():
+			fv.Set(reflect.MakeMapWithSize(fv.Type(), pv.Map().Len()))
+			var err error
+			pv.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				elem := reflect.New(fv.Type().Elem()).Elem()
+				if err = toValue(fd.MapValue(), mv, elem); err != nil {
+					return false
+				}
+				fv.SetMapIndex(reflect.ValueOf(k.Interface()), elem)
+				return true
+			})
+			if err != nil {
+				return err
+			}
+		case fd.IsList():
+			list := pv.List()
+			fv.Set(reflect.MakeSlice(fv.Type(), list.Len(), list.Len()))
+			for j := 0; j < list.Len(); j++ {
+				if err := toValue(fd, list.Get(j), fv.Index(j)); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := toValue(fd, pv, fv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// toValue stores the single value pv of fd in v, allocating v first when
+// it is a pointer.
+func toValue(fd protoreflect.FieldDescriptor, pv protoreflect.Value, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		v.SetString(enumName(fd.Enum(), pv.Enum()))
+	case protoreflect.BytesKind:
+		v.SetBytes(append([]byte(nil), pv.Bytes()...))
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return toStruct(pv.Message(), v)
+	default:
+		v.Set(reflect.ValueOf(pv.Interface()))
+	}
+	return nil
+}
+
+// fromStruct sets the fields of msg from the struct v. Nil pointers,
+// empty lists and maps, and zero values of fields without presence leave
+// their field unset.
+func fromStruct(v reflect.Value, msg protoreflect.Message) error {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		fv := v.Field(i)
+		if fv.IsZero() {
+			continue
+		}
+
+		switch {
+		case fd.IsMap():
+			m := msg.Mutable(fd).Map()
+			iter := fv.MapRange()
+			for iter.Next() {
+				pv, err := fromValue(fd.MapValue(), iter.Value(), m.NewValue)
+				if err != nil {
+					return err
+				}
+				m.Set(protoreflect.ValueOf(iter.Key().Interface()).MapKey(), pv)
+			}
+		case fd.IsList():
+			list := msg.Mutable(fd).List()
+			for j := 0; j < fv.Len(); j++ {
+				pv, err := fromValue(fd, fv.Index(j), list.NewElement)
+				if err != nil {
+					return err
+				}
+				list.Append(pv)
+			}
+		default:
+			pv, err := fromValue(fd, fv, func() protoreflect.Value { return msg.NewField(fd) })
+			if err != nil {
+				return err
+			}
+			msg.Set(fd, pv)
+		}
+	}
+	return nil
+}
+
+// fromValue returns the single value of fd held in v. Messages are read
+// into a value from newValue.
+func fromValue(fd protoreflect.FieldDescriptor, v reflect.Value, newValue func() protoreflect.Value) (protoreflect.Value, error) {
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		n, err := enumNumber(fd.Enum(), v.String())
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("protoadapt: field %s: %w", fd.FullName(), err)
+		}
+		return protoreflect.ValueOfEnum(n), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes(v.Bytes()), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		pv := newValue()
+		return pv, fromStruct(v, pv.Message())
+	}
+	return protoreflect.ValueOf(v.Interface()), nil
+}
+
+// enumName returns the name of the value n of ed, or n in decimal when
+// ed has no such value.
+func enumName(ed protoreflect.EnumDescriptor, n protoreflect.EnumNumber) string {
+	if ev := ed.Values().ByNumber(n); ev != nil {
+		return string(ev.Name())
+	}
+	return strconv.Itoa(int(n))
+}
+
+// enumNumber returns the number of the value of ed named s, or written as
+// a decimal number.
+func enumNumber(ed protoreflect.EnumDescriptor, s string) (protoreflect.EnumNumber, error) {
+	if ev := ed.Values().ByName(protoreflect.Name(s)); ev != nil {
+		return ev.Number(), nil
+	}
+	n, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a value of %s", s, ed.FullName())
+	}
+	return protoreflect.EnumNumber(n), nil
+}
@@ -0,0 +1,97 @@
+package protoadapt_test
+
+import (
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+	"github.com/l00pss/gotoon/protoadapt"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/sourcecontextpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestRoundTrip(t *testing.T) {
+	in := &typepb.Type{
+		Name: "trails.Hike",
+		Fields: []*typepb.Field{
+			{Kind: typepb.Field_TYPE_STRING, Cardinality: typepb.Field_CARDINALITY_OPTIONAL, Number: 1, Name: "name", JsonName: "name"},
+			{Kind: typepb.Field_TYPE_DOUBLE, Cardinality: typepb.Field_CARDINALITY_OPTIONAL, Number: 2, Name: "distance_km", JsonName: "distanceKm"},
+			{
+				Kind: typepb.Field_TYPE_STRING, Cardinality: typepb.Field_CARDINALITY_REPEATED, Number: 3, Name: "tags",
+				Options: []*typepb.Option{{Name: "packed", Value: &anypb.Any{TypeUrl: "type.googleapis.com/google.protobuf.BoolValue", Value: []byte{8, 1}}}},
+			},
+		},
+		Oneofs:        []string{"route", "area"},
+		SourceContext: &sourcecontextpb.SourceContext{FileName: "trails/hike.proto"},
+		Syntax:        typepb.Syntax_SYNTAX_PROTO3,
+	}
+
+	data, err := protoadapt.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	for _, want := range []string{
+		"name: trails.Hike\n",
+		"oneofs[2]: route,area\n",
+		"sourceContext:\n  fileName: trails/hike.proto\n",
+		"syntax: SYNTAX_PROTO3\n",
+		"kind: TYPE_DOUBLE",
+		"jsonName: distanceKm",
+		"typeUrl: type.googleapis.com/google.protobuf.BoolValue",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Marshal output lacks %q:\n%s", want, data)
+		}
+	}
+	// Fields that are not set are left out
+	if strings.Contains(string(data), "edition") || strings.Contains(string(data), "packed:") {
+		t.Errorf("Marshal wrote unset fields:\n%s", data)
+	}
+
+	out := &typepb.Type{Name: "stale", Oneofs: []string{"stale"}}
+	if err := protoadapt.Unmarshal(data, out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !proto.Equal(out, in) {
+		t.Fatalf("Unmarshal = %v, want %v", out, in)
+	}
+}
+
+func TestEnumsByNumber(t *testing.T) {
+	var out typepb.Field
+	if err := protoadapt.Unmarshal([]byte("kind: 9\nname: x\n"), &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Kind != typepb.Field_TYPE_STRING {
+		t.Errorf("Kind = %v, want TYPE_STRING", out.Kind)
+	}
+
+	err := protoadapt.Unmarshal([]byte("kind: TYPE_UNKNOWN_THING\n"), &out)
+	if err == nil || !strings.Contains(err.Error(), "google.protobuf.Field.Kind") {
+		t.Errorf("err = %v, want one naming the enum", err)
+	}
+}
+
+func TestMarshalOptions(t *testing.T) {
+	in := &typepb.Type{Name: "trails.Hike", Oneofs: []string{"route", "area"}}
+	data, err := protoadapt.Marshal(in, toon.WithDelimiter(toon.DelimiterPipe))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := "name: trails.Hike\noneofs[2|]: route|area\n"; string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+}
+
+func TestRecursiveMessages(t *testing.T) {
+	s, err := structpb.NewStruct(map[string]any{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := protoadapt.Marshal(s); err == nil || !strings.Contains(err.Error(), "recursive message") {
+		t.Errorf("err = %v, want a recursive message error", err)
+	}
+}
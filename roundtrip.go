@@ -0,0 +1,149 @@
+package toon
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type Difference struct {
+	Path     string
+	Original any
+	Decoded  any
+}
+
+func (d Difference) String() string {
+	path := d.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return fmt.Sprintf("%s: %#v != %#v", path, d.Original, d.Decoded)
+}
+
+// RoundTripEqual marshals v with opts, unmarshals the result into a fresh
+// value of the same type and reports every path at which the two differ.
+// Unexported fields are not compared, and nil and empty collections are
+// considered equal.
+func RoundTripEqual(v any, opts MarshalOptions) (bool, []Difference, error) {
+	data, err := MarshalWithOptions(v, opts)
+	if err != nil {
+		return false, nil, err
+	}
+
+	original := reflect.ValueOf(v)
+	if !original.IsValid() {
+		return true, nil, nil
+	}
+
+	decoded := reflect.New(original.Type())
+	uopts := DefaultUnmarshalOptions()
+	uopts.Delimiter = opts.Delimiter
+	if err := UnmarshalWithOptions(data, decoded.Interface(), uopts); err != nil {
+		return false, nil, err
+	}
+
+	diffs := diffValues("", original, decoded.Elem(), nil)
+	return len(diffs) == 0, diffs, nil
+}
+
+func diffValues(path string, a, b reflect.Value, diffs []Difference) []Difference {
+	a = derefValue(a)
+	b = derefValue(b)
+
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() && !(isEmptyCollection(a) || isEmptyCollection(b)) {
+			diffs = append(diffs, newDifference(path, a, b))
+		}
+		return diffs
+	}
+
+	if a.Type() != b.Type() {
+		return append(diffs, newDifference(path, a, b))
+	}
+
+	switch a.Kind() {
+	case reflect.Struct:
+		for _, field := range structFields(a.Type()) {
+			fa, okA := fieldByIndex(a, field.index)
+			fb, okB := fieldByIndex(b, field.index)
+			if !okA && !okB {
+				continue
+			}
+			diffs = diffValues(joinPath(path, field.name), fa, fb, diffs)
+		}
+	case reflect.Map:
+		keys := a.MapKeys()
+		for _, k := range b.MapKeys() {
+			if !a.MapIndex(k).IsValid() {
+				keys = append(keys, k)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			diffs = diffValues(joinPath(path, fmt.Sprint(k.Interface())), a.MapIndex(k), b.MapIndex(k), diffs)
+		}
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return append(diffs, Difference{
+				Path:     path,
+				Original: fmt.Sprintf("%d items", a.Len()),
+				Decoded:  fmt.Sprintf("%d items", b.Len()),
+			})
+		}
+		for i := 0; i < a.Len(); i++ {
+			diffs = diffValues(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i), diffs)
+		}
+	default:
+		if !a.CanInterface() || !b.CanInterface() {
+			return diffs
+		}
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			diffs = append(diffs, newDifference(path, a, b))
+		}
+	}
+	return diffs
+}
+
+// derefValue follows pointers and interfaces, returning the zero Value for
+// nil ones.
+func derefValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isEmptyCollection(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return false
+}
+
+func newDifference(path string, a, b reflect.Value) Difference {
+	return Difference{Path: path, Original: interfaceOf(a), Decoded: interfaceOf(b)}
+}
+
+func interfaceOf(v reflect.Value) any {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func joinPath(path, segment string) string {
+	if path == "" {
+		return segment
+	}
+	if strings.HasPrefix(segment, "[") {
+		return path + segment
+	}
+	return path + "." + segment
+}
@@ -0,0 +1,42 @@
+package toon_test
+
+import (
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestRoundTripEqual(t *testing.T) {
+	data := HikesData{
+		Context: Context{Task: "Our favorite hikes together", Location: "Boulder", Season: "spring_2025"},
+		Friends: []string{"ana", "luis", "sam"},
+		Hikes: []Hike{
+			{ID: 1, Name: "Blue Lake Trail", DistanceKm: 7.5, ElevationGain: 320, Companion: "ana", WasSunny: true},
+			{ID: 2, Name: "Ridge Overlook", DistanceKm: 9.2, ElevationGain: 540, Companion: "luis", WasSunny: false},
+		},
+	}
+
+	equal, diffs, err := toon.RoundTripEqual(data, toon.DefaultMarshalOptions())
+	if err != nil {
+		t.Fatalf("RoundTripEqual failed: %v", err)
+	}
+	if !equal || len(diffs) != 0 {
+		t.Errorf("Expected lossless round trip, got %v", diffs)
+	}
+
+	lossy := struct {
+		Name  string `toon:"name"`
+		Value any    `toon:"value"`
+	}{Name: "count", Value: 3}
+
+	equal, diffs, err = toon.RoundTripEqual(lossy, toon.DefaultMarshalOptions())
+	if err != nil {
+		t.Fatalf("RoundTripEqual failed: %v", err)
+	}
+	if equal || len(diffs) != 1 {
+		t.Fatalf("Expected one difference, got %v", diffs)
+	}
+	if diffs[0].Path != "value" || diffs[0].Original != 3 || diffs[0].Decoded != int64(3) {
+		t.Errorf("Unexpected difference: %v", diffs[0])
+	}
+}
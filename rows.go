@@ -0,0 +1,86 @@
+package toon
+
+import (
+	"database/sql"
+	"reflect"
+	"unicode/utf8"
+)
+
+// EncodeRows writes the result of a query as a table under key, with the
+// column names as its header, so database snapshots go into prompts
+// without declaring a struct for them. Rows are scanned and written one
+// at a time until the result is exhausted, which closes rows. Cells are
+// written as Marshal writes the values the driver returns: NULL as null,
+// and bytes holding valid UTF-8, as text columns often arrive, as strings.
+// TransformValue sees cells at paths such as key[2].column.
+func EncodeRows(rows *sql.Rows, key string, options ...MarshalOption) ([]byte, error) {
+	opts := DefaultMarshalOptions()
+	for _, option := range options {
+		if err := option(&opts); err != nil {
+			return nil, err
+		}
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	e := newEncoder(opts)
+	key = e.prefixKey(0, key)
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = e.fieldKey(column)
+	}
+
+	return e.writeDocument(func() error {
+		// Rows go to their own buffer until the header can give their count
+		body := newEncoder(opts)
+		body.ids = e.ids
+		body.pushPath(key)
+
+		cells := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range cells {
+			dest[i] = &cells[i]
+		}
+		n := 0
+		for ; rows.Next(); n++ {
+			if err := rows.Scan(dest...); err != nil {
+				return err
+			}
+			body.writeIndent(1)
+			body.pushIndex(n)
+			for i, cell := range cells {
+				if i > 0 {
+					body.buf.WriteString(string(opts.Delimiter))
+				}
+				if b, ok := cell.([]byte); ok && utf8.Valid(b) {
+					cell = string(b)
+				}
+				body.pushPath(names[i])
+				if err := body.writeLeafValue(reflect.ValueOf(&cell).Elem()); err != nil {
+					return err
+				}
+				body.popPath()
+			}
+			body.popPath()
+			body.buf.WriteString("\n")
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		e.writeHeader(key, n)
+		if n == 0 {
+			e.buf.WriteString(":\n")
+			return nil
+		}
+		e.writeHeaderFields(names)
+		e.buf.Write(body.buf.Bytes())
+		return nil
+	})
+}
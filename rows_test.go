@@ -0,0 +1,163 @@
+package toon_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+// tableDriver serves the rows of its tables to any query, the query text
+// naming the table.
+type tableDriver map[string]*table
+
+type table struct {
+	columns []string
+	rows    [][]driver.Value
+	err     error // returned after the rows
+}
+
+func (d tableDriver) Open(string) (driver.Conn, error) { return tableConn(d), nil }
+
+type tableConn tableDriver
+
+func (c tableConn) Prepare(query string) (driver.Stmt, error) {
+	t, ok := c[query]
+	if !ok {
+		return nil, errors.New("no such table")
+	}
+	return tableStmt{t}, nil
+}
+func (c tableConn) Close() error              { return nil }
+func (c tableConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type tableStmt struct{ t *table }
+
+func (s tableStmt) Close() error  { return nil }
+func (s tableStmt) NumInput() int { return 0 }
+func (s tableStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s tableStmt) Query([]driver.Value) (driver.Rows, error) { return &tableRows{t: s.t}, nil }
+
+type tableRows struct {
+	t *table
+	i int
+}
+
+func (r *tableRows) Columns() []string { return r.t.columns }
+func (r *tableRows) Close() error      { return nil }
+func (r *tableRows) Next(dest []driver.Value) error {
+	if r.i == len(r.t.rows) {
+		if r.t.err != nil {
+			return r.t.err
+		}
+		return io.EOF
+	}
+	copy(dest, r.t.rows[r.i])
+	r.i++
+	return nil
+}
+
+func init() {
+	sql.Register("toontables", tableDriver{
+		"hikes": {
+			columns: []string{"id", "name", "dist_km", "sunny", "notes", "started"},
+			rows: [][]driver.Value{
+				{int64(1), []byte("Blue Lake"), 7.5, true, nil, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+				{int64(2), "Ridge, north", 9.0, false, []byte("42"), time.Date(2024, 5, 2, 7, 30, 0, 0, time.UTC)},
+				{int64(3), "Saddle", 12.25, true, []byte{0xff, 0x00}, nil},
+			},
+		},
+		"empty":  {columns: []string{"id"}},
+		"broken": {columns: []string{"id"}, rows: [][]driver.Value{{int64(1)}}, err: errors.New("connection lost")},
+	})
+}
+
+func queryRows(t *testing.T, query string) *sql.Rows {
+	t.Helper()
+	db, err := sql.Open("toontables", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rows.Close() })
+	return rows
+}
+
+func TestEncodeRows(t *testing.T) {
+	data, err := toon.EncodeRows(queryRows(t, "hikes"), "hikes", toon.WithTimeFormat(time.DateOnly))
+	if err != nil {
+		t.Fatalf("EncodeRows failed: %v", err)
+	}
+	want := `hikes[3]{id,name,dist_km,sunny,notes,started}:
+  1,Blue Lake,7.5,true,null,2024-05-01
+  2,"Ridge, north",9,false,"42",2024-05-02
+  3,Saddle,12.25,true,/wA=,null
+`
+	if string(data) != want {
+		t.Fatalf("EncodeRows = %q, want %q", data, want)
+	}
+
+	type Hike struct {
+		ID     int     `toon:"id"`
+		Name   string  `toon:"name"`
+		DistKm float64 `toon:"dist_km"`
+	}
+	var out struct {
+		Hikes []Hike `toon:"hikes"`
+	}
+	if err := toon.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if wantHikes := []Hike{{1, "Blue Lake", 7.5}, {2, "Ridge, north", 9}, {3, "Saddle", 12.25}}; !reflect.DeepEqual(out.Hikes, wantHikes) {
+		t.Errorf("Unmarshal = %+v, want %+v", out.Hikes, wantHikes)
+	}
+}
+
+func TestEncodeRowsOptions(t *testing.T) {
+	var paths []string
+	data, err := toon.EncodeRows(queryRows(t, "hikes"), "hikes",
+		toon.WithDelimiter(toon.DelimiterPipe),
+		toon.WithKeyTranslator(func(name string) string { return strings.ReplaceAll(name, "_", "") }),
+		func(o *toon.MarshalOptions) error {
+			o.TransformValue = func(path string, v any) (any, error) {
+				paths = append(paths, path)
+				if strings.HasSuffix(path, ".started") {
+					return nil, nil
+				}
+				return v, nil
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("EncodeRows failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "hikes[3|]{id|name|distkm|sunny|notes|started}:\n  1|Blue Lake|7.5|true|null|null\n") {
+		t.Errorf("EncodeRows = %q", data)
+	}
+	if len(paths) != 18 || paths[0] != "hikes[0].id" || paths[17] != "hikes[2].started" {
+		t.Errorf("TransformValue saw %q", paths)
+	}
+}
+
+func TestEncodeRowsEmptyAndErrors(t *testing.T) {
+	data, err := toon.EncodeRows(queryRows(t, "empty"), "ids")
+	if err != nil || string(data) != "ids[0]:\n" {
+		t.Errorf("EncodeRows = %q, %v, want an empty array", data, err)
+	}
+
+	if _, err := toon.EncodeRows(queryRows(t, "broken"), "ids"); err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Errorf("err = %v, want the error ending the rows", err)
+	}
+}
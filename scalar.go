@@ -0,0 +1,176 @@
+package toon
+
+import (
+	"encoding"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DecimalCapable is implemented by exact decimal types such as
+// shopspring/decimal.Decimal, whose value is Coefficient × 10^Exponent.
+// Types implementing it, with a pointer that implements
+// encoding.TextUnmarshaler, are written as plain decimal numbers and read
+// back through UnmarshalText, so amounts never pass through float64.
+type DecimalCapable interface {
+	Coefficient() *big.Int
+	Exponent() int32
+}
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+	ipType       = reflect.TypeOf(net.IP{})
+	ipNetType    = reflect.TypeOf(net.IPNet{})
+	urlType      = reflect.TypeOf(url.URL{})
+
+	decimalType         = reflect.TypeOf((*DecimalCapable)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isScalarType reports whether values of the struct or slice type t are
+// written as a single value rather than as a nested block or array.
+func isScalarType(t reflect.Type) bool {
+	switch t {
+	case timeType, bigIntType, bigFloatType, bigRatType, ipType, ipNetType, urlType:
+		return true
+	}
+	// Predeclared and unnamed types other than structs have no methods
+	if t.PkgPath() == "" && t.Kind() != reflect.Struct {
+		return false
+	}
+	return isMarshalerType(t) || isDecimalType(t)
+}
+
+func isDecimalType(t reflect.Type) bool {
+	p := reflect.PointerTo(t)
+	return p.Implements(decimalType) && p.Implements(textUnmarshalerType)
+}
+
+func (e *encoder) writeScalarValue(v reflect.Value) {
+	switch v.Type() {
+	case timeType:
+		t := inLocation(v.Interface().(time.Time), e.opts.TimeLocation)
+		e.writeString(t.Format(timeLayout(e.opts.TimeFormat)))
+	case bigIntType:
+		e.buf.WriteString(addressable(v).Interface().(*big.Int).String())
+	case bigFloatType:
+		e.buf.WriteString(addressable(v).Interface().(*big.Float).Text('g', -1))
+	case bigRatType:
+		e.buf.WriteString(addressable(v).Interface().(*big.Rat).RatString())
+	case ipType:
+		if v.Len() == 0 {
+			e.buf.WriteString("null")
+			return
+		}
+		e.writeString(v.Interface().(net.IP).String())
+	case ipNetType:
+		e.writeString(addressable(v).Interface().(*net.IPNet).String())
+	case urlType:
+		e.writeString(addressable(v).Interface().(*url.URL).String())
+	default:
+		d := addressable(v).Interface().(DecimalCapable)
+		e.buf.WriteString(formatDecimal(d.Coefficient(), d.Exponent()))
+	}
+}
+
+// formatDecimal writes coef × 10^exp in plain positional notation.
+func formatDecimal(coef *big.Int, exp int32) string {
+	digits := new(big.Int).Abs(coef).String()
+	sign := ""
+	if coef.Sign() < 0 {
+		sign = "-"
+	}
+
+	if exp >= 0 {
+		if coef.Sign() == 0 {
+			return "0"
+		}
+		return sign + digits + strings.Repeat("0", int(exp))
+	}
+
+	scale := int(-exp)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	point := len(digits) - scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func (d *decoder) setScalarValue(v reflect.Value, raw, s string) error {
+	if raw == "null" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		t, err := time.Parse(timeLayout(d.opts.TimeFormat), s)
+		if err != nil {
+			return d.conversionError(raw, v.Type(), err)
+		}
+		v.Set(reflect.ValueOf(inLocation(t, d.opts.TimeLocation)))
+	case bigIntType:
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return d.typeError(raw, v.Type())
+		}
+		v.Set(reflect.ValueOf(n).Elem())
+	case bigFloatType:
+		// Keep at least as many bits as the digits written carry
+		f, ok := new(big.Float).SetPrec(max(64, 4*uint(len(s)))).SetString(s)
+		if !ok {
+			return d.typeError(raw, v.Type())
+		}
+		v.Set(reflect.ValueOf(f).Elem())
+	case bigRatType:
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return d.typeError(raw, v.Type())
+		}
+		v.Set(reflect.ValueOf(r).Elem())
+	case ipType:
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return d.typeError(raw, v.Type())
+		}
+		v.Set(reflect.ValueOf(ip))
+	case ipNetType:
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return d.conversionError(raw, v.Type(), err)
+		}
+		v.Set(reflect.ValueOf(network).Elem())
+	case urlType:
+		u, err := url.Parse(s)
+		if err != nil {
+			return d.conversionError(raw, v.Type(), err)
+		}
+		v.Set(reflect.ValueOf(u).Elem())
+	default:
+		// Types that only implement Marshaler cannot be read back
+		u, ok := v.Addr().Interface().(encoding.TextUnmarshaler)
+		if !ok {
+			return d.typeError(raw, v.Type())
+		}
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return d.conversionError(raw, v.Type(), err)
+		}
+	}
+	return nil
+}
+
+// addressable returns a pointer to v, copying v first if it cannot be
+// addressed, so pointer-receiver methods can be called on it.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v.Addr()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p
+}
@@ -0,0 +1,166 @@
+package toon_test
+
+import (
+	"errors"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+// decimal is a minimal shopspring/decimal-style type for testing.
+type decimal struct {
+	coef big.Int
+	exp  int32
+}
+
+func (d decimal) Coefficient() *big.Int { return new(big.Int).Set(&d.coef) }
+
+func (d decimal) Exponent() int32 { return d.exp }
+
+func (d *decimal) UnmarshalText(text []byte) error {
+	s := string(text)
+	d.exp = 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		d.exp = -int32(len(s) - i - 1)
+		s = s[:i] + s[i+1:]
+	}
+	if _, ok := d.coef.SetString(s, 10); !ok {
+		return errors.New("invalid decimal " + string(text))
+	}
+	return nil
+}
+
+// TestScalarTypes writes values of types held as a single scalar and
+// checks that what decodes from the document writes the same document,
+// as these types do not compare with reflect.DeepEqual.
+func TestScalarTypes(t *testing.T) {
+	type Ledger struct {
+		Balance *big.Int   `toon:"balance"`
+		Rate    *big.Float `toon:"rate"`
+		Share   big.Rat    `toon:"share"`
+		Missing *big.Int   `toon:"missing"`
+	}
+	type Line struct {
+		Item   string  `toon:"item"`
+		Amount decimal `toon:"amount"`
+	}
+	type Invoice struct {
+		Lines []Line `toon:"lines"`
+	}
+	type Signal struct {
+		Phase   complex128   `toon:"phase"`
+		Small   complex64    `toon:"small"`
+		Samples []complex128 `toon:"samples"`
+	}
+	type Host struct {
+		Name    string    `toon:"name"`
+		Addr    net.IP    `toon:"addr"`
+		Subnet  net.IPNet `toon:"subnet"`
+		Gateway net.IP    `toon:"gateway"`
+		Admin   *url.URL  `toon:"admin"`
+	}
+	type Inventory struct {
+		Hosts []Host   `toon:"hosts"`
+		DNS   []net.IP `toon:"dns"`
+	}
+	type Blob struct {
+		Name string `toon:"name"`
+		Data []byte `toon:"data"`
+	}
+	type Store struct {
+		Blobs  []Blob  `toon:"blobs"`
+		Levels []uint8 `toon:"levels"`
+		Empty  []byte  `toon:"empty"`
+	}
+
+	balance, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	rate, _ := new(big.Float).SetPrec(200).SetString("3.14159265358979323846264338327950288")
+	ledger := Ledger{Balance: balance, Rate: rate}
+	ledger.Share.SetString("1/3")
+
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/24")
+	admin, _ := url.Parse("https://10.0.0.1:8443/ui?tab=a,b")
+
+	tests := []struct {
+		name string
+		in   any
+		opts []toon.MarshalOption
+		want string
+	}{
+		{
+			name: "big numbers",
+			in:   ledger,
+			want: "balance: 123456789012345678901234567890\nrate: 3.14159265358979323846264338327950288\nshare: 1/3\nmissing: null\n",
+		},
+		{
+			name: "decimals",
+			in: Invoice{Lines: []Line{
+				{Item: "coffee", Amount: decimal{coef: *big.NewInt(1999), exp: -2}},
+				{Item: "refund", Amount: decimal{coef: *big.NewInt(-5), exp: -3}},
+				{Item: "car", Amount: decimal{coef: *big.NewInt(12), exp: 3}},
+			}},
+			want: "lines[3]{item,amount}:\n  coffee,19.99\n  refund,-0.005\n  car,12000\n",
+		},
+		{
+			name: "complex numbers",
+			in:   Signal{Phase: complex(1.5, -2), Small: complex(0, 1), Samples: []complex128{complex(1, 2), complex(-3, 0.25)}},
+			want: "phase: 1.5-2i\nsmall: 0+1i\nsamples[2]: 1+2i,-3+0.25i\n",
+		},
+		{
+			name: "network types",
+			in: Inventory{
+				Hosts: []Host{
+					{Name: "web", Addr: net.ParseIP("10.0.0.5"), Subnet: *subnet, Admin: admin},
+					{Name: "db", Addr: net.ParseIP("fe80::1"), Subnet: *subnet, Gateway: net.ParseIP("10.0.0.1")},
+				},
+				DNS: []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("8.8.8.8")},
+			},
+			want: "hosts[2]{name,addr,subnet,gateway,admin}:\n" +
+				"  web,10.0.0.5,10.0.0.0/24,null,\"https://10.0.0.1:8443/ui?tab=a,b\"\n" +
+				"  db,fe80::1,10.0.0.0/24,10.0.0.1,null\n" +
+				"dns[2]: 1.1.1.1,8.8.8.8\n",
+		},
+		{
+			name: "byte slices",
+			in:   Store{Blobs: []Blob{{Name: "a", Data: []byte("hi")}, {Name: "b", Data: []byte{0xff, 0x00}}}, Levels: []uint8{1, 2, 3}},
+			want: "blobs[2]{name,data}:\n  a,aGk=\n  b,/wA=\nlevels: AQID\nempty: null\n",
+		},
+		{
+			name: "bytes as arrays",
+			in:   Store{Levels: []uint8{1, 2, 3}},
+			opts: []toon.MarshalOption{toon.WithBytesAsArray(true)},
+			want: "blobs[0]:\nlevels[3]: 1,2,3\nempty[0]:\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := toon.Marshal(tt.in, tt.opts...)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Fatalf("Marshal = %q, want %q", data, tt.want)
+			}
+
+			out := reflect.New(reflect.TypeOf(tt.in))
+			if err := toon.Unmarshal(data, out.Interface()); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			again, err := toon.Marshal(out.Elem().Interface(), tt.opts...)
+			if err != nil || string(again) != tt.want {
+				t.Errorf("Marshal of the decoded value = %q, %v, want %q", again, err, tt.want)
+			}
+		})
+	}
+
+	var bad Ledger
+	if err := toon.Unmarshal([]byte("balance: 12abc\n"), &bad); err == nil {
+		t.Error("expected error for malformed big.Int")
+	}
+}
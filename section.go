@@ -0,0 +1,106 @@
+package toon
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MarshalField encodes only the value at path within v, such as hikes,
+// context.season or hikes[2], as a document of its own: the value appears
+// under its key with the header it would have in the full document, so
+// prompts can quote individual sections. Elements picked by an index
+// have no key and are written like a document of their own. Paths follow
+// the names Marshal writes, including map keys, and FieldFilter and
+// TransformValue see the full paths. A path that leads nowhere fails with
+// an error wrapping ErrPathNotFound.
+func MarshalField(v any, path string, options ...MarshalOption) ([]byte, error) {
+	opts := DefaultMarshalOptions()
+	for _, option := range options {
+		if err := option(&opts); err != nil {
+			return nil, err
+		}
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	e := newEncoder(opts)
+	segments := splitPath(path)
+	value, err := e.resolve(reflect.ValueOf(v), segments)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, path)
+	}
+
+	key := ""
+	if last := segments[len(segments)-1]; !isIndexSegment(last) {
+		key = e.prefixKey(0, last)
+	}
+	return e.encodeDocument(value, key)
+}
+
+func isIndexSegment(segment string) bool {
+	_, ok := parseIndex(segment)
+	return ok
+}
+
+// resolve follows segments from v the way Marshal would write them,
+// leaving them on the encoder's path.
+func (e *encoder) resolve(v reflect.Value, segments []string) (reflect.Value, error) {
+	if len(segments) == 0 {
+		return reflect.Value{}, ErrPathNotFound
+	}
+
+	for _, segment := range segments {
+		v = derefValue(v)
+		if !v.IsValid() {
+			return reflect.Value{}, ErrPathNotFound
+		}
+
+		next, ok := e.child(v, segment)
+		if !ok {
+			return reflect.Value{}, ErrPathNotFound
+		}
+		if index, ok := parseIndex(segment); ok {
+			e.pushIndex(index)
+		} else {
+			e.pushPath(segment)
+		}
+		v = next
+	}
+	return v, nil
+}
+
+// child returns the field, map element or array element of v that segment
+// names.
+func (e *encoder) child(v reflect.Value, segment string) (reflect.Value, bool) {
+	if index, ok := parseIndex(segment); ok {
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && index < v.Len() {
+			return v.Index(index), true
+		}
+		return reflect.Value{}, false
+	}
+
+	switch {
+	case v.Type() == orderedMapType:
+		value, ok := v.Interface().(OrderedMap).Get(segment)
+		return reflect.ValueOf(&value).Elem(), ok
+	case v.Kind() == reflect.Struct && !isScalarType(v.Type()):
+		for _, field := range e.fields(v.Type()) {
+			if field.name != segment {
+				continue
+			}
+			fv, ok := field.valueOf(v)
+			if !ok {
+				return reflect.Value{}, false
+			}
+			return e.encodedFieldValue(field, fv), true
+		}
+	case v.Kind() == reflect.Map:
+		for _, k := range v.MapKeys() {
+			if mapKeyString(k) == segment {
+				return v.MapIndex(k), true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
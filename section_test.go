@@ -0,0 +1,68 @@
+package toon_test
+
+import (
+	"errors"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestMarshalField(t *testing.T) {
+	data := HikesData{
+		Context: Context{Task: "Our favorite hikes", Location: "Boulder", Season: "spring"},
+		Friends: []string{"ana", "luis"},
+		Hikes: []Hike{
+			{ID: 1, Name: "Blue Lake", DistanceKm: 7.5, ElevationGain: 320, Companion: "ana", WasSunny: true},
+			{ID: 2, Name: "Ridge", DistanceKm: 9, ElevationGain: 540, Companion: "luis"},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"hikes", "hikes[2]{id,name,distanceKm,elevationGain,companion,wasSunny}:\n  1,Blue Lake,7.5,320,ana,true\n  2,Ridge,9,540,luis,false\n"},
+		{"context", "context:\n  task: Our favorite hikes\n  location: Boulder\n  season: spring\n"},
+		{"context.season", "season: spring\n"},
+		{"friends", "friends[2]: ana,luis\n"},
+		{"hikes[1]", "id: 2\nname: Ridge\ndistanceKm: 9\nelevationGain: 540\ncompanion: luis\nwasSunny: false\n"},
+		{"hikes[0].name", "name: Blue Lake\n"},
+	}
+	for _, tt := range tests {
+		got, err := toon.MarshalField(data, tt.path)
+		if err != nil {
+			t.Errorf("MarshalField(%s) failed: %v", tt.path, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("MarshalField(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	// Options apply as in Marshal, and callbacks see full paths
+	var paths []string
+	got, err := toon.MarshalField(&data, "friends", toon.WithDelimiter(toon.DelimiterPipe), func(o *toon.MarshalOptions) error {
+		o.TransformValue = func(path string, v any) (any, error) {
+			paths = append(paths, path)
+			return v, nil
+		}
+		return nil
+	})
+	if err != nil || string(got) != "friends[2|]: ana|luis\n" {
+		t.Errorf("MarshalField with options = %q, %v", got, err)
+	}
+	if len(paths) != 3 || paths[1] != "friends[0]" {
+		t.Errorf("TransformValue paths = %q", paths)
+	}
+
+	nested := map[string]map[string]int{"stats": {"km": 7}}
+	if got, err := toon.MarshalField(nested, "stats.km"); err != nil || string(got) != "km: 7\n" {
+		t.Errorf("MarshalField into a map = %q, %v", got, err)
+	}
+
+	for _, path := range []string{"", "missing", "hikes[5]", "context.season.x"} {
+		if _, err := toon.MarshalField(data, path); !errors.Is(err, toon.ErrPathNotFound) {
+			t.Errorf("MarshalField(%q) = %v, want ErrPathNotFound", path, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package toon
+
+import (
+	"reflect"
+	"sync"
+)
+
+// rowSample is the number of table rows written before the buffer is
+// grown for the rest of the table.
+const rowSample = 16
+
+var sizeHints sync.Map // reflect.Type -> [2]int, the last two sizes
+
+// growFor grows the buffer to hold a document about as large as the last
+// ones encoded from values of type t, so repeated encoding of the same type
+// allocates its buffer once. The smaller of the last two sizes is used, so
+// one large document does not make every later buffer large.
+func (e *encoder) growFor(t reflect.Type) {
+	if sizes, ok := sizeHints.Load(t); ok {
+		n := min(sizes.([2]int)[0], sizes.([2]int)[1])
+		e.buf.Grow(n + n/8)
+	}
+}
+
+// recordSize remembers the size of the document just encoded from a value
+// of type t for growFor.
+func (e *encoder) recordSize(t reflect.Type) {
+	last := e.buf.Len()
+	if sizes, ok := sizeHints.Load(t); ok {
+		last = sizes.([2]int)[1]
+	}
+	sizeHints.Store(t, [2]int{last, e.buf.Len()})
+}
+
+// growForRows grows the buffer for the rest of a table of total rows once
+// the first rowSample rows, written from start on, show how large rows are.
+func (e *encoder) growForRows(start, written, total int) {
+	if written != rowSample || total <= written {
+		return
+	}
+	perRow := (e.buf.Len() - start) / written
+	e.buf.Grow(perRow * (total - written))
+}
@@ -0,0 +1,63 @@
+package toon_test
+
+import (
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestMarshalRepeatedLargeTable(t *testing.T) {
+	type Row struct {
+		ID   int    `toon:"id"`
+		Name string `toon:"name"`
+	}
+	rows := make([]Row, 500)
+	for i := range rows {
+		rows[i] = Row{ID: i, Name: strings.Repeat("x", i%7)}
+	}
+
+	first, err := toon.Marshal(map[string][]Row{"rows": rows})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := string(first)
+
+	// Later documents of the same type start from a pre-grown buffer and
+	// must neither differ nor share memory with earlier ones
+	second, err := toon.Marshal(map[string][]Row{"rows": rows})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	small, err := toon.Marshal(map[string][]Row{"rows": rows[:1]})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(first) != want || string(second) != want {
+		t.Errorf("repeated Marshal output changed")
+	}
+	if string(small) != "rows[1]{id,name}:\n  0,\"\"\n" {
+		t.Errorf("Marshal = %q", small)
+	}
+	if strings.Count(want, "\n") != 501 {
+		t.Errorf("Marshal wrote %d lines, want 501", strings.Count(want, "\n"))
+	}
+
+	// The buffer is sized up front, so a larger table costs no more
+	// allocations than a small one, where doubling would cost several
+	type Reading struct {
+		ID int `toon:"id"`
+		Km int `toon:"km"`
+	}
+	allocs := func(n int) float64 {
+		readings := make([]Reading, n)
+		for i := range readings {
+			readings[i] = Reading{ID: i, Km: i * 7}
+		}
+		data := map[string][]Reading{"readings": readings}
+		return testing.AllocsPerRun(10, func() { _, _ = toon.Marshal(data) })
+	}
+	if small, large := allocs(100), allocs(10000); large > small {
+		t.Errorf("Marshal of 10000 rows made %v allocations, of 100 rows %v", large, small)
+	}
+}
@@ -0,0 +1,193 @@
+package toon
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Estimator returns the number of tokens a model would read from data.
+type Estimator func(data []byte) int
+
+// EstimateTokens is the Estimator Split uses by default: one token per
+// four bytes, rounded up, which is close for English text and TOON
+// punctuation with common tokenizers.
+func EstimateTokens(data []byte) int {
+	return (len(data) + 3) / 4
+}
+
+// Split cuts a document into chunks of at most maxTokens tokens as counted
+// by est, or EstimateTokens when est is nil, for models that are fed one
+// chunk at a time. Every chunk is a document of its own: the top-level
+// keys that are not arrays, and the "_ids" table of ShortenIDs, are
+// repeated at the start of each one as context, followed by a share of the
+// rows, items or values of the top-level arrays under a copy of their
+// header with the count adjusted. Folded columns travel with their table
+// and a "#toon" directive is repeated; comments between top-level keys and
+// "#crc32" footers are dropped. Split fails if the context or a single row
+// or item does not fit.
+func Split(data []byte, maxTokens int, est Estimator) ([][]byte, error) {
+	if est == nil {
+		est = EstimateTokens
+	}
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &splitter{maxTokens: maxTokens, est: est}
+	if len(doc.lines) > 0 && strings.HasPrefix(doc.lines[0], versionDirective) {
+		s.context = append(s.context, doc.lines[0])
+	}
+
+	folded := make(map[string][]string)
+	for _, n := range doc.nodes {
+		if table, _, ok := strings.Cut(n.key, foldedColumnSep); ok {
+			folded[table] = append(folded[table], doc.lines[n.line:n.end]...)
+		}
+	}
+
+	for _, n := range doc.nodes {
+		if strings.Contains(n.key, foldedColumnSep) {
+			continue
+		}
+		// The table of shortened identifiers must reach every chunk whole
+		if !isArrayNode(n) || len(n.children) == 0 && n.kind != inlineNode || n.key == idLegendKey {
+			s.context = append(s.context, doc.lines[n.line:n.end]...)
+			continue
+		}
+		s.sections = append(s.sections, newSplitSection(doc, n, folded[n.key]))
+	}
+
+	return s.split()
+}
+
+func isArrayNode(n *node) bool {
+	return n.kind == inlineNode || n.kind == tableNode || n.kind == listNode
+}
+
+// splitSection is a top-level array whose items are shared out among
+// chunks.
+type splitSection struct {
+	header string
+	folded []string
+	items  [][]string
+
+	// inline arrays hold one value per item, joined by delim on the
+	// header line
+	inline bool
+	delim  Delimiter
+}
+
+func newSplitSection(doc *Document, n *node, folded []string) *splitSection {
+	section := &splitSection{folded: folded}
+	if n.kind == inlineNode {
+		section.header = n.prefix
+		section.inline = true
+		section.delim = doc.delimiter(n)
+		for _, cell := range doc.cells(n) {
+			section.items = append(section.items, []string{cell})
+		}
+		return section
+	}
+
+	section.header = doc.lines[n.line]
+	for _, child := range n.children {
+		section.items = append(section.items, doc.lines[child.line:child.end])
+	}
+	return section
+}
+
+// lines renders items of the section, the header declaring their number.
+func (s *splitSection) lines(items [][]string) []string {
+	header := s.header
+	if loc := arrayCountPattern.FindStringSubmatchIndex(header); loc != nil {
+		header = header[:loc[2]] + strconv.Itoa(len(items)) + header[loc[3]:]
+	}
+
+	lines := append([]string(nil), s.folded...)
+	if s.inline {
+		values := make([]string, len(items))
+		for i, item := range items {
+			values[i] = item[0]
+		}
+		return append(lines, header+strings.Join(values, string(s.delim)))
+	}
+
+	lines = append(lines, header)
+	for _, item := range items {
+		lines = append(lines, item...)
+	}
+	return lines
+}
+
+type splitter struct {
+	maxTokens int
+	est       Estimator
+	context   []string
+	sections  []*splitSection
+}
+
+// splitPart is the share of a section's items that goes into one chunk.
+type splitPart struct {
+	section *splitSection
+	items   [][]string
+}
+
+func (s *splitter) render(parts []splitPart) []byte {
+	lines := append([]string(nil), s.context...)
+	for _, part := range parts {
+		lines = append(lines, part.section.lines(part.items)...)
+	}
+	if len(lines) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func (s *splitter) fits(parts []splitPart) bool {
+	return s.est(s.render(parts)) <= s.maxTokens
+}
+
+func (s *splitter) split() ([][]byte, error) {
+	if !s.fits(nil) {
+		return nil, fmt.Errorf("toon: context of %d tokens does not fit in %d", s.est(s.render(nil)), s.maxTokens)
+	}
+
+	var chunks [][]byte
+	var parts []splitPart
+	for _, section := range s.sections {
+		for i, item := range section.items {
+			// Try the item in the current chunk, then in a fresh one
+			for attempt := 0; ; attempt++ {
+				next := appendSplitItem(parts, section, item)
+				if s.fits(next) {
+					parts = next
+					break
+				}
+				if attempt > 0 || len(parts) == 0 {
+					return nil, fmt.Errorf("toon: item %d of %q does not fit in %d tokens with the context",
+						i, strings.TrimSpace(section.header), s.maxTokens)
+				}
+				chunks = append(chunks, s.render(parts))
+				parts = nil
+			}
+		}
+	}
+	if len(parts) > 0 || len(chunks) == 0 {
+		chunks = append(chunks, s.render(parts))
+	}
+	return chunks, nil
+}
+
+// appendSplitItem returns a copy of parts with item added to the part of
+// section, which is the last one if present.
+func appendSplitItem(parts []splitPart, section *splitSection, item []string) []splitPart {
+	next := append([]splitPart(nil), parts...)
+	if len(next) == 0 || next[len(next)-1].section != section {
+		next = append(next, splitPart{section: section})
+	}
+	last := &next[len(next)-1]
+	last.items = append(last.items[:len(last.items):len(last.items)], item)
+	return next
+}
@@ -0,0 +1,72 @@
+package toon_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestSplit(t *testing.T) {
+	input := "#toon 1.0\ncontext:\n  task: hikes\nfriends[3]: ana,luis,sam\n" +
+		"hikes.*.season: spring\nhikes[3]{id,name}:\n  1,Blue Lake\n  2,Ridge\n  3,Wonderland\n" +
+		"notes[2]:\n  - text: steep\n    by: ana\n  - text: muddy\n    by: sam\n#crc32: 00000000\n"
+
+	// Count lines rather than bytes so the expected chunks are easy to see
+	lines := func(data []byte) int { return strings.Count(string(data), "\n") }
+
+	chunks, err := toon.Split([]byte(input), 8, lines)
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	want := []string{
+		"#toon 1.0\ncontext:\n  task: hikes\nfriends[3]: ana,luis,sam\nhikes.*.season: spring\nhikes[2]{id,name}:\n  1,Blue Lake\n  2,Ridge\n",
+		"#toon 1.0\ncontext:\n  task: hikes\nhikes.*.season: spring\nhikes[1]{id,name}:\n  3,Wonderland\n",
+		"#toon 1.0\ncontext:\n  task: hikes\nnotes[2]:\n  - text: steep\n    by: ana\n  - text: muddy\n    by: sam\n",
+	}
+	got := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		got[i] = string(chunk)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Split =\n%q\nwant\n%q", got, want)
+	}
+
+	// Every chunk decodes on its own
+	for _, chunk := range chunks {
+		var out HikesData
+		if err := toon.Unmarshal(chunk, &out); err != nil {
+			t.Errorf("Unmarshal of %q failed: %v", chunk, err)
+		}
+		if out.Context.Task != "hikes" {
+			t.Errorf("chunk %q lost its context", chunk)
+		}
+	}
+
+	// Inline arrays are split by value
+	chunks, err = toon.Split([]byte("tags[4]: a,b,c,d\n"), 4, nil)
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	if len(chunks) != 2 || string(chunks[0]) != "tags[3]: a,b,c\n" || string(chunks[1]) != "tags[1]: d\n" {
+		t.Errorf("Split of an inline array = %q", chunks)
+	}
+}
+
+func TestSplitTooLarge(t *testing.T) {
+	input := []byte("context:\n  task: a long description of the task\nrows[1]{id}:\n  1\n")
+	if _, err := toon.Split(input, 5, nil); err == nil || !strings.Contains(err.Error(), "context") {
+		t.Errorf("Split with oversized context = %v", err)
+	}
+
+	input = []byte("rows[2]{text}:\n  short\n  a row far longer than the limit allows\n")
+	if _, err := toon.Split(input, 8, nil); err == nil || !strings.Contains(err.Error(), "item 1") {
+		t.Errorf("Split with an oversized row = %v", err)
+	}
+
+	chunks, err := toon.Split([]byte("name: a\n"), 100, nil)
+	if err != nil || len(chunks) != 1 || string(chunks[0]) != "name: a\n" {
+		t.Errorf("Split of a small document = %q, %v", chunks, err)
+	}
+}
@@ -0,0 +1,123 @@
+package toon
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ColumnStats summarizes one column of a table.
+type ColumnStats struct {
+	Name string
+
+	// Type is the type inferred from the column's values: "int", "float",
+	// "bool", "string", or "mixed" when they disagree. Columns holding
+	// only nulls and blanks have type "null".
+	Type string
+
+	Count    int // non-null values
+	Nulls    int // null values and blank cells
+	Distinct int // distinct non-null values
+
+	// Min, Max and Mean are set for "int" and "float" columns only.
+	Min, Max, Mean float64
+}
+
+// ColumnStats reports statistics for every column of the table at path.
+func (doc *Document) ColumnStats(path string) ([]ColumnStats, error) {
+	n, cell, err := doc.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	if n.kind != tableNode || cell >= 0 {
+		return nil, fmt.Errorf("toon: %s is not a table", path)
+	}
+
+	rows := make([][]string, len(n.children))
+	for i, row := range n.children {
+		rows[i] = doc.cells(row)
+	}
+	return columnStats(n.fields, rows), nil
+}
+
+// columnStats computes statistics over rows of raw cells.
+func columnStats(fields []string, rows [][]string) []ColumnStats {
+	stats := make([]ColumnStats, len(fields))
+	distinct := make([]map[string]bool, len(fields))
+	sums := make([]float64, len(fields))
+	for i, field := range fields {
+		stats[i] = ColumnStats{Name: field, Type: "null"}
+		distinct[i] = make(map[string]bool)
+	}
+
+	for _, cells := range rows {
+		for i := range stats {
+			raw := ""
+			if i < len(cells) {
+				raw = strings.TrimSpace(cells[i])
+			}
+
+			s := &stats[i]
+			typ := valueType(raw)
+			if typ == "null" {
+				s.Nulls++
+				continue
+			}
+
+			s.Type = mergeValueTypes(s.Type, typ)
+			distinct[i][raw] = true
+			if typ == "int" || typ == "float" {
+				f, _ := strconv.ParseFloat(raw, 64)
+				if s.Count == 0 || f < s.Min {
+					s.Min = f
+				}
+				if s.Count == 0 || f > s.Max {
+					s.Max = f
+				}
+				sums[i] += f
+			}
+			s.Count++
+		}
+	}
+
+	for i := range stats {
+		s := &stats[i]
+		s.Distinct = len(distinct[i])
+		if s.Type == "int" || s.Type == "float" {
+			s.Mean = sums[i] / float64(s.Count)
+		} else {
+			s.Min, s.Max = 0, 0
+		}
+	}
+	return stats
+}
+
+// valueType infers the type of a raw cell the way decoding into any does.
+func valueType(raw string) string {
+	if raw == "" {
+		return "null"
+	}
+	lit, ok := parseLiteral(raw)
+	if !ok || isQuoted(raw) {
+		return "string"
+	}
+	switch lit.(type) {
+	case bool:
+		return "bool"
+	case int64, uint64:
+		return "int"
+	case float64:
+		return "float"
+	}
+	return "null"
+}
+
+func mergeValueTypes(a, b string) string {
+	switch {
+	case a == "null" || a == b:
+		return b
+	case a == "int" && b == "float", a == "float" && b == "int":
+		return "float"
+	}
+	return "mixed"
+}
@@ -0,0 +1,45 @@
+package toon_test
+
+import (
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestDocumentColumnStats(t *testing.T) {
+	input := `hikes[4]{id,name,distanceKm,sunny,note}:
+  1,Blue Lake,7.5,true,
+  2,Ridge,9,false,"42"
+  3,Loop,5.5,true,null
+  4,Blue Lake,2,true,7
+`
+	doc, err := toon.ParseDocument([]byte(input))
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+
+	stats, err := doc.ColumnStats("hikes")
+	if err != nil {
+		t.Fatalf("ColumnStats failed: %v", err)
+	}
+
+	want := []toon.ColumnStats{
+		{Name: "id", Type: "int", Count: 4, Distinct: 4, Min: 1, Max: 4, Mean: 2.5},
+		{Name: "name", Type: "string", Count: 4, Distinct: 3},
+		{Name: "distanceKm", Type: "float", Count: 4, Distinct: 4, Min: 2, Max: 9, Mean: 6},
+		{Name: "sunny", Type: "bool", Count: 4, Distinct: 2},
+		{Name: "note", Type: "mixed", Count: 2, Nulls: 2, Distinct: 2},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("got %d columns, want %d", len(stats), len(want))
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("column %d = %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+
+	if _, err := doc.ColumnStats("hikes[0]"); err == nil {
+		t.Error("expected error for a row path")
+	}
+}
@@ -0,0 +1,226 @@
+package toon
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Decoder reads a document from an input stream. Documents decoded into a
+// struct, a map or an interface are read one top-level key at a time, so
+// only the lines of that key are held in memory besides the decoded value;
+// a single large table or list is still read whole. Other destinations are
+// read whole as well.
+type Decoder struct {
+	r    *bufio.Reader
+	opts UnmarshalOptions
+}
+
+// NewDecoder returns a Decoder reading from r with DefaultUnmarshalOptions.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), opts: DefaultUnmarshalOptions()}
+}
+
+// SetOptions sets the options of later Decode calls.
+func (dec *Decoder) SetOptions(opts UnmarshalOptions) {
+	dec.opts = opts
+}
+
+// Decode reads the input to its end and stores the document in v, as
+// UnmarshalWithOptions would. Each top-level key is stored as soon as it
+// has been read, so on error v holds the keys before it, and decoding
+// stops after the first key with problems. With VerifyChecksum the footer
+// is only checked once every key has been stored. AutoDetectDelimiter
+// picks a delimiter for each top-level key on its own.
+func (dec *Decoder) Decode(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return ErrUnmarshalType
+	}
+	if rv.IsNil() {
+		return ErrNilPointer
+	}
+
+	target := rv.Elem()
+	for target.Kind() == reflect.Ptr {
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		target = target.Elem()
+	}
+
+	switch {
+	case target.Kind() == reflect.Struct && !isScalarType(target.Type()),
+		target.Kind() == reflect.Map:
+		return dec.stream(target.Addr().Interface())
+	case target.Kind() == reflect.Interface && target.NumMethod() == 0:
+		m := make(map[string]any)
+		if err := dec.stream(&m); err != nil {
+			return err
+		}
+		target.Set(reflect.ValueOf(m))
+		return nil
+	}
+
+	var data []byte
+	for n := 1; ; n++ {
+		line, err := readLine(dec.r, dec.opts.MaxLineBytes, n, len(data))
+		data = append(data, line...)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return UnmarshalWithOptions(data, v, dec.opts)
+}
+
+// stream decodes the document into v one top-level key at a time.
+func (dec *Decoder) stream(v any) error {
+	s := &streamState{dec: dec, v: v, hash: crc32.NewIEEE(), footerLine: -1, topKeys: make(map[string]bool)}
+	for {
+		line, readErr := readLine(dec.r, dec.opts.MaxLineBytes, s.lines+1, s.bytes)
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return readErr
+		}
+		if line != "" {
+			if err := s.add(line); err != nil {
+				return err
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+
+	if s.chunks == 0 || len(s.chunk) > 0 {
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+	if dec.opts.VerifyChecksum {
+		return s.verifyChecksum()
+	}
+	return nil
+}
+
+// streamState holds the state of one streaming Decode call.
+type streamState struct {
+	dec *Decoder
+	v   any
+
+	// chunk holds the lines of the top-level key being read, along with
+	// the blank lines, comments and folded columns before it
+	chunk    []byte
+	hasEntry bool
+
+	// line and offset are the number of lines and bytes read before the
+	// chunk
+	line, offset int
+	chunks       int
+
+	// ids carries the references read with ResolveIDs to later chunks,
+	// and topKeys the keys decoded so far
+	ids     map[string]string
+	topKeys map[string]bool
+
+	// hash sums every line read; footerSum is its value before the
+	// "#crc32" footer, found on line footerLine at byte footerOffset, or
+	// footerLine is -1 when content follows the footer or there is none
+	hash         hash.Hash32
+	footer       string
+	footerSum    uint32
+	footerLine   int
+	footerOffset int
+	lines, bytes int
+}
+
+func (s *streamState) add(line string) error {
+	text := strings.TrimSpace(line)
+	if footer, ok := cutChecksumFooter(line); ok {
+		s.footer = footer
+		s.footerSum, s.footerLine, s.footerOffset = s.hash.Sum32(), s.lines+1, s.bytes
+	} else if text != "" {
+		s.footerLine = -1
+	}
+	s.hash.Write([]byte(line))
+	s.lines++
+	s.bytes += len(line)
+
+	// A key at the top level starts the next chunk, unless it is a folded
+	// column that belongs with the table after it
+	if text != "" && !strings.HasPrefix(text, "#") && line[0] != ' ' && line[0] != '\t' {
+		key, _, _ := cutKeyValue(text)
+		folded := strings.Contains(key, foldedColumnSep)
+		if s.hasEntry {
+			if err := s.flush(); err != nil {
+				return err
+			}
+		}
+		s.hasEntry = !folded
+	}
+	s.chunk = append(s.chunk, line...)
+	return nil
+}
+
+// flush decodes the chunk read so far into v.
+func (s *streamState) flush() error {
+	opts := s.dec.opts
+	opts.VerifyChecksum = false
+	if s.chunks > 0 {
+		// Only the first chunk can hold the version directive
+		opts.RequireVersion = false
+	}
+
+	d := newDecoder(s.chunk, opts)
+	d.lineBase, d.offsetBase = s.line, s.offset
+	d.ids = s.ids
+	d.topKeys = s.topKeys
+	err := d.decode(s.v)
+	s.ids = d.ids
+
+	s.line += bytes.Count(s.chunk, []byte("\n"))
+	s.offset += len(s.chunk)
+	s.chunks++
+	s.chunk = s.chunk[:0]
+	s.hasEntry = false
+	return err
+}
+
+// verifyChecksum checks the "#crc32" footer on the last non-empty line
+// against the bytes read before it.
+func (s *streamState) verifyChecksum() error {
+	if s.footerLine < 0 {
+		return &SyntaxError{
+			Line:    s.lines + 1,
+			Column:  1,
+			Offset:  s.bytes,
+			Message: fmt.Sprintf("missing %q footer", strings.TrimSpace(checksumDirective)),
+			Err:     ErrChecksum,
+		}
+	}
+	footer := strings.TrimSpace(checksumDirective + s.footer)
+	want, err := strconv.ParseUint(s.footer, 16, 32)
+	if err != nil {
+		return &SyntaxError{Line: s.footerLine, Column: 1, Offset: s.footerOffset, Message: fmt.Sprintf("malformed checksum %q", s.footer), Content: footer}
+	}
+	if uint32(want) != s.footerSum {
+		return &SyntaxError{
+			Line:    s.footerLine,
+			Column:  1,
+			Offset:  s.footerOffset,
+			Message: fmt.Sprintf("footer has %08X, content has %08X", want, s.footerSum),
+			Content: footer,
+			Err:     ErrChecksum,
+		}
+	}
+	return nil
+}
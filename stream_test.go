@@ -0,0 +1,136 @@
+package toon_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestDecoder(t *testing.T) {
+	type Trip struct {
+		HikesData
+		Owner string         `toon:"owner"`
+		Extra map[string]any `toon:"extra"`
+	}
+	in := Trip{
+		HikesData: HikesData{
+			Context: Context{Task: "hiking", Location: "Boulder", Season: "spring"},
+			Friends: []string{"ana", "luis"},
+			Hikes: []Hike{
+				{ID: 1, Name: "Blue Lake", DistanceKm: 7.5, ElevationGain: 320, Companion: "ana", WasSunny: true},
+				{ID: 2, Name: "Ridge", DistanceKm: 9.2, ElevationGain: 540, Companion: "ana", WasSunny: true},
+			},
+		},
+		Owner: "3f2a6c1e-9b7d-4e2a-8c1f-0d9e8b7a6c5d",
+		Extra: map[string]any{"nested": map[string]any{"n": int64(1)}},
+	}
+
+	data, err := toon.Marshal(in, toon.WithVersionHeader(true), toon.WithChecksum(true),
+		toon.WithFoldConstantColumns(true), toon.WithShortenIDs(true))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	opts := toon.DefaultUnmarshalOptions()
+	opts.Strict = true
+	opts.RequireVersion = true
+	opts.VerifyChecksum = true
+	opts.ResolveIDs = true
+
+	var want Trip
+	if err := toon.UnmarshalWithOptions(data, &want, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(want, in) {
+		t.Fatalf("Unmarshal = %+v, want %+v", want, in)
+	}
+
+	dec := toon.NewDecoder(strings.NewReader(string(data)))
+	dec.SetOptions(opts)
+	var out Trip
+	if err := dec.Decode(&out); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("Decode = %+v, want %+v", out, in)
+	}
+
+	// Dynamic and whole-document destinations
+	var dynamic, dynamicWant any
+	if err := toon.Unmarshal(data, &dynamicWant); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if err := toon.NewDecoder(strings.NewReader(string(data))).Decode(&dynamic); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(dynamic, dynamicWant) {
+		t.Errorf("Decode into any = %v, want %v", dynamic, dynamicWant)
+	}
+
+	var friends []string
+	if err := toon.NewDecoder(strings.NewReader("- ana\n- luis\n")).Decode(&friends); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(friends, []string{"ana", "luis"}) {
+		t.Errorf("Decode into slice = %v", friends)
+	}
+
+	// An altered document fails the checksum after decoding
+	altered := strings.Replace(string(data), "Boulder", "Denver", 1)
+	dec = toon.NewDecoder(strings.NewReader(altered))
+	dec.SetOptions(opts)
+	if err := dec.Decode(&out); !errors.Is(err, toon.ErrChecksum) {
+		t.Errorf("Decode of altered document = %v, want ErrChecksum", err)
+	}
+}
+
+func TestDecoderErrorPositions(t *testing.T) {
+	data := "context:\n  task: hiking\n\nfriends[2]: ana,luis\nhikes[2]{id,name}:\n  1,Lake\n  x,Ridge\n"
+	opts := toon.DefaultUnmarshalOptions()
+	opts.StrictTypes = true
+
+	var want, got HikesData
+	wantErr := toon.UnmarshalWithOptions([]byte(data), &want, opts)
+	dec := toon.NewDecoder(strings.NewReader(data))
+	dec.SetOptions(opts)
+	gotErr := dec.Decode(&got)
+
+	var wantType, gotType *toon.UnmarshalTypeError
+	if !errors.As(wantErr, &wantType) || !errors.As(gotErr, &gotType) {
+		t.Fatalf("errors = %v and %v, want *UnmarshalTypeError", wantErr, gotErr)
+	}
+	if *gotType != *wantType {
+		t.Errorf("Decode error = %+v, want %+v", *gotType, *wantType)
+	}
+	if got.Context.Task != "hiking" || len(got.Friends) != 2 {
+		t.Errorf("keys before the error = %+v", got)
+	}
+}
+
+func TestDecoderDuplicateKeys(t *testing.T) {
+	input := "a: 1\nb: 2\na: 3\n"
+	for _, v := range []any{new(map[string]int), new(any), new(struct {
+		A int `toon:"a"`
+		B int `toon:"b"`
+	})} {
+		var want, got []toon.Warning
+		opts := toon.DefaultUnmarshalOptions()
+		opts.Warnings = &want
+		if err := toon.UnmarshalWithOptions([]byte(input), v, opts); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+
+		opts.Warnings = &got
+		dec := toon.NewDecoder(strings.NewReader(input))
+		dec.SetOptions(opts)
+		if err := dec.Decode(v); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if len(want) != 1 || !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode into %T warned %+v, Unmarshal %+v", v, got, want)
+		}
+	}
+}
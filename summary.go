@@ -0,0 +1,134 @@
+package toon
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// summaryMarker is the key that flags a block written in place of an array
+// by MarshalOptions.SummarizeArrays.
+const summaryMarker = "_summary"
+
+// summaryExamples is the number of leading and trailing elements a summary
+// shows.
+const summaryExamples = 2
+
+type summaryStat struct {
+	Column   string   `toon:"column"`
+	Type     string   `toon:"type"`
+	Count    int      `toon:"count"`
+	Nulls    int      `toon:"nulls"`
+	Distinct int      `toon:"distinct"`
+	Min      *float64 `toon:"min"`
+	Max      *float64 `toon:"max"`
+	Mean     *float64 `toon:"mean"`
+}
+
+// encodeSummary writes a block describing the array v instead of its
+// elements: the marker, the element count, the first and last elements
+// and, for scalars and flat structs, per-column statistics.
+func (e *encoder) encodeSummary(v reflect.Value, depth int, key string) error {
+	length := v.Len()
+	if key != "" {
+		e.writeIndent(depth)
+		e.buf.WriteString(key)
+		e.buf.WriteString(":\n")
+		depth++
+	}
+
+	e.writeIndent(depth)
+	e.buf.WriteString(summaryMarker + e.opts.KeyValueSeparator + "true\n")
+	e.writeIndent(depth)
+	e.buf.WriteString("count" + e.opts.KeyValueSeparator + strconv.Itoa(length) + "\n")
+
+	// Never show more examples than the threshold, or they would be
+	// summarized in turn
+	examples := min(summaryExamples, e.opts.SummarizeArrays)
+	parts := []struct {
+		name       string
+		start, end int
+	}{
+		{"first", 0, examples},
+		{"last", length - examples, length},
+	}
+	for _, part := range parts {
+		e.pushPath(part.name)
+		if err := e.encodeValue(subSlice(v, part.start, part.end), depth, part.name); err != nil {
+			return err
+		}
+		e.popPath()
+	}
+
+	fields, rows, ok := e.summaryCells(v)
+	if !ok {
+		return nil
+	}
+
+	var stats []summaryStat
+	for _, s := range columnStats(fields, rows) {
+		stat := summaryStat{Column: s.Name, Type: s.Type, Count: s.Count, Nulls: s.Nulls, Distinct: s.Distinct}
+		if s.Type == "int" || s.Type == "float" {
+			stat.Min, stat.Max, stat.Mean = &s.Min, &s.Max, &s.Mean
+		}
+		stats = append(stats, stat)
+	}
+
+	// The statistics table is never summarized itself
+	threshold := e.opts.SummarizeArrays
+	e.opts.SummarizeArrays = 0
+	defer func() { e.opts.SummarizeArrays = threshold }()
+
+	e.pushPath("stats")
+	defer e.popPath()
+	return e.encodeValue(reflect.ValueOf(stats), depth, "stats")
+}
+
+// summaryCells renders the elements of v as rows of raw cells, with one
+// column per field for flat structs and a single value column for scalars.
+// It reports false for other elements.
+func (e *encoder) summaryCells(v reflect.Value) ([]string, [][]string, bool) {
+	elemType := derefType(v.Type().Elem())
+	rows := make([][]string, v.Len())
+	switch {
+	case elemType.Kind() == reflect.Struct && !isScalarType(elemType):
+		if !e.isTabularType(elemType) {
+			return nil, nil, false
+		}
+		fields := e.fields(elemType)
+		var names []string
+		for _, field := range fields {
+			names = append(names, e.columnName(field))
+		}
+		for i := range rows {
+			elem := derefValue(v.Index(i))
+			rows[i] = make([]string, len(fields))
+			for j, field := range fields {
+				if !elem.IsValid() {
+					continue
+				}
+				if fv, ok := field.valueOf(elem); ok {
+					rows[i][j] = e.cellText(fv)
+				}
+			}
+		}
+		return names, rows, true
+	case elemType.Kind() == reflect.Map, elemType.Kind() == reflect.Interface,
+		!isScalarType(elemType) && (elemType.Kind() == reflect.Slice || elemType.Kind() == reflect.Array):
+		return nil, nil, false
+	}
+
+	for i := range rows {
+		rows[i] = []string{e.cellText(v.Index(i))}
+	}
+	return []string{"value"}, rows, true
+}
+
+// subSlice returns the elements [i, j) of the slice or array v as a new
+// slice.
+func subSlice(v reflect.Value, i, j int) reflect.Value {
+	s := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), j-i, j-i)
+	for k := i; k < j; k++ {
+		s.Index(k - i).Set(v.Index(k))
+	}
+	return s
+}
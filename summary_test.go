@@ -0,0 +1,61 @@
+package toon_test
+
+import (
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestMarshalSummarizeArrays(t *testing.T) {
+	type Reading struct {
+		ID    int     `toon:"id"`
+		Temp  float64 `toon:"temp"`
+		Label string  `toon:"label"`
+	}
+	type Log struct {
+		Readings []Reading `toon:"readings"`
+		Codes    []int     `toon:"codes"`
+		Tags     []string  `toon:"tags"`
+	}
+
+	in := Log{
+		Readings: []Reading{{1, 20.5, "a"}, {2, 21, "b"}, {3, 19.5, "a"}, {4, 23, "c"}},
+		Codes:    []int{7, 3, 5},
+		Tags:     []string{"x", "y"},
+	}
+
+	data, err := toon.Marshal(in, toon.WithSummarizeArrays(2))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	want := `readings:
+  _summary: true
+  count: 4
+  first[2]{id,temp,label}:
+    1,20.5,a
+    2,21,b
+  last[2]{id,temp,label}:
+    3,19.5,a
+    4,23,c
+  stats[3]{column,type,count,nulls,distinct,min,max,mean}:
+    id,int,4,0,4,1,4,2.5
+    temp,float,4,0,4,19.5,23,21
+    label,string,4,0,3,null,null,null
+codes:
+  _summary: true
+  count: 3
+  first[2]: 7,3
+  last[2]: 3,5
+  stats[1]{column,type,count,nulls,distinct,min,max,mean}:
+    value,int,3,0,3,3,7,5
+tags[2]: x,y
+`
+	if string(data) != want {
+		t.Errorf("Marshal =\n%s\nwant:\n%s", data, want)
+	}
+
+	if _, err := toon.Marshal(in, toon.WithSummarizeArrays(-1)); err == nil {
+		t.Error("expected error for negative threshold")
+	}
+}
@@ -0,0 +1,74 @@
+package toon
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// FuncMap returns template functions that write values as TOON with the
+// given options, for prompt templates that embed data inline:
+//
+//	tmpl := template.New("prompt").Funcs(toon.FuncMap(toon.WithDelimiter(toon.DelimiterTab)))
+//
+// {{ toon .Trip }} writes a value as Marshal does, and {{ toonTable .Hikes }}
+// writes a slice or array of structs as a table however few rows it has,
+// failing for other values. Either takes a key as a second argument, as
+// in {{ toonTable .Hikes "hikes" }}, to write the value under it. The
+// final line break is left out so the template decides what follows.
+// html/template callers convert the result to html/template.FuncMap.
+func FuncMap(options ...MarshalOption) template.FuncMap {
+	opts := DefaultMarshalOptions()
+	var optsErr error
+	for _, option := range options {
+		if optsErr = option(&opts); optsErr != nil {
+			break
+		}
+	}
+	if optsErr == nil {
+		optsErr = opts.validate()
+	}
+
+	tableOpts := opts
+	tableOpts.UseTabular = true
+	tableOpts.MinTabularRows = 0
+
+	return template.FuncMap{
+		"toon": func(v any, key ...string) (string, error) {
+			if optsErr != nil {
+				return "", optsErr
+			}
+			return templateValue(opts, v, key)
+		},
+		"toonTable": func(v any, key ...string) (string, error) {
+			if optsErr != nil {
+				return "", optsErr
+			}
+			t := reflect.TypeOf(v)
+			if t == nil || t.Kind() != reflect.Slice && t.Kind() != reflect.Array ||
+				derefType(t.Elem()).Kind() != reflect.Struct || isScalarType(derefType(t.Elem())) {
+				return "", fmt.Errorf("toon: toonTable needs a slice of structs, got %T", v)
+			}
+			return templateValue(tableOpts, v, key)
+		},
+	}
+}
+
+// templateValue writes v under the key given to a template function, if
+// any.
+func templateValue(opts MarshalOptions, v any, key []string) (string, error) {
+	if len(key) > 1 {
+		return "", fmt.Errorf("toon: expected at most one key, got %d", len(key))
+	}
+	e := newEncoder(opts)
+	name := ""
+	if len(key) == 1 {
+		name = e.prefixKey(0, key[0])
+	}
+	data, err := e.encodeDocument(reflect.ValueOf(v), name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
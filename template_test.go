@@ -0,0 +1,65 @@
+package toon_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"text/template"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestFuncMap(t *testing.T) {
+	type Hike struct {
+		Name string  `toon:"name"`
+		Km   float64 `toon:"km"`
+	}
+	data := struct {
+		Owner string
+		Hikes []Hike
+		Tags  []string
+	}{"ana", []Hike{{"Blue Lake", 7.5}}, []string{"lake", "ridge"}}
+
+	const text = `Trips of {{ .Owner }}:
+{{ toonTable .Hikes "hikes" }}
+Tags: {{ toon .Tags }}
+{{ toon .Hikes }}
+{{ toon .Tags "tags" }}
+`
+	tmpl := template.Must(template.New("prompt").Funcs(toon.FuncMap(toon.WithMinTabularRows(2))).Parse(text))
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// toonTable writes a table below MinTabularRows, toon a list
+	want := `Trips of ana:
+hikes[1]{name,km}:
+  Blue Lake,7.5
+Tags: [2]: lake,ridge
+[1]:
+  - name: Blue Lake
+    km: 7.5
+tags[2]: lake,ridge
+`
+	if out.String() != want {
+		t.Fatalf("Execute = %q, want %q", out.String(), want)
+	}
+}
+
+func TestFuncMapErrors(t *testing.T) {
+	run := func(funcs template.FuncMap, text string, data any) error {
+		tmpl := template.Must(template.New("prompt").Funcs(funcs).Parse(text))
+		return tmpl.Execute(&strings.Builder{}, data)
+	}
+
+	if err := run(toon.FuncMap(), `{{ toonTable . }}`, []string{"a"}); err == nil || !strings.Contains(err.Error(), "toonTable needs a slice of structs") {
+		t.Errorf("toonTable of strings: err = %v", err)
+	}
+	if err := run(toon.FuncMap(), `{{ toon . "a" "b" }}`, 1); err == nil {
+		t.Error("toon accepted two keys")
+	}
+	if err := run(toon.FuncMap(toon.WithIndent(0)), `{{ toon . }}`, 1); !errors.Is(err, toon.ErrInvalidOptions) {
+		t.Errorf("invalid options: err = %v, want ErrInvalidOptions", err)
+	}
+}
@@ -0,0 +1,98 @@
+package toon
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// fieldTimeValue converts a time held in v to the form selected by the
+// field's tag options: an integer for unix or unixmilli, or text in the
+// layout of format=. Other values are returned as is.
+func (e *encoder) fieldTimeValue(field structField, v reflect.Value) reflect.Value {
+	tv := derefValue(v)
+	if !tv.IsValid() || tv.Type() != timeType {
+		return v
+	}
+	t := tv.Interface().(time.Time)
+
+	switch {
+	case field.hasOption("unix"):
+		return reflect.ValueOf(t.Unix())
+	case field.hasOption("unixmilli"):
+		return reflect.ValueOf(t.UnixMilli())
+	case field.timeFormat() != "":
+		return reflect.ValueOf(inLocation(t, e.opts.TimeLocation).Format(field.timeFormat()))
+	}
+	return v
+}
+
+// setFieldValue is setPrimitiveValue with the field's tag options applied,
+// so integer timestamps and times in the layout of format= decode back
+// into time fields.
+func (d *decoder) setFieldValue(v reflect.Value, field structField, s string) error {
+	if err := d.observe(s); err != nil {
+		return err
+	}
+
+	// The string option quotes numbers and booleans
+	if field.hasOption("string") && isQuoted(s) {
+		switch derefType(v.Type()).Kind() {
+		case reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+			s = unquote(s)
+		}
+	}
+
+	unix, milli, layout := field.hasOption("unix"), field.hasOption("unixmilli"), field.timeFormat()
+	if !unix && !milli && layout == "" || s == "null" {
+		return d.collect(d.setPrimitiveValue(v, s))
+	}
+
+	target := indirect(v)
+	if target.Type() != timeType {
+		return d.collect(d.setPrimitiveValue(v, s))
+	}
+
+	if !unix && !milli {
+		t, err := time.Parse(layout, unquote(strings.TrimSpace(s)))
+		if err != nil {
+			return d.collect(d.conversionError(strings.TrimSpace(s), target.Type(), err))
+		}
+		target.Set(reflect.ValueOf(inLocation(t, d.opts.TimeLocation)))
+		return nil
+	}
+
+	n, err := strconv.ParseInt(unquote(s), 10, 64)
+	if err != nil {
+		return d.collect(d.typeError(strings.TrimSpace(s), target.Type()))
+	}
+	t := time.UnixMilli(n)
+	if unix {
+		t = time.Unix(n, 0)
+	}
+	target.Set(reflect.ValueOf(inLocation(t, d.opts.TimeLocation)))
+	return nil
+}
+
+// timeLayout returns the layout times are written and read in by default:
+// format, or RFC 3339 with fractional seconds when they are not zero.
+func timeLayout(format string) string {
+	if format == "" {
+		return time.RFC3339Nano
+	}
+	return format
+}
+
+// inLocation returns t in loc, or t unchanged when loc is nil.
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	return t.In(loc)
+}
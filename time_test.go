@@ -0,0 +1,135 @@
+package toon_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+// TestTimeLayouts writes times in each layout and checks that what decodes
+// from the document, read with the same layout, writes the same document.
+func TestTimeLayouts(t *testing.T) {
+	type Event struct {
+		ID      int        `toon:"id"`
+		At      time.Time  `toon:"at,unix"`
+		Logged  time.Time  `toon:"logged,unixmilli"`
+		Expires *time.Time `toon:"expires,unix"`
+	}
+	type Log struct {
+		Events []Event `toon:"events"`
+	}
+	type Stamp struct {
+		At time.Time `toon:"at"`
+	}
+	type Hike struct {
+		Name  string     `toon:"name"`
+		Date  time.Time  `toon:"date,format=2006-01-02"`
+		Year  time.Time  `toon:"year,format=2006"`
+		Start *time.Time `toon:"start,format=15:04"`
+	}
+	type Trip struct {
+		Booked time.Time `toon:"booked"`
+		Hikes  []Hike    `toon:"hikes"`
+	}
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	logged := at.Add(1500 * time.Millisecond)
+	booked := time.Date(2024, 2, 10, 8, 30, 15, 500000000, time.UTC)
+	start := time.Date(2024, 3, 1, 7, 45, 0, 0, time.UTC)
+	year := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		in     any
+		format string
+		loc    *time.Location
+		want   string
+	}{
+		{
+			name: "unix tags in a table",
+			in:   Log{Events: []Event{{ID: 1, At: at, Logged: logged, Expires: &at}, {ID: 2, At: at, Logged: logged}}},
+			want: "events[2]{id,at,logged,expires}:\n  1,1709294400,1709294401500,1709294400\n  2,1709294400,1709294401500,null\n",
+		},
+		{
+			name: "unix tags in a block",
+			in:   Event{ID: 3, At: at, Logged: logged},
+			want: "id: 3\nat: 1709294400\nlogged: 1709294401500\nexpires: null\n",
+		},
+		{
+			name: "location",
+			in:   Stamp{At: at.In(tokyo)},
+			loc:  time.UTC,
+			want: "at: 2024-03-01T12:00:00Z\n",
+		},
+		{
+			name: "format tags",
+			in: Trip{
+				Booked: booked,
+				Hikes: []Hike{
+					{Name: "Blue Lake", Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Year: year, Start: &start},
+					{Name: "Ridge", Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Year: year},
+				},
+			},
+			want: "booked: 2024-02-10T08:30:15.5Z\nhikes[2]{name,date,year,start}:\n" +
+				"  Blue Lake,2024-03-01,\"2024\",07:45\n  Ridge,2024-03-02,\"2024\",null\n",
+		},
+		{
+			// TimeFormat changes the default layout; TimeLocation applies first
+			name:   "default format",
+			in:     Trip{Booked: booked},
+			format: time.RFC1123Z,
+			loc:    tokyo,
+			want:   "booked: \"Sat, 10 Feb 2024 17:30:15 +0900\"\nhikes[0]:\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []toon.MarshalOption
+			if tt.format != "" {
+				options = append(options, toon.WithTimeFormat(tt.format))
+			}
+			if tt.loc != nil {
+				options = append(options, toon.WithTimeLocation(tt.loc))
+			}
+			data, err := toon.Marshal(tt.in, options...)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Fatalf("Marshal = %q, want %q", data, tt.want)
+			}
+
+			opts := toon.DefaultUnmarshalOptions()
+			opts.TimeFormat = tt.format
+			out := reflect.New(reflect.TypeOf(tt.in))
+			if err := toon.UnmarshalWithOptions(data, out.Interface(), opts); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			again, err := toon.Marshal(out.Elem().Interface(), options...)
+			if err != nil || string(again) != tt.want {
+				t.Errorf("Marshal of the decoded value = %q, %v, want %q", again, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeLocation(t *testing.T) {
+	type Stamp struct {
+		At time.Time `toon:"at"`
+	}
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	opts := toon.DefaultUnmarshalOptions()
+	opts.TimeLocation = tokyo
+	var out Stamp
+	if err := toon.UnmarshalWithOptions([]byte("at: 2024-03-01T12:00:00Z\n"), &out, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if want := time.Date(2024, 3, 1, 21, 0, 0, 0, tokyo); out.At.Location() != tokyo || !out.At.Equal(want) {
+		t.Errorf("At = %v, want %v", out.At, want)
+	}
+}
@@ -3,21 +3,300 @@ package toon
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
+	"time"
 )
 
+// FormatVersion is the version written in and accepted from the optional
+// "#toon <version>" directive on the first line of a document.
+const FormatVersion = "1.0"
+
+const versionDirective = "#toon "
+
+const checksumDirective = "#crc32: "
+
+// cutChecksumFooter returns the hash a checksum footer line holds, with or
+// without the space after its colon.
+func cutChecksumFooter(line string) (string, bool) {
+	hash, ok := strings.CutPrefix(line, strings.TrimSpace(checksumDirective))
+	return strings.TrimSpace(hash), ok
+}
+
+func isChecksumFooter(line string) bool {
+	_, ok := cutChecksumFooter(line)
+	return ok
+}
+
+// appendChecksumFooter appends the footer line holding sum as eight
+// uppercase hex digits.
+func appendChecksumFooter(dst []byte, sum uint32) []byte {
+	const digits = "0123456789ABCDEF"
+	dst = append(dst, checksumDirective...)
+	for shift := 28; shift >= 0; shift -= 4 {
+		dst = append(dst, digits[sum>>shift&0xF])
+	}
+	return append(dst, '\n')
+}
+
 type Delimiter string
 
 const (
-	DelimiterComma Delimiter = ","
-	DelimiterTab   Delimiter = "\t"
-	DelimiterPipe  Delimiter = "|"
+	DelimiterComma     Delimiter = ","
+	DelimiterTab       Delimiter = "\t"
+	DelimiterPipe      Delimiter = "|"
+	DelimiterSemicolon Delimiter = ";"
+)
+
+type StringQuoting int
+
+const (
+	// QuoteAuto quotes strings containing any delimiter, a quote or a line
+	// break, strings that look like a number, boolean or null, and empty or
+	// space-padded strings.
+	QuoteAuto StringQuoting = iota
+	// QuoteAlways quotes every string.
+	QuoteAlways
+	// QuoteMinimal quotes only strings that would be misread: those that
+	// look like a number, boolean or null, contain the active delimiter or
+	// a line break, start with a quote, or are empty or space-padded.
+	// Documents may then need an explicit UnmarshalOptions.Delimiter.
+	QuoteMinimal
 )
 
 type MarshalOptions struct {
 	Indent     int
 	Delimiter  Delimiter
 	UseTabular bool
+
+	// KeyValueSeparator goes between a key and the value on its line, ": "
+	// when empty. Dialects closer to TOML use " = "; decoding reads either
+	// form. Lines opening a block or table keep their colon.
+	KeyValueSeparator string
+
+	// CompactSeparator drops the spaces around KeyValueSeparator, writing
+	// key:value to save a token per key. Decoding reads either spacing.
+	CompactSeparator bool
+
+	// Lenient skips values of unsupported kinds (channels, functions and
+	// unsafe pointers) instead of failing with an *UnsupportedTypeError.
+	Lenient bool
+
+	StringQuoting StringQuoting
+
+	// VersionHeader writes a "#toon 1.0" directive as the first line.
+	VersionHeader bool
+
+	// Checksum appends a "#crc32: XXXXXXXX" footer holding the IEEE CRC-32
+	// of everything before it, with CRLF line endings read as LF.
+	Checksum bool
+
+	// MaxInlineItems switches primitive arrays longer than this from the
+	// single-line inline form to the list form. Zero means no limit.
+	MaxInlineItems int
+
+	// MinTabularRows is the smallest number of elements for which
+	// UseTabular writes a table; shorter collections use the list or
+	// nested form, avoiding a header that costs more than it saves.
+	MinTabularRows int
+
+	// TimeLocation, when set, converts time.Time values to this location
+	// before they are written, so output does not depend on the zone the
+	// values were created in.
+	TimeLocation *time.Location
+
+	// TimeFormat is the layout, as for time.Format, that time.Time values
+	// are written in. Empty means RFC 3339 with fractional seconds when
+	// they are not zero. Fields with a format= tag option, as in
+	// `toon:"date,format=2006-01-02"`, use that layout instead.
+	TimeFormat string
+
+	// BytesAsArray writes []byte and other []uint8 values as arrays of
+	// numbers instead of base64 strings. Both forms decode either way.
+	BytesAsArray bool
+
+	// FieldFilter, when set, is called for every struct field before it is
+	// written; returning false drops the field and everything below it.
+	// path is the dotted path of the field without array indexes, such as
+	// hikes.name, so every row of a table gets the same columns.
+	FieldFilter func(path string, field reflect.StructField) bool
+
+	// TransformValue, when set, is called with the path (such as
+	// hikes[2].name) and value of everything written, and the value it
+	// returns is written instead. An error aborts encoding.
+	TransformValue func(path string, v any) (any, error)
+
+	// SummarizeArrays, when positive, replaces arrays with more elements
+	// than this by a block marked "_summary: true" holding the element
+	// count, the first and last elements and per-column statistics. The
+	// output is meant for readers that only need the gist and does not
+	// decode back to the original.
+	SummarizeArrays int
+
+	// OptimizeColumns moves table columns in which most rows share one
+	// value, typically an empty one, behind the other columns, keeping the
+	// varied data first. Decoding matches columns by name, so the order
+	// does not affect it.
+	OptimizeColumns bool
+
+	// FoldConstantColumns lifts table columns that hold the same value in
+	// every row out of the table into a line such as
+	// "hikes.*.season: spring" above it. Decoding sets the value on every
+	// element again.
+	FoldConstantColumns bool
+
+	// SparseTables writes tables in which more than half of the cells hold
+	// zero values with a "{=}" header instead of a column list, each row
+	// holding only its non-zero fields as column=value pairs. Omitted
+	// fields decode as zero values.
+	SparseTables bool
+
+	// OmitArrayCounts writes open-ended array headers such as rows[]{a,b}:
+	// without the element count, for producers that stream rows before
+	// knowing how many there are. The decoder accepts rows[] and rows[?]
+	// and takes the count from the rows present.
+	OmitArrayCounts bool
+
+	// SkipNilMaps, SkipNilSlices and SkipNilPointers drop struct fields
+	// holding a nil map, slice or pointer outside tables, as omitempty
+	// does for a single field, rather than writing them as an empty
+	// block, an empty array or null. Empty but non-nil maps and slices are
+	// still written.
+	SkipNilMaps     bool
+	SkipNilSlices   bool
+	SkipNilPointers bool
+
+	// KeyPrefix is prepended to every top-level key, such as "app." giving
+	// app.name, so documents from several services can be concatenated
+	// into one prompt without their keys colliding.
+	KeyPrefix string
+
+	// Audience selects the fields tagged for a destination, such as "llm",
+	// "log" or "api", so one type can produce a document for each. Fields
+	// with only= tag options, as in `toon:"debugInfo,only=internal"` or
+	// only=log|api, are written only when Audience is one of those named;
+	// other fields are always written.
+	Audience string
+
+	// ShortenIDs replaces UUIDs and hex strings of 16 or more digits, such
+	// as hashes, with references like @1 that cost far fewer tokens, and
+	// adds an "_ids[N]{ref,id}:" table mapping them back at the top of the
+	// document. Each identifier gets one reference however often it
+	// occurs. Strings that look like references are quoted. Decode with
+	// UnmarshalOptions.ResolveIDs to get the identifiers back.
+	ShortenIDs bool
+
+	// KeyTranslator, when set, maps the name of every struct field, or
+	// its toonCol name in table headers, to the key written for it, such
+	// as "distanceKm" to "distance_km", so prompts can use their own
+	// vocabulary. Map keys are written as they are. The keys it returns
+	// must not contain colons or delimiters.
+	KeyTranslator func(name string) string
+}
+
+type UnmarshalOptions struct {
+	// TabWidth is the number of columns a leading tab counts for when
+	// measuring indentation. Zero uses the default of 2.
+	TabWidth int
+
+	// Delimiter splits inline and tabular array values. When empty the
+	// delimiter is guessed per row, preferring tab, then pipe, then
+	// semicolon, then comma.
+	Delimiter Delimiter
+
+	// AutoDetectDelimiter, when Delimiter is empty, picks one delimiter for
+	// the whole document before decoding: the one that gives every sampled
+	// table row as many cells as its header has columns and every inline
+	// array its declared count. A row holding a stray pipe then no longer
+	// switches it away from commas. When no delimiter fits, decoding fails
+	// with a *SyntaxError describing how each one found in the document
+	// splits its first mismatching line.
+	AutoDetectDelimiter bool
+
+	// Strict rejects input that is otherwise tolerated, such as blank
+	// cells, trailing delimiters and arrays whose item count differs from
+	// the length in their header.
+	Strict bool
+
+	// DisallowUnknownFields rejects keys and table columns that match no
+	// field of the struct being decoded, with a *SyntaxError for each,
+	// instead of skipping them. Decoding carries on past them, so that
+	// every misspelled key in a document is reported at once.
+	DisallowUnknownFields bool
+
+	// WeaklyTypedInput coerces scalars across types where the intent is
+	// clear: numbers and booleans into each other, floats truncated into
+	// integers, empty values into zero values, and a single scalar into a
+	// one-element slice.
+	WeaklyTypedInput bool
+
+	// StrictTypes disables implicit coercion: quoted values into numbers
+	// or booleans, non-integer numbers into integers and 1/0 into booleans
+	// fail with an *UnmarshalTypeError. It takes precedence over
+	// WeaklyTypedInput.
+	StrictTypes bool
+
+	// Warnings, when set, collects non-fatal issues found while decoding:
+	// coerced values, ignored cells, duplicate keys and length mismatches.
+	Warnings *[]Warning
+
+	// RequireVersion rejects documents without a "#toon" directive. A
+	// directive that is present is always validated.
+	RequireVersion bool
+
+	// VerifyChecksum requires a "#crc32" footer and rejects the document
+	// if it does not match the content before it.
+	VerifyChecksum bool
+
+	// TimeLocation, when set, converts decoded time.Time values to this
+	// location, including those read from unix timestamps.
+	TimeLocation *time.Location
+
+	// TimeFormat is the layout, as for time.Parse, that time.Time values
+	// are read in, matching MarshalOptions.TimeFormat. Empty means RFC
+	// 3339. Fields with a format= tag option are read in that layout.
+	TimeFormat string
+
+	// OnValue, when set, is called with the path (such as hikes[2].name)
+	// and raw text of every scalar before it is assigned. An error aborts
+	// decoding and is returned as is.
+	OnValue func(path, raw string) error
+
+	// KeyPrefix is stripped from top-level keys before they are matched,
+	// undoing MarshalOptions.KeyPrefix. Top-level keys without it are
+	// decoded as they are, so the keys of other services sharing the
+	// document stay unknown.
+	KeyPrefix string
+
+	// MergeMaps decodes a nested block for a key that a destination map
+	// already holds into the existing element instead of a zero value, so
+	// documents of overrides can be loaded one after another. Maps, structs
+	// and interfaces holding map[string]any are merged recursively, keeping
+	// keys and fields the block does not mention; scalars and arrays are
+	// replaced. Maps and pointers held by existing elements are updated in
+	// place. Without it, every key read replaces the element as a whole,
+	// though keys the document does not mention are always kept.
+	MergeMaps bool
+
+	// ResolveIDs reads the "_ids" table written by
+	// MarshalOptions.ShortenIDs and decodes the unquoted references it
+	// lists as the identifiers they stand for. The table itself is not
+	// decoded as a key.
+	ResolveIDs bool
+
+	// KeyTranslator, when set, maps every key and column name read for a
+	// struct back to the field name it stands for, undoing
+	// MarshalOptions.KeyTranslator. A unit such as (km) is removed from
+	// column names before they are passed to it.
+	KeyTranslator func(key string) string
+
+	// MaxLineBytes rejects documents holding a line longer than this many
+	// bytes, not counting its line break, with an error wrapping
+	// ErrLineTooLong that names the line. Zero places no limit: lines of
+	// any length, such as inline arrays of millions of values, are read
+	// whole. Decoder and Transcoder stop reading a line once it exceeds
+	// the limit.
+	MaxLineBytes int
 }
 
 var (
@@ -25,53 +304,159 @@ var (
 	ErrUnmarshalType   = errors.New("toon: cannot unmarshal into non-pointer value")
 	ErrNilPointer      = errors.New("toon: cannot unmarshal into nil pointer")
 	ErrUnsupportedType = errors.New("toon: unsupported type")
+	ErrInvalidOptions  = errors.New("toon: invalid options")
+	ErrVersion         = errors.New("toon: unsupported format version")
+	ErrChecksum        = errors.New("toon: checksum mismatch")
+	ErrKeyCollision    = errors.New("toon: duplicate top-level key")
+	ErrLineTooLong     = errors.New("toon: line too long")
 )
 
+// SyntaxError describes input that cannot be decoded, at a 1-based Line
+// and Column. errors.Is matches it against ErrInvalidSyntax, and against
+// Err when set. Values of the wrong type for their destination are
+// reported as *UnmarshalTypeError instead.
 type SyntaxError struct {
 	Line    int
 	Column  int
 	Message string
+
+	// Offset is the byte offset of Line and Column in the decoded input.
+	Offset int
+
+	// Content is the text of Line without its indentation, or its first
+	// MaxLineBytes bytes when it is too long.
+	Content string
+
+	// Err is the more specific error the problem is an instance of, such
+	// as ErrVersion, ErrChecksum or ErrLineTooLong.
+	Err error
 }
 
 func (e *SyntaxError) Error() string {
 	return fmt.Sprintf("toon: syntax error at line %d, column %d: %s", e.Line, e.Column, e.Message)
 }
 
+func (e *SyntaxError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrInvalidSyntax}
+	}
+	return []error{ErrInvalidSyntax, e.Err}
+}
+
+type Warning struct {
+	Line    int
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+}
+
+type UnsupportedTypeError struct {
+	Path string
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("toon: unsupported type %s", e.Type)
+	}
+	return fmt.Sprintf("toon: unsupported type %s at %s", e.Type, e.Path)
+}
+
+func (e *UnsupportedTypeError) Unwrap() error {
+	return ErrUnsupportedType
+}
+
+// UnmarshalTypeError describes a value that cannot be decoded into the Go
+// type at Path.
+type UnmarshalTypeError struct {
+	Value string
+	Type  reflect.Type
+	Path  string
+	Line  int
+
+	// Offset is the byte offset in the decoded input of the first
+	// non-blank character of Line.
+	Offset int
+
+	// Err is the reason the parser or unmarshaler of Type gave for
+	// rejecting Value, such as a *time.ParseError, if it gave one.
+	Err error
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	msg := fmt.Sprintf("toon: cannot unmarshal %s into %s at line %d", e.Value, e.Type, e.Line)
+	if e.Path != "" {
+		msg = fmt.Sprintf("toon: cannot unmarshal %s into %s at %s, line %d", e.Value, e.Type, e.Path, e.Line)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *UnmarshalTypeError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is returned when decoding finds more than one problem, such as
+// several blank cells in strict mode or values of the wrong type, each of
+// which only affects its own value. errors.Is and errors.As look through
+// it to the individual errors.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e Errors) Unwrap() []error {
+	return e
+}
+
 func DefaultMarshalOptions() MarshalOptions {
 	return MarshalOptions{
-		Indent:     2,
-		Delimiter:  DelimiterComma,
-		UseTabular: true,
+		Indent:            2,
+		Delimiter:         DelimiterComma,
+		UseTabular:        true,
+		KeyValueSeparator: ": ",
+	}
+}
+
+func DefaultUnmarshalOptions() UnmarshalOptions {
+	return UnmarshalOptions{
+		TabWidth: 2,
 	}
 }
 
-func Marshal(v any) ([]byte, error) {
-	return MarshalWithOptions(v, DefaultMarshalOptions())
+func Marshal(v any, options ...MarshalOption) ([]byte, error) {
+	opts := DefaultMarshalOptions()
+	for _, option := range options {
+		if err := option(&opts); err != nil {
+			return nil, err
+		}
+	}
+	return MarshalWithOptions(v, opts)
 }
 
 func MarshalWithOptions(v any, opts MarshalOptions) ([]byte, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
 	e := newEncoder(opts)
 	return e.encode(v)
 }
 
 func Unmarshal(data []byte, v any) error {
-	d := newDecoder(data)
-	return d.decode(v)
+	return UnmarshalWithOptions(data, v, DefaultUnmarshalOptions())
 }
 
-func Valid(data []byte) bool {
-	input := string(data)
-	lines := strings.Split(input, "\n")
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
-			continue
-		}
-		if !strings.Contains(trimmed, ":") && !strings.Contains(trimmed, "[") {
-			return false
-		}
-	}
-
-	return true
+func UnmarshalWithOptions(data []byte, v any, opts UnmarshalOptions) error {
+	d := newDecoder(data, opts)
+	return d.decode(v)
 }
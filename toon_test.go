@@ -1,6 +1,7 @@
 package toon_test
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -28,6 +29,44 @@ type HikesData struct {
 	Hikes   []Hike   `toon:"hikes"`
 }
 
+// marshalCase is a value, the options to write it with and the document
+// it should become, for table-driven tests of how features are written.
+type marshalCase struct {
+	name string
+	in   any
+	opts []toon.MarshalOption
+	want string
+}
+
+// testMarshalCases checks that each case is written as its document and
+// that the document decodes, in strict mode, back to the value written.
+func testMarshalCases(t *testing.T, tests []marshalCase) {
+	t.Helper()
+	opts := toon.DefaultUnmarshalOptions()
+	opts.Strict = true
+	opts.StrictTypes = true
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := toon.Marshal(tt.in, tt.opts...)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Fatalf("Marshal = %q, want %q", data, tt.want)
+			}
+
+			out := reflect.New(reflect.TypeOf(tt.in))
+			if err := toon.UnmarshalWithOptions(data, out.Interface(), opts); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(out.Elem().Interface(), tt.in) {
+				t.Errorf("round trip = %+v, want %+v", out.Elem(), tt.in)
+			}
+		})
+	}
+}
+
 func TestMarshalSimple(t *testing.T) {
 	data := struct {
 		Name  string `toon:"name"`
@@ -167,7 +206,7 @@ func TestMarshalWithTabDelimiter(t *testing.T) {
 		t.Fatalf("Marshal failed: %v", err)
 	}
 
-	expected := "numbers[5]: 1\t2\t3\t4\t5\n"
+	expected := "numbers[5\t]: 1\t2\t3\t4\t5\n"
 	if string(result) != expected {
 		t.Errorf("Expected:\n%q\nGot:\n%q", expected, string(result))
 	}
@@ -300,12 +339,12 @@ func TestRoundTrip(t *testing.T) {
 
 func TestValid(t *testing.T) {
 	validToon := "name: Alice\nage: 30\n"
-	if !toon.Valid([]byte(validToon)) {
+	if !toon.Valid([]byte(validToon), toon.LevelSyntax) {
 		t.Error("Expected valid TOON to be valid")
 	}
 
 	invalidToon := "invalid syntax here"
-	if toon.Valid([]byte(invalidToon)) {
+	if toon.Valid([]byte(invalidToon), toon.LevelSyntax) {
 		t.Error("Expected invalid TOON to be invalid")
 	}
 }
@@ -0,0 +1,236 @@
+package toon
+
+import (
+	"bufio"
+	"errors"
+	"hash/crc32"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// transcodeHeaderPattern matches an array header after any list dash,
+// capturing its key, count, declared delimiter, column list, separator and
+// the text after it. The separator is the colon, or the '=' of inline
+// arrays written with an "=" KeyValueSeparator, with any spacing before
+// it. Root arrays and arrays as list items have no key.
+var transcodeHeaderPattern = regexp.MustCompile(`^((?:"(?:[^"\\]|\\.)*"|[^\[":])*)\[(\d+|\?)?([,\t|;])?\](?:\{((?:"(?:[^"\\]|\\.)*"|[^}"])+)\})? *([:=])(.*)$`)
+
+// Transcoder rewrites TOON documents from one dialect into another line by
+// line, without decoding them or holding more than a line in memory, for
+// gateways normalizing documents they only pass on.
+//
+// Indentation is measured with the TabWidth and delimiters found as the
+// source options say, and written with the Indent, Delimiter,
+// OmitArrayCounts, VersionHeader and Checksum of the target options. Cells
+// that would be split by the new delimiter are quoted. Other options do
+// not apply, as values are copied rather than re-encoded. A Transcoder is
+// safe for concurrent use.
+type Transcoder struct {
+	from UnmarshalOptions
+	to   MarshalOptions
+}
+
+// NewTranscoder returns a Transcoder reading documents written with from
+// and writing them as to describes.
+func NewTranscoder(from UnmarshalOptions, to MarshalOptions) (*Transcoder, error) {
+	if err := to.validate(); err != nil {
+		return nil, err
+	}
+	if from.TabWidth <= 0 {
+		from.TabWidth = DefaultUnmarshalOptions().TabWidth
+	}
+	return &Transcoder{from: from, to: to}, nil
+}
+
+// Transcode reads a document from src and writes it to dst. A "#crc32"
+// footer in the source is recomputed for the output.
+func (t *Transcoder) Transcode(dst io.Writer, src io.Reader) error {
+	out := bufio.NewWriter(dst)
+	hash := crc32.NewIEEE()
+	tc := &transcoder{
+		t:           t,
+		d:           newDecoder(nil, t.from),
+		out:         io.MultiWriter(out, hash),
+		tableIndent: -1,
+	}
+
+	in := bufio.NewReader(src)
+	footer := t.to.Checksum
+	for n, offset := 1, 0; ; n++ {
+		first := n == 1
+		line, err := readLine(in, t.from.MaxLineBytes, n, offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if line == "" && err != nil {
+			break
+		}
+		offset += len(line)
+		line = strings.TrimRight(line, "\r\n")
+
+		if first && t.to.VersionHeader && !strings.HasPrefix(line, versionDirective) {
+			tc.write(versionDirective + FormatVersion)
+		}
+		if isChecksumFooter(line) {
+			footer = true
+		} else {
+			tc.line(line)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if footer {
+		out.Write(appendChecksumFooter(nil, hash.Sum32()))
+	}
+	if tc.err != nil {
+		return tc.err
+	}
+	return out.Flush()
+}
+
+// transcoder holds the state of one Transcode call.
+type transcoder struct {
+	t   *Transcoder
+	d   *decoder
+	out io.Writer
+	err error
+
+	// levels holds the source indentation of every open nesting level
+	levels []int
+
+	// tableIndent is the indentation of the header of the table whose rows
+	// are being read, or -1
+	tableIndent int
+	tableDelim  Delimiter
+	sparse      bool
+}
+
+func (tc *transcoder) write(line string) {
+	if tc.err == nil {
+		_, tc.err = io.WriteString(tc.out, line+"\n")
+	}
+}
+
+// depth returns the nesting depth of a line indented by indent. Content
+// lines close the levels indented deeper and open one if none matches.
+func (tc *transcoder) depth(indent int, content bool) int {
+	if !content {
+		depth := 0
+		for _, level := range tc.levels {
+			if level < indent {
+				depth++
+			}
+		}
+		return depth
+	}
+
+	for len(tc.levels) > 0 && tc.levels[len(tc.levels)-1] > indent {
+		tc.levels = tc.levels[:len(tc.levels)-1]
+	}
+	if len(tc.levels) == 0 || tc.levels[len(tc.levels)-1] < indent {
+		tc.levels = append(tc.levels, indent)
+	}
+	return len(tc.levels) - 1
+}
+
+func (tc *transcoder) line(line string) {
+	text := strings.TrimSpace(line)
+	if text == "" {
+		tc.write("")
+		return
+	}
+	indent := tc.d.getIndent(line)
+	if strings.HasPrefix(text, "#") {
+		tc.write(strings.Repeat(" ", tc.depth(indent, false)*tc.t.to.Indent) + text)
+		return
+	}
+
+	prefix := strings.Repeat(" ", tc.depth(indent, true)*tc.t.to.Indent)
+	if tc.tableIndent >= 0 && indent > tc.tableIndent {
+		tc.write(prefix + tc.row(text))
+		return
+	}
+	tc.tableIndent = -1
+
+	lead := ""
+	if text == "-" || strings.HasPrefix(text, "- ") {
+		lead, text = "- ", strings.TrimSpace(text[1:])
+		// The fields of an item line up one level below its dash
+		tc.levels = append(tc.levels, indent+2)
+	}
+
+	m := transcodeHeaderPattern.FindStringSubmatch(text)
+	if m == nil {
+		tc.write(prefix + lead + text)
+		return
+	}
+	key, count, hint, fields, sep, value := m[1], m[2], Delimiter(m[3]), m[4], m[5], strings.TrimSpace(m[6])
+
+	var b strings.Builder
+	b.WriteString(prefix + lead + key + "[")
+	if !tc.t.to.OmitArrayCounts {
+		b.WriteString(count)
+	}
+	if tc.t.to.Delimiter != DelimiterComma {
+		b.WriteString(string(tc.t.to.Delimiter))
+	}
+	b.WriteString("]")
+	if fields != "" {
+		b.WriteString("{" + tc.join(splitQuoted(fields, string(tc.delimiter(hint, fields))), false) + "}")
+	}
+	if sep == "=" {
+		b.WriteString(" =")
+	} else {
+		b.WriteString(":")
+	}
+
+	switch {
+	case value != "":
+		b.WriteString(" " + tc.join(splitQuoted(value, string(tc.delimiter(hint, value))), false))
+	case fields != "":
+		tc.tableIndent, tc.tableDelim, tc.sparse = indent, hint, fields == sparseField
+		if lead != "" {
+			tc.tableIndent += 2
+		}
+	}
+	tc.write(b.String())
+}
+
+// row rewrites a table row with the target delimiter.
+func (tc *transcoder) row(text string) string {
+	return tc.join(splitQuoted(text, string(tc.delimiter(tc.tableDelim, text))), tc.sparse)
+}
+
+// delimiter returns the delimiter the source splits s on.
+func (tc *transcoder) delimiter(hint Delimiter, s string) Delimiter {
+	switch {
+	case hint != "":
+		return hint
+	case tc.t.from.Delimiter != "":
+		return tc.t.from.Delimiter
+	}
+	return guessDelimiter(s)
+}
+
+// join joins cells with the target delimiter, quoting those it would
+// split. In sparse rows only the value of each column=value pair is.
+func (tc *transcoder) join(cells []string, sparse bool) string {
+	delim := string(tc.t.to.Delimiter)
+	for i, cell := range cells {
+		cell = strings.TrimSpace(cell)
+		name, value := "", cell
+		if sparse {
+			if _, v, ok := cutSparsePair(cell); ok {
+				name, value = cell[:len(cell)-len(v)], v
+			}
+		}
+		if !isQuoted(value) && strings.Contains(value, delim) {
+			value = quoteString(value)
+		}
+		cells[i] = name + value
+	}
+	return strings.Join(cells, delim)
+}
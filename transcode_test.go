@@ -0,0 +1,87 @@
+package toon_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestTranscodeMatchesMarshal(t *testing.T) {
+	type Stop struct {
+		Name string `toon:"name"`
+		Km   int    `toon:"km"`
+	}
+	type Day struct {
+		Title string            `toon:"title"`
+		Stops []Stop            `toon:"stops"`
+		Meta  map[string]string `toon:"meta"`
+	}
+	in := struct {
+		Data HikesData `toon:"data"`
+		Days []Day     `toon:"days"`
+		Grid [][]int   `toon:"grid"`
+	}{
+		Data: HikesData{
+			Context: Context{Task: "Our favorite hikes", Location: "Boulder", Season: "spring"},
+			Friends: []string{"ana", "luis"},
+			Hikes:   []Hike{{ID: 1, Name: "Blue Lake", DistanceKm: 7.5}, {ID: 2, Name: "Ridge"}},
+		},
+		Days: []Day{{Title: "one", Stops: []Stop{{"hut", 3}}, Meta: map[string]string{"by": "ana"}}},
+		Grid: [][]int{{1, 2}, {3}},
+	}
+
+	src, err := toon.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	for _, to := range []toon.MarshalOptions{
+		{Indent: 4, Delimiter: toon.DelimiterTab, UseTabular: true},
+		{Indent: 1, Delimiter: toon.DelimiterPipe, UseTabular: true, OmitArrayCounts: true, Checksum: true},
+	} {
+		want, err := toon.MarshalWithOptions(in, to)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+
+		tr, err := toon.NewTranscoder(toon.DefaultUnmarshalOptions(), to)
+		if err != nil {
+			t.Fatalf("NewTranscoder failed: %v", err)
+		}
+		var got bytes.Buffer
+		if err := tr.Transcode(&got, bytes.NewReader(src)); err != nil {
+			t.Fatalf("Transcode failed: %v", err)
+		}
+		if got.String() != string(want) {
+			t.Errorf("Transcode to %q =\n%s\nwant\n%s", to.Delimiter, got.String(), want)
+		}
+	}
+}
+
+func TestTranscodeQuotesCells(t *testing.T) {
+	src := "# trails\r\nrows[2]{id,name}:\r\n  1,Blue|Lake\r\n  2,\"Ridge, north\"\r\nsparse[1,]{=}:\r\n  a=x|y,b=2\r\ntags[2] = a|b,c\r\n"
+	to := toon.DefaultMarshalOptions()
+	to.Delimiter = toon.DelimiterPipe
+
+	from := toon.DefaultUnmarshalOptions()
+	from.Delimiter = toon.DelimiterComma
+	tr, err := toon.NewTranscoder(from, to)
+	if err != nil {
+		t.Fatalf("NewTranscoder failed: %v", err)
+	}
+	var got bytes.Buffer
+	if err := tr.Transcode(&got, strings.NewReader(src)); err != nil {
+		t.Fatalf("Transcode failed: %v", err)
+	}
+	want := "# trails\nrows[2|]{id|name}:\n  1|\"Blue|Lake\"\n  2|\"Ridge, north\"\nsparse[1|]{=}:\n  a=\"x|y\"|b=2\ntags[2|] = \"a|b\"|c\n"
+	if got.String() != want {
+		t.Errorf("Transcode = %q, want %q", got.String(), want)
+	}
+
+	if _, err := toon.NewTranscoder(from, toon.MarshalOptions{}); !errors.Is(err, toon.ErrInvalidOptions) {
+		t.Errorf("NewTranscoder with zero options = %v, want ErrInvalidOptions", err)
+	}
+}
@@ -0,0 +1,110 @@
+package toon
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// TypeOptions are encoding defaults applied wherever values of a
+// registered type appear in a document, without tagging every field.
+type TypeOptions struct {
+	// ListFormat writes arrays of the type, or arrays whose elements are
+	// of the type, in list form instead of tabular or inline form.
+	ListFormat bool
+
+	// FloatPrecision, when positive, writes floats of the type, and the
+	// float fields of structs of the type, with this many decimals.
+	FloatPrecision int
+
+	// Methods names methods of a struct type, such as Pace, whose results
+	// are written as derived fields after the declared ones, keyed by the
+	// method name with its first letter lowered. Each must take no
+	// arguments and return a single value; pointer receivers are allowed.
+	// Decoding ignores derived fields.
+	Methods []string
+}
+
+var typeOptions sync.Map // reflect.Type -> TypeOptions
+
+// RegisterTypeOptions sets the encoding defaults for values of type t,
+// which may be given as a pointer type, replacing any set before. It is
+// safe for concurrent use. It panics if a method named in opts.Methods is
+// missing or does not fit.
+func RegisterTypeOptions(t reflect.Type, opts TypeOptions) {
+	t = derefType(t)
+	for _, name := range opts.Methods {
+		m, ok := reflect.PointerTo(t).MethodByName(name)
+		if !ok || t.Kind() != reflect.Struct || m.Type.NumIn() != 1 || m.Type.NumOut() != 1 {
+			panic(fmt.Sprintf("toon: %s has no method %s taking no arguments and returning one value", t, name))
+		}
+	}
+	typeOptions.Store(t, opts)
+	derivedFieldCache.Store(t, methodFields(t, opts.Methods))
+}
+
+var derivedFieldCache sync.Map // reflect.Type -> []structField
+
+// derivedFields returns the fields written for the methods registered for
+// the struct type t, as worked out when they were registered.
+func derivedFields(t reflect.Type) []structField {
+	fields, ok := derivedFieldCache.Load(derefType(t))
+	if !ok {
+		return nil
+	}
+	return fields.([]structField)
+}
+
+// methodFields returns a field for each of the named methods of t.
+func methodFields(t reflect.Type, methods []string) []structField {
+	if len(methods) == 0 {
+		return nil
+	}
+
+	fields := make([]structField, len(methods))
+	for i, name := range methods {
+		m, _ := reflect.PointerTo(t).MethodByName(name)
+		key := getFieldName(reflect.StructField{Name: name})
+		fields[i] = structField{
+			name:   key,
+			typ:    m.Type.Out(0),
+			field:  reflect.StructField{Name: name, Type: m.Type.Out(0)},
+			column: key,
+			method: name,
+		}
+	}
+	return fields
+}
+
+func lookupTypeOptions(t reflect.Type) (TypeOptions, bool) {
+	opts, ok := typeOptions.Load(derefType(t))
+	if !ok {
+		return TypeOptions{}, false
+	}
+	return opts.(TypeOptions), true
+}
+
+func isListFormatType(t reflect.Type) bool {
+	opts, ok := lookupTypeOptions(t)
+	return ok && opts.ListFormat
+}
+
+// enterType applies the float precision registered for t until the
+// returned function is called.
+func (e *encoder) enterType(t reflect.Type) func() {
+	opts, ok := lookupTypeOptions(t)
+	if !ok || opts.FloatPrecision <= 0 {
+		return func() {}
+	}
+
+	saved := e.floatPrecision
+	e.floatPrecision = opts.FloatPrecision
+	return func() { e.floatPrecision = saved }
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,110 @@
+package toon_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestRegisterTypeOptions(t *testing.T) {
+	type Price float64
+	type Point struct {
+		Lat float64 `toon:"lat"`
+		Lng float64 `toon:"lng"`
+	}
+	type Step struct {
+		Name string `toon:"name"`
+	}
+	type Route struct {
+		Cost   Price   `toon:"cost"`
+		Points []Point `toon:"points"`
+		Steps  []Step  `toon:"steps"`
+		Ratio  float64 `toon:"ratio"`
+	}
+
+	toon.RegisterTypeOptions(reflect.TypeOf(Price(0)), toon.TypeOptions{FloatPrecision: 2})
+	toon.RegisterTypeOptions(reflect.TypeOf(&Point{}), toon.TypeOptions{FloatPrecision: 3})
+	toon.RegisterTypeOptions(reflect.TypeOf(Step{}), toon.TypeOptions{ListFormat: true})
+
+	in := Route{
+		Cost:   12.5,
+		Points: []Point{{Lat: 40.01499, Lng: -105.27055}},
+		Steps:  []Step{{Name: "start"}, {Name: "end"}},
+		Ratio:  0.125,
+	}
+
+	data, err := toon.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := "cost: 12.50\npoints[1]{lat,lng}:\n  40.015,-105.271\nsteps[2]:\n  - name: start\n  - name: end\nratio: 0.125\n"
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+
+	var out Route
+	if err := toon.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out.Cost != 12.5 || len(out.Steps) != 2 || out.Steps[1].Name != "end" {
+		t.Errorf("got %+v", out)
+	}
+}
+
+type timedHike struct {
+	Name    string  `toon:"name"`
+	Km      float64 `toon:"km"`
+	Minutes float64 `toon:"minutes"`
+}
+
+func (h timedHike) Pace() float64 { return h.Minutes / h.Km }
+
+func (h *timedHike) Label() string { return strings.ToUpper(h.Name) }
+
+func TestDerivedFields(t *testing.T) {
+	toon.RegisterTypeOptions(reflect.TypeOf(timedHike{}), toon.TypeOptions{Methods: []string{"Pace", "Label"}})
+	defer toon.RegisterTypeOptions(reflect.TypeOf(timedHike{}), toon.TypeOptions{})
+
+	in := struct {
+		Longest timedHike   `toon:"longest"`
+		Hikes   []timedHike `toon:"hikes"`
+	}{
+		Longest: timedHike{Name: "Ridge", Km: 10, Minutes: 150},
+		Hikes:   []timedHike{{Name: "Lake", Km: 5, Minutes: 60}, {Name: "Ridge", Km: 10, Minutes: 150}},
+	}
+	data, err := toon.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := "longest:\n  name: Ridge\n  km: 10\n  minutes: 150\n  pace: 15\n  label: RIDGE\n" +
+		"hikes[2]{name,km,minutes,pace,label}:\n  Lake,5,60,12,LAKE\n  Ridge,10,150,15,RIDGE\n"
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+
+	// Derived fields are skipped when decoding
+	out := in
+	out.Hikes = nil
+	if err := toon.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+
+	// Registering again replaces the derived fields
+	toon.RegisterTypeOptions(reflect.TypeOf(timedHike{}), toon.TypeOptions{Methods: []string{"Pace"}})
+	data, err = toon.Marshal(in.Longest)
+	if want := "name: Ridge\nkm: 10\nminutes: 150\npace: 15\n"; err != nil || string(data) != want {
+		t.Errorf("Marshal after registering again = %q, %v, want %q", data, err, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterTypeOptions accepted a missing method")
+		}
+	}()
+	toon.RegisterTypeOptions(reflect.TypeOf(timedHike{}), toon.TypeOptions{Methods: []string{"Pace", "String"}})
+}
@@ -0,0 +1,195 @@
+package toon
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Level is how much Valid and Validate require of a document.
+type Level struct {
+	structure bool
+	schema    reflect.Type
+}
+
+var (
+	// LevelSyntax requires every line to be a comment, a key with its
+	// value, an array header, a table row or a list item.
+	LevelSyntax = Level{}
+
+	// LevelStructure additionally requires nested lines to be indented
+	// one step deeper than their parent, arrays to hold as many items as
+	// their headers declare and table rows to have one cell per column.
+	LevelStructure = Level{structure: true}
+)
+
+// LevelSchema additionally requires the document to decode into the type
+// of v in strict mode, with StrictTypes and without keys or columns the
+// type does not have.
+func LevelSchema(v any) Level {
+	return Level{structure: true, schema: reflect.TypeOf(v)}
+}
+
+// Valid reports whether data is a TOON document at the given level.
+func Valid(data []byte, level Level) bool {
+	return Validate(data, level) == nil
+}
+
+// Validate is Valid returning the first problem found, usually a
+// *SyntaxError locating it, or Errors from decoding in the schema level.
+func Validate(data []byte, level Level) error {
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return err
+	}
+	v := &validator{doc: doc, d: newDecoder(data, DefaultUnmarshalOptions())}
+
+	if level.structure {
+		if err := v.structure(doc.nodes, 0); err != nil {
+			return err
+		}
+	}
+	if level.schema == nil {
+		return nil
+	}
+	if err := v.schema(doc.nodes, level.schema); err != nil {
+		return err
+	}
+
+	opts := DefaultUnmarshalOptions()
+	opts.Strict = true
+	opts.StrictTypes = true
+	return UnmarshalWithOptions(data, reflect.New(level.schema).Interface(), opts)
+}
+
+type validator struct {
+	doc *Document
+	d   *decoder
+}
+
+func (v *validator) errorf(n *node, format string, args ...any) error {
+	return v.d.syntaxError(n.line+1, fmt.Sprintf(format, args...))
+}
+
+// structure checks nodes, which should all be indented by indent, and
+// everything below them.
+func (v *validator) structure(nodes []*node, indent int) error {
+	unit := v.doc.unit
+	for _, n := range nodes {
+		if n.indent != indent {
+			return v.errorf(n, "indented %d columns, expected %d", n.indent, indent)
+		}
+
+		switch n.kind {
+		case blockNode:
+			if err := v.structure(n.children, indent+unit); err != nil {
+				return err
+			}
+		case inlineNode:
+			if err := v.count(n, len(v.doc.cells(n))); err != nil {
+				return err
+			}
+		case tableNode:
+			if err := v.count(n, len(n.children)); err != nil {
+				return err
+			}
+			sparse := len(n.fields) == 1 && n.fields[0] == sparseField
+			for _, row := range n.children {
+				if row.indent != indent+unit {
+					return v.errorf(row, "indented %d columns, expected %d", row.indent, indent+unit)
+				}
+				if cells := len(v.doc.cells(row)); !sparse && cells != len(n.fields) {
+					return v.errorf(row, "row has %d cells, header declares %d columns", cells, len(n.fields))
+				}
+			}
+		case listNode:
+			if err := v.count(n, len(n.children)); err != nil {
+				return err
+			}
+			for _, item := range n.children {
+				if item.indent != indent+unit {
+					return v.errorf(item, "indented %d columns, expected %d", item.indent, indent+unit)
+				}
+				// Fields of an item line up with the one on its dash line
+				if err := v.structure(item.children, item.indent+2); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// count checks the number of items of array n against its header, unless
+// the header leaves the count open.
+func (v *validator) count(n *node, found int) error {
+	m := arrayCountPattern.FindStringSubmatch(v.doc.lines[n.line])
+	if m == nil {
+		return nil
+	}
+	if declared, _ := strconv.Atoi(m[1]); declared != found {
+		return v.errorf(n, "array declares %d items, found %d", declared, found)
+	}
+	return nil
+}
+
+// schema checks that every key and column below nodes names a field of t.
+// Maps accept any key and interfaces anything at all.
+func (v *validator) schema(nodes []*node, t reflect.Type) error {
+	t = derefType(t)
+	for _, n := range nodes {
+		var field reflect.Type
+		switch {
+		case t.Kind() == reflect.Map:
+			field = t.Elem()
+		case t.Kind() == reflect.Struct && !isScalarType(t):
+			key, _, _ := strings.Cut(n.key, foldedColumnSep)
+			f, ok := fieldsByName(t)[key]
+			if !ok {
+				return v.errorf(n, "unknown key %q for %s", key, t)
+			}
+			if key != n.key {
+				continue
+			}
+			field = f.typ
+		default:
+			return nil
+		}
+
+		if err := v.schemaNode(n, derefType(field)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *validator) schemaNode(n *node, t reflect.Type) error {
+	switch n.kind {
+	case blockNode:
+		return v.schema(n.children, t)
+	case tableNode, listNode:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array && t.Kind() != reflect.Map {
+			return nil
+		}
+		elem := derefType(t.Elem())
+		if n.kind == listNode {
+			for _, item := range n.children {
+				if err := v.schema(item.children, elem); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if elem.Kind() != reflect.Struct || isScalarType(elem) || n.fields[0] == sparseField {
+			return nil
+		}
+		columns := fieldsByColumn(elem)
+		for _, name := range n.fields {
+			if _, ok := columns[name]; !ok {
+				return v.errorf(n, "unknown column %q for %s", name, elem)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package toon_test
+
+import (
+	"errors"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+type validHike struct {
+	ID   int    `toon:"id"`
+	Name string `toon:"name"`
+}
+
+type validTrip struct {
+	Context struct {
+		Task string `toon:"task"`
+	} `toon:"context"`
+	Friends []string    `toon:"friends"`
+	Hikes   []validHike `toon:"hikes"`
+}
+
+func TestValidLevels(t *testing.T) {
+	schema := toon.LevelSchema(validTrip{})
+
+	tests := []struct {
+		name      string
+		input     string
+		syntax    bool
+		structure bool
+		schema    bool
+	}{
+		{
+			name:      "valid",
+			input:     "context:\n  task: hiking\nfriends[2]: ana,luis\nhikes[2]{id,name}:\n  1,Blue Lake\n  2,Ridge\n",
+			syntax:    true,
+			structure: true,
+			schema:    true,
+		},
+		{
+			name:   "not toon",
+			input:  "{\n  \"context\": 1\n}\n",
+			syntax: false,
+		},
+		{
+			name:   "row count differs from header",
+			input:  "hikes[3]{id,name}:\n  1,Blue Lake\n  2,Ridge\n",
+			syntax: true,
+		},
+		{
+			name:   "row with extra cell",
+			input:  "hikes[2]{id,name}:\n  1,Blue Lake,x\n  2,Ridge\n",
+			syntax: true,
+		},
+		{
+			name:   "inline count differs",
+			input:  "friends[3]: ana,luis\n",
+			syntax: true,
+		},
+		{
+			name:   "uneven indentation",
+			input:  "context:\n  task: hiking\nhikes[2]{id,name}:\n    1,Blue Lake\n    2,Ridge\n",
+			syntax: true,
+		},
+		{
+			name:      "unknown key",
+			input:     "context:\n  task: hiking\n  mood: great\n",
+			syntax:    true,
+			structure: true,
+		},
+		{
+			name:      "unknown column",
+			input:     "hikes[1]{id,elevation}:\n  1,320\n",
+			syntax:    true,
+			structure: true,
+		},
+		{
+			name:      "wrong type",
+			input:     "hikes[1]{id,name}:\n  one,Blue Lake\n",
+			syntax:    true,
+			structure: true,
+		},
+		{
+			name:      "open count",
+			input:     "hikes[]{id,name}:\n  1,Blue Lake\n",
+			syntax:    true,
+			structure: true,
+			schema:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.input)
+			if got := toon.Valid(data, toon.LevelSyntax); got != tt.syntax {
+				t.Errorf("Valid(LevelSyntax) = %v, want %v", got, tt.syntax)
+			}
+			if got := toon.Valid(data, toon.LevelStructure); got != tt.structure {
+				t.Errorf("Valid(LevelStructure) = %v, want %v", got, tt.structure)
+			}
+			if got := toon.Valid(data, schema); got != tt.schema {
+				t.Errorf("Valid(LevelSchema) = %v, want %v: %v", got, tt.schema, toon.Validate(data, schema))
+			}
+		})
+	}
+}
+
+func TestValidateReportsLine(t *testing.T) {
+	input := "friends[2]: ana,luis\nhikes[2]{id,name}:\n  1,Blue Lake\n  2\n"
+	err := toon.Validate([]byte(input), toon.LevelStructure)
+
+	var syntaxErr *toon.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected *SyntaxError, got %v", err)
+	}
+	if syntaxErr.Line != 4 {
+		t.Errorf("Line = %d, want 4", syntaxErr.Line)
+	}
+}
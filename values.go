@@ -0,0 +1,76 @@
+package toon
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// MarshalValues writes v, such as the query parameters of a request, as a
+// flat document with its keys sorted: a key holding one value is written
+// as key: value, and one holding any other number as an inline array
+// key[N]: a,b. Keys that cannot be written bare, such as those holding a
+// colon or brackets, are rejected.
+func MarshalValues(v url.Values, options ...MarshalOption) ([]byte, error) {
+	m := make(map[string]any, len(v))
+	for key, values := range v {
+		if !isFlatKey(key) {
+			return nil, fmt.Errorf("toon: cannot write %q as a key", key)
+		}
+		if len(values) == 1 {
+			m[key] = values[0]
+		} else {
+			m[key] = append([]string{}, values...)
+		}
+	}
+	return Marshal(m, options...)
+}
+
+// UnmarshalValues reads a flat document, whose top-level keys hold single
+// values or inline arrays, into url.Values for form and query APIs; it
+// converts to map[string][]string as it is. Values are kept as text with
+// quotes and escapes resolved, so numbers keep their spelling, and a key
+// repeated in the document collects the values of every occurrence.
+// Nested blocks, tables and lists fail with a *SyntaxError.
+func UnmarshalValues(data []byte) (url.Values, error) {
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(url.Values, len(doc.nodes))
+	for _, n := range doc.nodes {
+		switch {
+		case n.kind == scalarNode:
+			values.Add(n.key, unquote(strings.TrimSpace(doc.value(n))))
+		case n.kind == blockNode && len(n.children) == 0:
+			// A key without a value is an empty one, as in "q="
+			values.Add(n.key, "")
+		case n.kind == inlineNode || n.kind == listNode && len(n.children) == 0:
+			if _, ok := values[n.key]; !ok {
+				values[n.key] = []string{}
+			}
+			if n.kind == listNode || strings.TrimSpace(doc.value(n)) == "" {
+				continue
+			}
+			for _, cell := range doc.cells(n) {
+				values.Add(n.key, unquote(strings.TrimSpace(cell)))
+			}
+		default:
+			d := newDecoder(data, DefaultUnmarshalOptions())
+			return nil, d.syntaxError(n.line+1, fmt.Sprintf("%s %q is not a value or inline array", describeKind(n.kind), n.key))
+		}
+	}
+	return values, nil
+}
+
+// isFlatKey reports whether key can be written bare as a key and read back
+// unchanged.
+func isFlatKey(key string) bool {
+	return key != "" &&
+		strings.TrimSpace(key) == key &&
+		!strings.ContainsAny(key, ":=[]{}\"\n\r") &&
+		!strings.Contains(key, foldedColumnSep) &&
+		!holdsComment(key) &&
+		key != "-" && !strings.HasPrefix(key, "- ")
+}
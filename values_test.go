@@ -0,0 +1,80 @@
+package toon_test
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	toon "github.com/l00pss/gotoon"
+)
+
+func TestMarshalValues(t *testing.T) {
+	in := url.Values{
+		"q":     {"hikes near Boulder"},
+		"page":  {"2"},
+		"tag":   {"lake", "ridge,view"},
+		"empty": {""},
+		"none":  {},
+	}
+	data, err := toon.MarshalValues(in)
+	if err != nil {
+		t.Fatalf("MarshalValues failed: %v", err)
+	}
+	want := `empty: ""
+none[0]:
+page: "2"
+q: hikes near Boulder
+tag[2]: lake,"ridge,view"
+`
+	if string(data) != want {
+		t.Fatalf("MarshalValues = %q, want %q", data, want)
+	}
+
+	out, err := toon.UnmarshalValues(data)
+	if err != nil {
+		t.Fatalf("UnmarshalValues failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("UnmarshalValues = %#v, want %#v", out, in)
+	}
+
+	if _, err := toon.MarshalValues(url.Values{"tags[]": {"a"}}); err == nil {
+		t.Fatal("MarshalValues accepted a key with brackets")
+	}
+}
+
+func TestUnmarshalValues(t *testing.T) {
+	data := []byte(`#toon 1.0
+limit: 10
+sort: -date
+sort: name  # repeated keys add values
+ids[3|]: 7|8|9
+q:
+`)
+	out, err := toon.UnmarshalValues(data)
+	if err != nil {
+		t.Fatalf("UnmarshalValues failed: %v", err)
+	}
+	want := url.Values{
+		"limit": {"10"},
+		"sort":  {"-date", "name"},
+		"ids":   {"7", "8", "9"},
+		"q":     {""},
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Fatalf("UnmarshalValues = %#v, want %#v", out, want)
+	}
+	if got := out.Encode(); got != "ids=7&ids=8&ids=9&limit=10&q=&sort=-date&sort=name" {
+		t.Errorf("Encode = %q", got)
+	}
+
+	_, err = toon.UnmarshalValues([]byte("limit: 10\nfilter:\n  season: spring\n"))
+	var syntaxErr *toon.SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Line != 2 {
+		t.Fatalf("nested block: err = %v, want a SyntaxError at line 2", err)
+	}
+	if want := `toon: syntax error at line 2, column 1: block "filter" is not a value or inline array`; err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}